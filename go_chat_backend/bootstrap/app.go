@@ -12,6 +12,7 @@ type App struct {
 	Services       *Services
 	GrpcServices   *GrpcServices
 	Handlers       *Handlers
+	Workers        *Workers
 }
 
 func NewApp(cfg *config.Config) (*App, error) {
@@ -24,11 +25,15 @@ func NewApp(cfg *config.Config) (*App, error) {
 	app.Infrastructure = infra
 
 	// repos
-	repos := NewRepositories(infra.DB)
+	repos := NewRepositories(cfg, infra.DB)
 	app.Repositories = repos
 
 	// services
-	services := NewServices(repos, infra)
+	services, err := NewServices(cfg, repos, infra)
+	if err != nil {
+		logging.Logger.Error("fail NewServices", "error", err)
+		return nil, err
+	}
 	app.Services = services
 
 	handlers := NewHandlers(services, infra)
@@ -43,6 +48,9 @@ func NewApp(cfg *config.Config) (*App, error) {
 
 	app.GrpcServices = GrpcServices
 
+	// background jobs
+	app.Workers = StartWorkers(cfg, services)
+
 	return app, nil
 }
 
@@ -51,6 +59,9 @@ func (a *App) Shutdown() error {
 	if a == nil {
 		return nil
 	}
+	if a.Workers != nil {
+		a.Workers.Stop()
+	}
 	if a.GrpcServices != nil {
 		if err := a.GrpcServices.Shutdown(); err != nil {
 			return err
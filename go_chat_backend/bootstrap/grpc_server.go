@@ -12,7 +12,7 @@ type GrpcServices struct {
 
 func NewGrpcServices(cfg *config.Config, services *Services, infra *Infrastructure) (*GrpcServices, error) {
 	s := &GrpcServices{
-		servers.NewIngestService(cfg, services.ChunkService, services.DocService, infra.EventPublisher),
+		servers.NewIngestService(cfg, services.ChunkService, services.DocService, services.ReprocessService, infra.EventPublisher),
 	}
 	if err := s.IngestService.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start ingest service: %w", err)
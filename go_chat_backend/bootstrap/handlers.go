@@ -6,15 +6,19 @@ type Handlers struct {
 	DocHandler  *handlers.DocHandler
 	WSHandler   *handlers.WSHandler
 	ChatHandler *handlers.ChatHandler
+	BlobHandler *handlers.BlobHandler // 仅 STORAGE_TYPE=local
 }
 
 func NewHandlers(services *Services, infra *Infrastructure) *Handlers {
 	res := &Handlers{}
-	d := handlers.NewDocHandler(services.DocService, services.GrpcServices, services.LLMConfigService)
+	d := handlers.NewDocHandler(services.DocService, services.GrpcServices, services.LLMConfigService, services.DeletionService, services.ReprocessService, services.BackfillService, services.ChunkService)
 	res.DocHandler = d
 	w := handlers.NewWSHandler(infra.EventPublisher)
 	res.WSHandler = w
 	c := handlers.NewChatHandler(services.ChatsService)
 	res.ChatHandler = c
+	if ls, ok := infra.Storage.LocalStore(); ok {
+		res.BlobHandler = handlers.NewBlobHandler(ls)
+	}
 	return res
 }
@@ -1,21 +1,33 @@
 package bootstrap
 
 import (
+	"go_chat_backend/config"
 	"go_chat_backend/platform/database"
 	"go_chat_backend/repository"
 )
 
 type Repositories struct {
-	ChunkRepository    repository.ChunkRepository
-	DocumentRepository repository.DocumentRepository
-	ChatRepository     repository.ChatRepository
+	ChunkRepository           repository.ChunkRepository
+	DocumentRepository        repository.DocumentRepository
+	ChatRepository            repository.ChatRepository
+	EmbeddingModelRepository  repository.EmbeddingModelRepository
+	DeletionJobRepository     repository.DeletionJobRepository
+	ChunkGenerationRepository repository.ChunkGenerationRepository
 }
 
-func NewRepositories(db *database.DB) *Repositories {
+func NewRepositories(cfg *config.Config, db *database.DB) *Repositories {
 	sqlDB := db.GetDatabase()
+	index := db.VectorIndex()
+	vectorStore := repository.NewVectorStore(cfg.VectorStore, sqlDB, repository.PgVectorSearch{
+		Settings: index.SearchSettings(),
+		Filtered: index.FilteredSearch(),
+	}, cfg.MemoryVectorLimit)
 	return &Repositories{
-		ChunkRepository:    repository.NewChunkRepository(sqlDB),
-		DocumentRepository: repository.NewDocumentRepository(sqlDB),
-		ChatRepository:     repository.NewChatRepository(sqlDB),
+		ChunkRepository:           repository.NewChunkRepository(sqlDB, vectorStore),
+		DocumentRepository:        repository.NewDocumentRepository(sqlDB),
+		ChatRepository:            repository.NewChatRepository(sqlDB),
+		EmbeddingModelRepository:  repository.NewEmbeddingModelRepository(sqlDB),
+		DeletionJobRepository:     repository.NewDeletionJobRepository(sqlDB),
+		ChunkGenerationRepository: repository.NewChunkGenerationRepository(sqlDB),
 	}
 }
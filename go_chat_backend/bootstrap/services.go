@@ -1,6 +1,12 @@
 package bootstrap
 
-import "go_chat_backend/services"
+import (
+	"context"
+	"go_chat_backend/config"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/services"
+)
 
 type Services struct {
 	DocService       *services.DocumentService
@@ -9,30 +15,88 @@ type Services struct {
 	ChatsService     *services.ChatService
 	LLMConfigService *services.LLMConfigService
 	RagService       *services.RagModeService
+	RetrievalService *services.RetrievalService
+	EmbeddingModels  *services.EmbeddingModelRegistry
+	ReembedService   *services.ReembedService
+	DeletionService  *services.DeletionService
+	JanitorService   *services.JanitorService
+	ReprocessService *services.ReprocessService
+	BackfillService  *services.RagBackfillService
 }
 
-func NewServices(repos *Repositories, infra *Infrastructure) *Services {
+func NewServices(cfg *config.Config, repos *Repositories, infra *Infrastructure) (*Services, error) {
 	res := &Services{}
 
+	registry := services.NewEmbeddingModelRegistry(repos.EmbeddingModelRepository, repos.ChunkRepository)
+	if err := registry.Activate(context.Background(), cfg.EmbeddingModel, cfg.EmbeddingModelVersion, cfg.EmbeddingDimension); err != nil {
+		return nil, err
+	}
+	res.EmbeddingModels = registry
+	// CONCURRENTLY 建索引不阻塞读写，但在大表上耗时很长，不阻塞启动；中断留下的 INVALID 索引下次启动时重建
+	go func() {
+		if err := infra.DB.EnsureVectorIndex(context.Background(), registry.ActiveID(), cfg.EmbeddingDimension); err != nil {
+			logging.Logger.Error("fail EnsureVectorIndex", "error", err, "model", registry.ActiveID())
+		}
+	}()
+
 	llmConfigService := services.NewLLMConfigService(infra.Cache)
 	res.LLMConfigService = llmConfigService
 
 	ragService := services.NewRagModeService(infra.Cache, repos.DocumentRepository)
 	res.RagService = ragService
 
-	docService := services.NewDocumentService(repos.DocumentRepository, repos.ChatRepository, infra.Queue, infra.Storage, infra.Cache, llmConfigService, ragService)
+	docService := services.NewDocumentService(repos.DocumentRepository, repos.ChatRepository, infra.Queue, infra.Storage, infra.Cache, llmConfigService, ragService, infra.EventPublisher, cfg.MaxFileSize)
 	res.DocService = docService
 
-	chunkService := services.NewChunkService(infra.DB)
+	res.JanitorService = services.NewJanitorService(repos.DocumentRepository, infra.Storage, infra.EventPublisher, cfg.AbandonedUploadAfter, cfg.StaleProcessingAfter, cfg.JanitorInterval)
+	res.DeletionService = services.NewDeletionService(repos.DocumentRepository, repos.ChunkRepository, repos.ChatRepository, repos.DeletionJobRepository, repos.ChunkGenerationRepository, infra.Storage, infra.Cache, ragService, cfg.TrashRetention, cfg.DeletionInterval)
+
+	// 删除任务不运行时没有人清理被替换的 chunks，切换后立即删除
+	replacedGrace := cfg.ReplacedChunksGrace
+	if cfg.DeletionInterval <= 0 {
+		replacedGrace = 0
+	}
+	res.ReprocessService = services.NewReprocessService(repos.DocumentRepository, repos.ChunkRepository, repos.ChunkGenerationRepository, infra.Queue, infra.Storage, infra.Cache, registry, replacedGrace)
+
+	chunkService := services.NewChunkService(repos.ChunkRepository, repos.DocumentRepository, registry, cfg.IngestBatchSize, cfg.IngestFlushInterval, cfg.IngestContextTTL)
 	res.ChunkService = chunkService
-	grpcServices := services.NewGRPCService(infra.GrpcClients)
+	grpcServices := services.NewGRPCService(infra.GrpcClients, infra.Cache, embeddingConfig(cfg))
 	res.GrpcServices = grpcServices
 
+	reranker := services.NewReranker(cfg.Reranker, infra.GrpcClients)
+	rewriter := services.NewQueryRewriter(services.QueryRewriteConfig{
+		Enabled:     cfg.QueryRewrite,
+		Paraphrases: cfg.QueryParaphrases,
+		HyDE:        cfg.QueryHyDE,
+		Timeout:     cfg.QueryRewriteTimeout,
+	})
+	retrievalService := services.NewRetrievalService(repos.ChunkRepository, repos.DocumentRepository, grpcServices, reranker, rewriter, services.RetrievalConfig{
+		TopK:             cfg.RetrievalTopK,
+		RerankCandidates: cfg.RerankCandidates,
+		RerankTimeout:    cfg.RerankTimeout,
+		MMRLambda:        cfg.MMRLambda,
+		EmbeddingModel:   registry.ActiveID(),
+	})
+	res.RetrievalService = retrievalService
+
+	res.BackfillService = services.NewRagBackfillService(repos.DocumentRepository, repos.ChunkRepository, grpcServices, registry, ragService, infra.EventPublisher, cfg.EmbeddingBatchSize)
+	res.ReembedService = services.NewReembedService(repos.DocumentRepository, repos.ChunkRepository, grpcServices, registry, cfg.EmbeddingBatchSize, cfg.ReembedInterval)
+
 	// LLM 服务（注入 GRPCService）
-	llmServices := services.NewLLMService(repos.ChunkRepository, grpcServices)
+	llmServices := services.NewLLMService(repos.ChunkRepository, repos.DocumentRepository, grpcServices, retrievalService)
 	chatServices := services.NewChatService(repos.ChatRepository, repos.DocumentRepository, infra.Cache, llmServices, llmConfigService, ragService)
 	res.ChatsService = chatServices
 
+	return res, nil
+}
 
-	return res
+func embeddingConfig(cfg *config.Config) services.EmbeddingConfig {
+	return services.EmbeddingConfig{
+		Model:      models.EmbeddingModelID(cfg.EmbeddingModel, cfg.EmbeddingModelVersion),
+		Dimension:  cfg.EmbeddingDimension,
+		Timeout:    cfg.EmbeddingTimeout,
+		MaxRetries: cfg.EmbeddingMaxRetries,
+		BatchSize:  cfg.EmbeddingBatchSize,
+		CacheTTL:   cfg.EmbeddingCacheTTL,
+	}
 }
@@ -0,0 +1,47 @@
+package bootstrap
+
+import (
+	"context"
+	"go_chat_backend/config"
+	"sync"
+)
+
+// Workers 后台任务，随应用启动，Shutdown 时等待退出
+type Workers struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func StartWorkers(cfg *config.Config, services *Services) *Workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Workers{cancel: cancel}
+
+	if cfg.ReembedInterval > 0 {
+		w.run(ctx, services.ReembedService.Run)
+	}
+	if cfg.DeletionInterval > 0 {
+		w.run(ctx, services.DeletionService.Run)
+	}
+	if cfg.JanitorInterval > 0 {
+		w.run(ctx, services.JanitorService.Run)
+	}
+	w.run(ctx, services.BackfillService.Run)
+	if cfg.IngestContextTTL > 0 {
+		w.run(ctx, services.ChunkService.RunContextExpiry)
+	}
+	return w
+}
+
+func (w *Workers) run(ctx context.Context, job func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		job(ctx)
+	}()
+}
+
+// Stop 取消所有任务并等待退出
+func (w *Workers) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
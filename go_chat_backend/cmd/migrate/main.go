@@ -0,0 +1,66 @@
+// migrate 执行或撤销数据库的一次性结构迁移
+//
+// 默认执行 AutoMigrate 和尚未执行的迁移（与服务启动时相同）并列出迁移状态；-status 只列出状态，
+// -rollback 撤销指定迁移，下次启动时会重新执行。
+//
+//	go run ./cmd/migrate -rollback 0001_non_unique_file_hash_index
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go_chat_backend/config"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/database"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	logging.Init()
+	_ = godotenv.Load()
+	cfg := config.LoadConfig()
+
+	status := flag.Bool("status", false, "only list migrations and whether they have been applied")
+	rollback := flag.String("rollback", "", "roll back the given migration")
+	flag.Parse()
+
+	if err := run(cfg, *status, *rollback); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg *config.Config, statusOnly bool, rollback string) error {
+	db, err := database.InitPostgres(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch {
+	case rollback != "":
+		if err := db.RollbackMigration(rollback); err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %s\n", rollback)
+	case !statusOnly:
+		if err := db.AutoMigrate(); err != nil {
+			return err
+		}
+	}
+
+	ids, applied, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		state := "pending"
+		if applied[id] {
+			state = "applied"
+		}
+		fmt.Printf("%-48s %s\n", id, state)
+	}
+	return nil
+}
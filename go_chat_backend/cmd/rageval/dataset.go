@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GoldenItem 一条评测样本
+type GoldenItem struct {
+	ID               string    `json:"id"`
+	DocID            string    `json:"doc_id"`
+	Question         string    `json:"question"`
+	ExpectedChunkIDs []string  `json:"expected_chunk_ids"`
+	ReferenceAnswer  string    `json:"reference_answer"`
+	QueryEmbedding   []float32 `json:"query_embedding,omitempty"` // -embedder=dataset 时使用
+}
+
+// loadDataset 读取 JSON 数组或 JSONL 格式的评测集
+func loadDataset(path string) ([]*GoldenItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items []*GoldenItem
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.NewDecoder(f).Decode(&items); err != nil {
+			return nil, fmt.Errorf("failed to decode dataset: %w", err)
+		}
+	} else {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var item GoldenItem
+			if err := json.Unmarshal([]byte(text), &item); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			items = append(items, &item)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, item := range items {
+		if item.ID == "" {
+			item.ID = fmt.Sprintf("q%d", i+1)
+		}
+		if item.DocID == "" || item.Question == "" {
+			return nil, fmt.Errorf("item %s: doc_id and question are required", item.ID)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go_chat_backend/models"
+	"go_chat_backend/repository"
+)
+
+// datasetEmbedder 使用评测集中预先计算好的 query_embedding
+type datasetEmbedder struct {
+	byQuestion map[string][]float32
+}
+
+func newDatasetEmbedder(items []*GoldenItem) (*datasetEmbedder, error) {
+	e := &datasetEmbedder{byQuestion: make(map[string][]float32)}
+	for _, item := range items {
+		if len(item.QueryEmbedding) == 0 {
+			return nil, fmt.Errorf("item %s has no query_embedding", item.ID)
+		}
+		e.byQuestion[item.Question] = item.QueryEmbedding
+	}
+	return e, nil
+}
+
+func (e *datasetEmbedder) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	res := make([][]float32, len(texts))
+	for i, text := range texts {
+		v, ok := e.byQuestion[text]
+		if !ok {
+			return nil, fmt.Errorf("no embedding for %q", text)
+		}
+		res[i] = v
+	}
+	return res, nil
+}
+
+// lexicalEmbedder 离线桩：以当前文档中词重叠最高的已存储 chunk 的向量作为问题向量，
+// 不依赖 embedding 服务即可跑通完整检索路径；得到的指标反映的是词重叠，不是向量检索的效果
+type lexicalEmbedder struct {
+	byDoc map[string][]*models.Chunk
+	docID string // 当前评测样本的文档，只在该文档的 chunks 中选取
+}
+
+func newLexicalEmbedder(ctx context.Context, chunkRepo repository.ChunkRepository, docRepo repository.DocumentRepository, model string, items []*GoldenItem) (*lexicalEmbedder, error) {
+	e := &lexicalEmbedder{byDoc: make(map[string][]*models.Chunk)}
+	for _, item := range items {
+		if _, ok := e.byDoc[item.DocID]; ok {
+			continue
+		}
+		e.byDoc[item.DocID] = []*models.Chunk{}
+		doc, err := docRepo.GetByID(ctx, item.DocID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load document %s: %w", item.DocID, err)
+		}
+		chunks, err := chunkRepo.GetByFileID(ctx, doc.ChunkFileID())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chunks of %s: %w", item.DocID, err)
+		}
+		for _, c := range chunks {
+			// 只用与查询同一模型的向量，否则检索时会被过滤掉
+			if c.EmbeddingModel == model {
+				e.byDoc[item.DocID] = append(e.byDoc[item.DocID], c)
+			}
+		}
+	}
+	return e, nil
+}
+
+// useDocument 评测下一条样本前切换到它的文档
+func (e *lexicalEmbedder) useDocument(docID string) {
+	e.docID = docID
+}
+
+func (e *lexicalEmbedder) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	res := make([][]float32, len(texts))
+	for i, text := range texts {
+		v, err := e.embed(text)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = v
+	}
+	return res, nil
+}
+
+func (e *lexicalEmbedder) embed(text string) ([]float32, error) {
+	query := make(map[string]bool)
+	for _, t := range tokenize(text) {
+		query[t] = true
+	}
+	var best *models.Chunk
+	bestScore := -1
+	for _, c := range e.byDoc[e.docID] {
+		if len(c.EmbeddingVector.Slice()) == 0 {
+			continue
+		}
+		score := 0
+		for _, t := range tokenize(c.ChunkText) {
+			if query[t] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no embedded chunks loaded for document %s", e.docID)
+	}
+	return best.EmbeddingVector.Slice(), nil
+}
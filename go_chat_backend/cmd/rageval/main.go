@@ -0,0 +1,337 @@
+// rageval 离线评测 RAG 检索质量
+//
+// 读取评测集（文档、问题、期望命中的 chunk ID、参考答案），使用与线上相同的检索路径
+// （改写 → 向量召回 → 重排序 → MMR → 相邻扩展）对本地 Postgres 中的文档检索，
+// 输出 recall@k、MRR、nDCG 以及可选的答案评分，生成 JSON/CSV 报告，便于对比调参前后的效果。
+// 只有 -embedder=grpc（或 dataset 中由同一模型生成的向量）的结果反映线上的向量检索效果；
+// lexical 只用于在没有 embedding 服务时验证检索路径本身。
+//
+//	go run ./cmd/rageval -dataset golden.jsonl -embedder grpc -out reports
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go_chat_backend/config"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/database"
+	"go_chat_backend/platform/grpc/clients"
+	"go_chat_backend/repository"
+	"go_chat_backend/services"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// cutoffs 报告 recall@k / nDCG@k 的 k 值
+var cutoffs = []int{1, 3, 5, 10}
+
+type options struct {
+	dataset     string
+	out         string
+	label       string
+	embedder    string
+	grader      string
+	topK        int
+	candidates  int
+	mmrLambda   float64
+	reranker    string
+	llmProvider string
+	llmModel    string
+}
+
+func main() {
+	logging.Init()
+	_ = godotenv.Load()
+	cfg := config.LoadConfig()
+
+	opts := options{}
+	flag.StringVar(&opts.dataset, "dataset", "", "golden dataset (.json array or .jsonl)")
+	flag.StringVar(&opts.out, "out", "rageval-reports", "directory for JSON/CSV reports")
+	flag.StringVar(&opts.label, "label", "", "report name (default: timestamp)")
+	flag.StringVar(&opts.embedder, "embedder", "grpc", "query embedder: grpc, dataset (query_embedding field) or lexical (offline stub)")
+	flag.StringVar(&opts.grader, "grader", "none", "answer grading: none, fake (extractive, no LLM) or llm")
+	flag.IntVar(&opts.topK, "top-k", cfg.RetrievalTopK, "chunks kept after rerank/MMR")
+	flag.IntVar(&opts.candidates, "candidates", cfg.RerankCandidates, "vector search candidates")
+	flag.Float64Var(&opts.mmrLambda, "mmr-lambda", cfg.MMRLambda, "MMR lambda, >=1 disables MMR")
+	flag.StringVar(&opts.reranker, "reranker", cfg.Reranker, "reranker: none, grpc or llm")
+	flag.StringVar(&opts.llmProvider, "llm-provider", "", "LLM provider for -grader=llm, llm reranker and query rewriting (API key from LLM_API_KEY)")
+	flag.StringVar(&opts.llmModel, "llm-model", "", "LLM model name")
+	flag.Parse()
+
+	if err := run(cfg, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "rageval:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg *config.Config, opts options) error {
+	if opts.dataset == "" {
+		return fmt.Errorf("-dataset is required")
+	}
+	items, err := loadDataset(opts.dataset)
+	if err != nil {
+		return fmt.Errorf("failed to load dataset %s: %w", opts.dataset, err)
+	}
+
+	var llmConfig *services.LLMConfig
+	if opts.llmProvider != "" {
+		llmConfig = &services.LLMConfig{
+			Provider: opts.llmProvider,
+			Model:    opts.llmModel,
+			APIKey:   os.Getenv("LLM_API_KEY"),
+		}
+	}
+	if opts.grader == "llm" && llmConfig == nil {
+		return fmt.Errorf("-grader=llm requires -llm-provider")
+	}
+
+	db, err := database.InitPostgres(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer db.Close()
+	index := db.VectorIndex()
+	vectorStore := repository.NewVectorStore(cfg.VectorStore, db.GetDatabase(), repository.PgVectorSearch{
+		Settings: index.SearchSettings(),
+		Filtered: index.FilteredSearch(),
+	}, cfg.MemoryVectorLimit)
+	chunkRepo := repository.NewChunkRepository(db.GetDatabase(), vectorStore)
+	docRepo := repository.NewDocumentRepository(db.GetDatabase())
+
+	ctx := context.Background()
+
+	// 只有需要时才连接 Python gRPC 服务
+	var grpcClients *clients.GrpcClients
+	if opts.embedder == "grpc" || opts.reranker == "grpc" {
+		grpcClients = clients.NewGrpcClients(cfg)
+		if grpcClients == nil {
+			return fmt.Errorf("failed to connect to gRPC services")
+		}
+		defer grpcClients.Close()
+	}
+
+	modelID := models.EmbeddingModelID(cfg.EmbeddingModel, cfg.EmbeddingModelVersion)
+	var embedder services.Embedder
+	var lexical *lexicalEmbedder
+	var notes []string
+	switch opts.embedder {
+	case "grpc":
+		// 评测不走缓存，保证每次都真实调用 embedding 服务
+		embedder = services.NewGRPCService(grpcClients, nil, services.EmbeddingConfig{
+			Model:      modelID,
+			Dimension:  cfg.EmbeddingDimension,
+			Timeout:    cfg.EmbeddingTimeout,
+			MaxRetries: cfg.EmbeddingMaxRetries,
+			BatchSize:  cfg.EmbeddingBatchSize,
+		})
+	case "dataset":
+		if embedder, err = newDatasetEmbedder(items); err != nil {
+			return fmt.Errorf("invalid dataset embeddings: %w", err)
+		}
+	case "lexical":
+		if lexical, err = newLexicalEmbedder(ctx, chunkRepo, docRepo, modelID, items); err != nil {
+			return fmt.Errorf("failed to build lexical embedder: %w", err)
+		}
+		embedder = lexical
+		notes = append(notes, "lexical embedder: query vectors are copied from the chunk with the highest word overlap, "+
+			"so recall measures lexical overlap, not embedding retrieval quality")
+	default:
+		return fmt.Errorf("unknown embedder %q", opts.embedder)
+	}
+
+	// 改写需要 LLM；未提供 provider 时关闭，保证离线可跑
+	rewriteCfg := services.QueryRewriteConfig{}
+	if llmConfig != nil {
+		rewriteCfg = services.QueryRewriteConfig{
+			Enabled:     cfg.QueryRewrite,
+			Paraphrases: cfg.QueryParaphrases,
+			HyDE:        cfg.QueryHyDE,
+			Timeout:     cfg.QueryRewriteTimeout,
+		}
+	}
+	// 数据集只有原问题的向量，改写出的查询无法向量化
+	if opts.embedder == "dataset" && services.NewQueryRewriter(rewriteCfg).Active() {
+		rewriteCfg = services.QueryRewriteConfig{}
+		notes = append(notes, "query rewriting disabled: the dataset embedder only has embeddings for the original questions")
+	}
+	retrievalCfg := services.RetrievalConfig{
+		TopK:             opts.topK,
+		RerankCandidates: opts.candidates,
+		RerankTimeout:    cfg.RerankTimeout,
+		MMRLambda:        opts.mmrLambda,
+		EmbeddingModel:   modelID,
+	}
+	retrievalService := services.NewRetrievalService(chunkRepo, docRepo, embedder,
+		services.NewReranker(opts.reranker, grpcClients), services.NewQueryRewriter(rewriteCfg), retrievalCfg)
+	llmService := services.NewLLMService(chunkRepo, docRepo, nil, retrievalService)
+
+	report := &Report{
+		Label:     opts.label,
+		CreatedAt: time.Now(),
+		Dataset:   opts.dataset,
+		Notes:     notes,
+		Settings: Settings{
+			Embedder:   opts.embedder,
+			Reranker:   opts.reranker,
+			Grader:     opts.grader,
+			TopK:       opts.topK,
+			Candidates: opts.candidates,
+			MMRLambda:  opts.mmrLambda,
+			LLMModel:   opts.llmModel,
+		},
+	}
+	if report.Label == "" {
+		report.Label = report.CreatedAt.Format("20060102-150405")
+	}
+
+	for _, item := range items {
+		if lexical != nil {
+			lexical.useDocument(item.DocID)
+		}
+		res := evaluate(ctx, llmService, llmConfig, opts.grader, item)
+		report.Items = append(report.Items, res)
+		if res.Error != "" {
+			logging.Logger.Warn("evaluation failed", "id", item.ID, "error", res.Error)
+		}
+	}
+	report.Summary = summarize(report.Items, opts.grader != "none")
+
+	jsonPath, csvPath, err := report.Write(opts.out)
+	if err != nil {
+		return fmt.Errorf("failed to write reports: %w", err)
+	}
+	report.Print(os.Stdout)
+	fmt.Printf("\nreports: %s, %s\n", jsonPath, csvPath)
+	return nil
+}
+
+// evaluate 对单条样本执行检索（与聊天接口使用同一个 BuildPrompt）并计算指标
+func evaluate(ctx context.Context, llmService *services.LLMService, llmConfig *services.LLMConfig, grader string, item *GoldenItem) *ItemResult {
+	res := &ItemResult{
+		ID:       item.ID,
+		DocID:    item.DocID,
+		Question: item.Question,
+		Expected: item.ExpectedChunkIDs,
+		Recall:   make(map[int]float64),
+		NDCG:     make(map[int]float64),
+	}
+
+	start := time.Now()
+	prompt, debug := llmService.BuildPrompt(ctx, nil, item.Question, "", item.DocID, llmConfig, true)
+	res.RetrievalMs = time.Since(start).Milliseconds()
+	if debug.Trace == nil {
+		res.Error = "retrieval failed: " + debug.RetrievalError
+		return res
+	}
+	res.Timings = debug.Trace.Timings
+	res.Ranked = rankedChunkIDs(debug.Trace)
+	res.PromptTokens = debug.Tokens.Prompt
+
+	expected := make(map[string]bool, len(item.ExpectedChunkIDs))
+	for _, id := range item.ExpectedChunkIDs {
+		expected[id] = true
+	}
+	for _, k := range cutoffs {
+		res.Recall[k] = recallAtK(res.Ranked, expected, k)
+		res.NDCG[k] = ndcgAtK(res.Ranked, expected, k)
+	}
+	res.MRR = reciprocalRank(res.Ranked, expected)
+
+	var contextIDs []string
+	for _, p := range debug.Passages {
+		contextIDs = append(contextIDs, p.ChunkIDs...)
+	}
+	res.ContextRecall = contextRecall(contextIDs, expected)
+
+	switch grader {
+	case "fake":
+		res.Answer = extractiveAnswer(item.Question, debug.Passages)
+	case "llm":
+		answer, err := llmService.CallLLM(prompt, llmConfig.Provider, llmConfig.Model, llmConfig.APIKey)
+		if err != nil {
+			res.Error = fmt.Sprintf("llm answer failed: %v", err)
+			return res
+		}
+		res.Answer = answer
+		if item.ReferenceAnswer != "" {
+			score, err := judgeAnswer(llmService, llmConfig, item, answer)
+			if err != nil {
+				logging.Logger.Warn("llm judge failed", "id", item.ID, "error", err)
+			} else {
+				res.JudgeScore = &score
+			}
+		}
+	}
+	if grader != "none" && item.ReferenceAnswer != "" {
+		f1 := tokenF1(res.Answer, item.ReferenceAnswer)
+		res.AnswerF1 = &f1
+	}
+	return res
+}
+
+// rankedChunkIDs 检索排序：先是最终选中的 chunk（按选择顺序），再是未选中的候选（按召回顺序），
+// 这样 k 大于 TopK 时 recall@k 仍能反映召回阶段的效果
+func rankedChunkIDs(trace *models.RetrievalTrace) []string {
+	var selected, rest []*models.CandidateTrace
+	for _, c := range trace.Candidates {
+		if c.Selected {
+			selected = append(selected, c)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].SelectionRank < selected[j].SelectionRank })
+
+	ids := make([]string, 0, len(trace.Candidates))
+	for _, c := range append(selected, rest...) {
+		ids = append(ids, c.ChunkID)
+	}
+	return ids
+}
+
+// extractiveAnswer 假 LLM：从检索到的上下文中挑出与问题词重叠最多的句子作为答案
+func extractiveAnswer(question string, passages []*models.RetrievedPassage) string {
+	terms := make(map[string]bool)
+	for _, t := range tokenize(question) {
+		terms[t] = true
+	}
+	best, bestScore := "", 0
+	for _, p := range passages {
+		for _, sentence := range strings.FieldsFunc(p.Text, func(r rune) bool {
+			return r == '.' || r == '。' || r == '\n' || r == '?' || r == '!'
+		}) {
+			score := 0
+			for _, t := range tokenize(sentence) {
+				if terms[t] {
+					score++
+				}
+			}
+			if score > bestScore {
+				best, bestScore = strings.TrimSpace(sentence), score
+			}
+		}
+	}
+	return best
+}
+
+// judgeAnswer 让 LLM 对照参考答案给出 0-1 的正确性评分
+func judgeAnswer(llmService *services.LLMService, llmConfig *services.LLMConfig, item *GoldenItem, answer string) (float64, error) {
+	prompt := fmt.Sprintf("Grade how well the answer matches the reference answer on a scale from 0 to 10.\n"+
+		"Reply with a single number only.\n\nQuestion: %s\n\nReference answer: %s\n\nAnswer: %s\n",
+		item.Question, item.ReferenceAnswer, answer)
+	reply, err := llmService.CallLLM(prompt, llmConfig.Provider, llmConfig.Model, llmConfig.APIKey)
+	if err != nil {
+		return 0, err
+	}
+	var score float64
+	if _, err := fmt.Sscanf(strings.TrimSpace(reply), "%g", &score); err != nil {
+		return 0, fmt.Errorf("failed to parse judge score %q: %w", reply, err)
+	}
+	return min(max(score, 0), 10) / 10, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// recallAtK 前 k 个结果中命中的期望 chunk 占全部期望 chunk 的比例，重复的结果只计一次
+func recallAtK(ranked []string, expected map[string]bool, k int) float64 {
+	return contextRecall(ranked[:max(0, min(k, len(ranked)))], expected)
+}
+
+// reciprocalRank 第一个命中结果排名的倒数
+func reciprocalRank(ranked []string, expected map[string]bool) float64 {
+	for i, id := range ranked {
+		if expected[id] {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+// ndcgAtK 二值相关性的 nDCG@k；重复的结果只在第一次出现时计分
+func ndcgAtK(ranked []string, expected map[string]bool, k int) float64 {
+	var dcg float64
+	seen := make(map[string]bool)
+	for i, id := range ranked {
+		if i >= k {
+			break
+		}
+		if expected[id] && !seen[id] {
+			seen[id] = true
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+	var idcg float64
+	for i := 0; i < len(expected) && i < k; i++ {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+	if idcg == 0 {
+		return 0
+	}
+	return dcg / idcg
+}
+
+// contextRecall 期望 chunk 出现在最终上下文（含相邻扩展）中的比例
+func contextRecall(contextIDs []string, expected map[string]bool) float64 {
+	if len(expected) == 0 {
+		return 0
+	}
+	seen := make(map[string]bool)
+	for _, id := range contextIDs {
+		if expected[id] {
+			seen[id] = true
+		}
+	}
+	return float64(len(seen)) / float64(len(expected))
+}
+
+// tokenF1 答案与参考答案的词级 F1
+func tokenF1(answer, reference string) float64 {
+	pred := tokenize(answer)
+	gold := tokenize(reference)
+	if len(pred) == 0 || len(gold) == 0 {
+		return 0
+	}
+	counts := make(map[string]int)
+	for _, t := range gold {
+		counts[t]++
+	}
+	common := 0
+	for _, t := range pred {
+		if counts[t] > 0 {
+			counts[t]--
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+	precision := float64(common) / float64(len(pred))
+	recall := float64(common) / float64(len(gold))
+	return 2 * precision * recall / (precision + recall)
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func set(ids ...string) map[string]bool {
+	res := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		res[id] = true
+	}
+	return res
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRecallAtK(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranked   []string
+		expected map[string]bool
+		k        int
+		want     float64
+	}{
+		{"empty relevant set", []string{"a", "b"}, set(), 3, 0},
+		{"no results", nil, set("a"), 3, 0},
+		{"cut at k", []string{"x", "a", "b"}, set("a", "b"), 2, 0.5},
+		{"k beyond results", []string{"a", "x"}, set("a", "b"), 10, 0.5},
+		{"all found", []string{"b", "a"}, set("a", "b"), 2, 1},
+		{"duplicates count once", []string{"a", "a", "a"}, set("a", "b"), 3, 0.5},
+	}
+	for _, tt := range tests {
+		if got := recallAtK(tt.ranked, tt.expected, tt.k); !approx(got, tt.want) {
+			t.Errorf("%s: recallAtK = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNDCGAtK(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranked   []string
+		expected map[string]bool
+		k        int
+		want     float64
+	}{
+		{"empty relevant set", []string{"a"}, set(), 3, 0},
+		{"perfect ranking", []string{"a", "b", "x"}, set("a", "b"), 3, 1},
+		{"relevant at rank 2", []string{"x", "a"}, set("a"), 2, 1 / math.Log2(3)},
+		{"k beyond results", []string{"a"}, set("a", "b"), 10, 1 / (1 + 1/math.Log2(3))},
+		{"relevant after k", []string{"x", "a"}, set("a"), 1, 0},
+		{"duplicates count once", []string{"a", "a"}, set("a", "b"), 2, 1 / (1 + 1/math.Log2(3))},
+	}
+	for _, tt := range tests {
+		got := ndcgAtK(tt.ranked, tt.expected, tt.k)
+		if !approx(got, tt.want) {
+			t.Errorf("%s: ndcgAtK = %v, want %v", tt.name, got, tt.want)
+		}
+		if got > 1 {
+			t.Errorf("%s: ndcgAtK = %v exceeds 1", tt.name, got)
+		}
+	}
+}
+
+func TestReciprocalRank(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranked   []string
+		expected map[string]bool
+		want     float64
+	}{
+		{"empty relevant set", []string{"a"}, set(), 0},
+		{"no results", nil, set("a"), 0},
+		{"first", []string{"a", "b"}, set("a", "b"), 1},
+		{"third", []string{"x", "y", "a"}, set("a"), 1.0 / 3},
+		{"duplicates use first hit", []string{"x", "a", "a"}, set("a"), 0.5},
+	}
+	for _, tt := range tests {
+		if got := reciprocalRank(tt.ranked, tt.expected); !approx(got, tt.want) {
+			t.Errorf("%s: reciprocalRank = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTokenF1(t *testing.T) {
+	tests := []struct {
+		name      string
+		answer    string
+		reference string
+		want      float64
+	}{
+		{"empty answer", "", "the cache", 0},
+		{"empty reference", "the cache", "", 0},
+		{"identical ignoring case and punctuation", "The cache, expires!", "the cache expires", 1},
+		{"no overlap", "foo bar", "baz", 0},
+		// 共有 2 个词：precision 2/4，recall 2/2
+		{"partial", "the cache expires daily", "cache expires", 2 * 0.5 * 1 / 1.5},
+		// 重复的词按次数匹配：答案中两个 cache 只有一个能匹配
+		{"duplicate tokens", "cache cache", "cache", 2 * 0.5 * 1 / 1.5},
+	}
+	for _, tt := range tests {
+		if got := tokenF1(tt.answer, tt.reference); !approx(got, tt.want) {
+			t.Errorf("%s: tokenF1 = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go_chat_backend/models"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report 一次评测的完整结果
+type Report struct {
+	Label     string        `json:"label"`
+	CreatedAt time.Time     `json:"created_at"`
+	Dataset   string        `json:"dataset"`
+	Settings  Settings      `json:"settings"`
+	Notes     []string      `json:"notes,omitempty"` // 解读指标时需要注意的限制
+	Summary   Summary       `json:"summary"`
+	Items     []*ItemResult `json:"items"`
+}
+
+// Settings 评测时使用的检索参数，便于对比不同报告
+type Settings struct {
+	Embedder   string  `json:"embedder"`
+	Reranker   string  `json:"reranker"`
+	Grader     string  `json:"grader"`
+	TopK       int     `json:"top_k"`
+	Candidates int     `json:"candidates"`
+	MMRLambda  float64 `json:"mmr_lambda"`
+	LLMModel   string  `json:"llm_model,omitempty"`
+}
+
+// Summary 全部样本的平均指标（失败样本计为 0）
+type Summary struct {
+	Questions     int             `json:"questions"`
+	Failed        int             `json:"failed"`
+	Recall        map[int]float64 `json:"recall_at_k"`
+	NDCG          map[int]float64 `json:"ndcg_at_k"`
+	MRR           float64         `json:"mrr"`
+	ContextRecall float64         `json:"context_recall"`
+	AnswerF1      *float64        `json:"answer_f1,omitempty"`
+	JudgeScore    *float64        `json:"judge_score,omitempty"`
+	AvgLatencyMs  float64         `json:"avg_retrieval_ms"`
+}
+
+// ItemResult 单条样本的结果
+type ItemResult struct {
+	ID            string                  `json:"id"`
+	DocID         string                  `json:"doc_id"`
+	Question      string                  `json:"question"`
+	Expected      []string                `json:"expected_chunk_ids"`
+	Ranked        []string                `json:"ranked_chunk_ids"`
+	Recall        map[int]float64         `json:"recall_at_k"`
+	NDCG          map[int]float64         `json:"ndcg_at_k"`
+	MRR           float64                 `json:"mrr"`
+	ContextRecall float64                 `json:"context_recall"`
+	Answer        string                  `json:"answer,omitempty"`
+	AnswerF1      *float64                `json:"answer_f1,omitempty"`
+	JudgeScore    *float64                `json:"judge_score,omitempty"`
+	PromptTokens  int                     `json:"prompt_tokens"`
+	RetrievalMs   int64                   `json:"retrieval_ms"`
+	Timings       models.RetrievalTimings `json:"timings"`
+	Error         string                  `json:"error,omitempty"`
+}
+
+func summarize(items []*ItemResult, graded bool) Summary {
+	s := Summary{
+		Questions: len(items),
+		Recall:    make(map[int]float64),
+		NDCG:      make(map[int]float64),
+	}
+	if len(items) == 0 {
+		return s
+	}
+	var f1Sum, judgeSum float64
+	var f1Count, judgeCount int
+	n := float64(len(items))
+	for _, item := range items {
+		if item.Error != "" {
+			s.Failed++
+		}
+		for _, k := range cutoffs {
+			s.Recall[k] += item.Recall[k] / n
+			s.NDCG[k] += item.NDCG[k] / n
+		}
+		s.MRR += item.MRR / n
+		s.ContextRecall += item.ContextRecall / n
+		s.AvgLatencyMs += float64(item.RetrievalMs) / n
+		if item.AnswerF1 != nil {
+			f1Sum += *item.AnswerF1
+			f1Count++
+		}
+		if item.JudgeScore != nil {
+			judgeSum += *item.JudgeScore
+			judgeCount++
+		}
+	}
+	if graded && f1Count > 0 {
+		avg := f1Sum / float64(f1Count)
+		s.AnswerF1 = &avg
+	}
+	if graded && judgeCount > 0 {
+		avg := judgeSum / float64(judgeCount)
+		s.JudgeScore = &avg
+	}
+	return s
+}
+
+// Write 在 dir 下写出 <label>.json 和 <label>.csv
+func (r *Report) Write(dir string) (string, string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+	jsonPath := filepath.Join(dir, r.Label+".json")
+	csvPath := filepath.Join(dir, r.Label+".csv")
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(jsonPath, data, 0o644); err != nil {
+		return "", "", err
+	}
+
+	f, err := os.Create(csvPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	if err := r.writeCSV(f); err != nil {
+		return "", "", err
+	}
+	return jsonPath, csvPath, nil
+}
+
+func (r *Report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"id", "doc_id", "question"}
+	for _, k := range cutoffs {
+		header = append(header, fmt.Sprintf("recall@%d", k))
+	}
+	for _, k := range cutoffs {
+		header = append(header, fmt.Sprintf("ndcg@%d", k))
+	}
+	header = append(header, "mrr", "context_recall", "answer_f1", "judge_score", "retrieval_ms", "error")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, item := range r.Items {
+		row := []string{item.ID, item.DocID, item.Question}
+		for _, k := range cutoffs {
+			row = append(row, formatFloat(item.Recall[k]))
+		}
+		for _, k := range cutoffs {
+			row = append(row, formatFloat(item.NDCG[k]))
+		}
+		row = append(row,
+			formatFloat(item.MRR),
+			formatFloat(item.ContextRecall),
+			formatOptional(item.AnswerF1),
+			formatOptional(item.JudgeScore),
+			strconv.FormatInt(item.RetrievalMs, 10),
+			item.Error,
+		)
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Print 输出汇总表
+func (r *Report) Print(w io.Writer) {
+	s := r.Summary
+	fmt.Fprintf(w, "%s: %d questions (%d failed), top_k=%d candidates=%d mmr_lambda=%g reranker=%s\n",
+		r.Label, s.Questions, s.Failed, r.Settings.TopK, r.Settings.Candidates, r.Settings.MMRLambda, r.Settings.Reranker)
+	var recall, ndcg []string
+	for _, k := range cutoffs {
+		recall = append(recall, fmt.Sprintf("@%d=%.3f", k, s.Recall[k]))
+		ndcg = append(ndcg, fmt.Sprintf("@%d=%.3f", k, s.NDCG[k]))
+	}
+	fmt.Fprintf(w, "  recall   %s\n", strings.Join(recall, "  "))
+	fmt.Fprintf(w, "  ndcg     %s\n", strings.Join(ndcg, "  "))
+	fmt.Fprintf(w, "  mrr      %.3f\n", s.MRR)
+	fmt.Fprintf(w, "  context  %.3f\n", s.ContextRecall)
+	if s.AnswerF1 != nil {
+		fmt.Fprintf(w, "  answerF1 %.3f\n", *s.AnswerF1)
+	}
+	if s.JudgeScore != nil {
+		fmt.Fprintf(w, "  judge    %.3f\n", *s.JudgeScore)
+	}
+	fmt.Fprintf(w, "  latency  %.0fms avg\n", s.AvgLatencyMs)
+	for _, note := range r.Notes {
+		fmt.Fprintf(w, "  note: %s\n", note)
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 4, 64)
+}
+
+func formatOptional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatFloat(*v)
+}
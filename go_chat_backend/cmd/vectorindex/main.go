@@ -0,0 +1,71 @@
+// vectorindex 管理 chunks.embedding_vector 上的 pgvector ANN 索引
+//
+// 默认为当前配置的 embedding 模型创建缺失的索引；-rebuild 按当前 VECTOR_INDEX / HNSW_* / IVFFLAT_*
+// 参数并发重建索引（不阻塞读写），-all 处理所有已登记的模型。
+//
+//	go run ./cmd/vectorindex -rebuild
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go_chat_backend/config"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/database"
+	"go_chat_backend/repository"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	logging.Init()
+	_ = godotenv.Load()
+	cfg := config.LoadConfig()
+
+	rebuild := flag.Bool("rebuild", false, "rebuild the index concurrently with the current parameters")
+	all := flag.Bool("all", false, "process every registered embedding model instead of the configured one")
+	flag.Parse()
+
+	if err := run(cfg, *rebuild, *all); err != nil {
+		fmt.Fprintln(os.Stderr, "vectorindex:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg *config.Config, rebuild, all bool) error {
+	db, err := database.InitPostgres(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	targets := []*models.EmbeddingModel{{
+		ID:        models.EmbeddingModelID(cfg.EmbeddingModel, cfg.EmbeddingModelVersion),
+		Dimension: cfg.EmbeddingDimension,
+	}}
+	if all {
+		if targets, err = repository.NewEmbeddingModelRepository(db.GetDatabase()).List(ctx); err != nil {
+			return err
+		}
+	}
+
+	index := db.VectorIndex()
+	for _, model := range targets {
+		name := database.VectorIndexName(model.ID)
+		if rebuild {
+			err = db.RebuildVectorIndex(ctx, model.ID, model.Dimension)
+		} else {
+			err = db.EnsureVectorIndex(ctx, model.ID, model.Dimension)
+		}
+		if err != nil {
+			return fmt.Errorf("%s (%s): %w", name, model.ID, err)
+		}
+		fmt.Printf("%s: %s index on model %s (dimension %d)\n", name, index.Type, model.ID, model.Dimension)
+	}
+	fmt.Printf("search settings: %v (per-document searches use the index: %v)\n", index.SearchSettings(), index.FilteredSearch())
+	return nil
+}
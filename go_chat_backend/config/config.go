@@ -2,11 +2,14 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"time"
 )
 
 type Config struct {
 	HttpPort string
+	// MetricsAddr 内部监听地址，提供运行时指标（/debug/vars）；为空时不提供
+	MetricsAddr string
 	// S3/MinIO
 	BucketEndpoint  string
 	BucketAccessID  string
@@ -14,7 +17,12 @@ type Config struct {
 	BucketName      string
 	BucketRegion    string
 	UseSSL          bool   // MinIO: false, S3: true
-	StorageType     string //"minio" or "s3"
+	StorageType     string //"minio", "s3" or "local"
+
+	// local storage：文件存放在本地目录，上传/下载走本服务的签名链接
+	LocalStorageDir   string
+	StorageSigningKey string
+	PublicBaseURL     string // 签名链接的前缀，浏览器和 ETL worker 需要能访问
 
 	// Redis
 	RedisURL      string
@@ -31,33 +39,164 @@ type Config struct {
 	UploadTimeout time.Duration
 	MaxFileSize   int64
 
+	// presigned GET URL 有效期：下载接口返回给用户的和 ETL worker 取出任务时通过 GetTaskURL 获取的
+	DownloadURLTTL time.Duration
+	TaskURLTTL     time.Duration
+
+	// deletion
+	TrashRetention      time.Duration // 回收站保留期，0 表示删除后立即清理
+	DeletionInterval    time.Duration
+	ReplacedChunksGrace time.Duration // 重新处理后旧 chunks 保留多久再由删除任务清理，0 表示切换后立即删除
+
+	// janitor：清理放弃的上传、卡住的处理和存储中的孤儿对象
+	JanitorInterval      time.Duration // 0 表示不运行
+	AbandonedUploadAfter time.Duration // 创建后超过该时间仍没有文件的上传视为放弃
+	StaleProcessingAfter time.Duration // 开始处理后超过该时间仍未完成视为卡住
+
+	// ingest：流中的 chunks 攒够 IngestBatchSize 个或等待超过 IngestFlushInterval 时批量写入
+	IngestBatchSize     int
+	IngestFlushInterval time.Duration
+	IngestContextTTL    time.Duration // 处理上下文超过该时间没有收到 chunk 时清理，0 表示只在流结束时清理
+
 	// grpc
 	GoGrpcIngestPort  string
 	GrpcServerAddr    string
 	GrpcEmbeddingAddr string
+	GrpcRerankAddr    string
+
+	// embedding
+	EmbeddingModel        string
+	EmbeddingModelVersion string
+	EmbeddingDimension    int
+	ReembedInterval       time.Duration // 0 表示不运行重新向量化任务
+	EmbeddingTimeout      time.Duration
+	EmbeddingMaxRetries   int
+	EmbeddingBatchSize    int
+	EmbeddingCacheTTL     time.Duration
+
+	// retrieval
+	RetrievalTopK    int
+	Reranker         string // "none", "grpc" or "llm"
+	RerankCandidates int
+	RerankTimeout    time.Duration
+	QueryRewrite     bool
+	// QueryRewriteTimeout 查询改写（含同义改写、HyDE）的 LLM 调用超时，超时后使用原问题
+	QueryRewriteTimeout time.Duration
+	QueryParaphrases    int
+	QueryHyDE           bool
+	MMRLambda           float64
+
+	// vector store
+	VectorStore       string // "pgvector" or "memory"
+	MemoryVectorLimit int    // memory：缓存的向量数上限，超过后淘汰最久未搜索的文件；0 表示不限制
+
+	// pgvector ANN index
+	VectorIndex         string // "hnsw", "ivfflat" or "none"
+	HNSWM               int
+	HNSWEfConstruction  int
+	IVFFlatLists        int
+	VectorSearchRecall  string // "fast", "balanced" or "accurate"
+	HNSWEfSearch        int    // 0 表示按 VectorSearchRecall 推导
+	IVFFlatProbes       int    // 0 表示按 VectorSearchRecall 推导
+	VectorIterativeScan string // "auto"（pgvector >= 0.8 时开启）、"true" 或 "false"；开启后按文件的检索也走 ANN 索引
 }
 
 func LoadConfig() *Config {
+	httpPort := getEnv("PORT", "3000")
 	return &Config{
-		HttpPort:          os.Getenv("PORT"),
-		BucketEndpoint:    os.Getenv("BUCKET_ENDPOINT"),
-		BucketAccessID:    os.Getenv("BUCKET_ACCESS_ID"),
-		BucketAccessKey:   os.Getenv("BUCKET_ACCESS_KEY"),
-		BucketName:        os.Getenv("BUCKET_NAME"),
-		BucketRegion:      os.Getenv("BUCKET_REGION"),
-		RedisURL:          os.Getenv("REDIS_URL"),
-		UseSSL:            os.Getenv("BUCKET_USE_SSL") == "true",
-		StorageType:       os.Getenv("STORAGE_TYPE"),
-		RedisPassword:     os.Getenv("REDIS_PASSWORD"),
-		UploadTimeout:     15 * time.Minute,
-		MaxFileSize:       50 * 1024 * 1024,
-		Host:              os.Getenv("PG_HOST"),
-		User:              os.Getenv("PG_USER"),
-		Password:          os.Getenv("PG_PASSWORD"),
-		DBName:            os.Getenv("PG_DB"),
-		Port:              os.Getenv("PG_PORT"),
-		GoGrpcIngestPort:  os.Getenv("GO_GRPC_INGEST_PORT"),
-		GrpcServerAddr:    os.Getenv("GRPC_SERVER_ADDR"),
-		GrpcEmbeddingAddr: os.Getenv("GRPC_EMBEDDING_ADDR"),
+		HttpPort:              httpPort,
+		MetricsAddr:           os.Getenv("METRICS_ADDR"),
+		BucketEndpoint:        os.Getenv("BUCKET_ENDPOINT"),
+		BucketAccessID:        os.Getenv("BUCKET_ACCESS_ID"),
+		BucketAccessKey:       os.Getenv("BUCKET_ACCESS_KEY"),
+		BucketName:            os.Getenv("BUCKET_NAME"),
+		BucketRegion:          os.Getenv("BUCKET_REGION"),
+		RedisURL:              os.Getenv("REDIS_URL"),
+		UseSSL:                os.Getenv("BUCKET_USE_SSL") == "true",
+		StorageType:           os.Getenv("STORAGE_TYPE"),
+		RedisPassword:         os.Getenv("REDIS_PASSWORD"),
+		UploadTimeout:         15 * time.Minute,
+		MaxFileSize:           50 * 1024 * 1024,
+		Host:                  os.Getenv("PG_HOST"),
+		User:                  os.Getenv("PG_USER"),
+		Password:              os.Getenv("PG_PASSWORD"),
+		DBName:                os.Getenv("PG_DB"),
+		Port:                  os.Getenv("PG_PORT"),
+		GoGrpcIngestPort:      os.Getenv("GO_GRPC_INGEST_PORT"),
+		IngestBatchSize:       getEnvInt("INGEST_BATCH_SIZE", 50),
+		IngestFlushInterval:   getEnvDuration("INGEST_FLUSH_INTERVAL", 2*time.Second),
+		IngestContextTTL:      getEnvDuration("INGEST_CONTEXT_TTL", 30*time.Minute),
+		GrpcServerAddr:        os.Getenv("GRPC_SERVER_ADDR"),
+		GrpcEmbeddingAddr:     os.Getenv("GRPC_EMBEDDING_ADDR"),
+		GrpcRerankAddr:        os.Getenv("GRPC_RERANK_ADDR"),
+		EmbeddingModel:        getEnv("EMBEDDING_MODEL", "paraphrase-multilingual-MiniLM-L12-v2"),
+		EmbeddingModelVersion: getEnv("EMBEDDING_MODEL_VERSION", "1"),
+		EmbeddingDimension:    getEnvInt("EMBEDDING_DIMENSION", 384),
+		ReembedInterval:       getEnvDuration("REEMBED_INTERVAL", 10*time.Minute),
+		EmbeddingTimeout:      getEnvDuration("EMBEDDING_TIMEOUT", 5*time.Second),
+		EmbeddingMaxRetries:   getEnvInt("EMBEDDING_MAX_RETRIES", 2),
+		EmbeddingBatchSize:    getEnvInt("EMBEDDING_BATCH_SIZE", 32),
+		EmbeddingCacheTTL:     getEnvDuration("EMBEDDING_CACHE_TTL", 24*time.Hour),
+		RetrievalTopK:         getEnvInt("RETRIEVAL_TOP_K", 3),
+		Reranker:              getEnv("RERANKER", "none"),
+		RerankCandidates:      getEnvInt("RERANK_CANDIDATES", 20),
+		RerankTimeout:         getEnvDuration("RERANK_TIMEOUT", 2*time.Second),
+		QueryRewrite:          os.Getenv("QUERY_REWRITE") == "true",
+		QueryRewriteTimeout:   getEnvDuration("QUERY_REWRITE_TIMEOUT", 3*time.Second),
+		QueryParaphrases:      getEnvInt("QUERY_PARAPHRASES", 0),
+		QueryHyDE:             os.Getenv("QUERY_HYDE") == "true",
+		VectorStore:           getEnv("VECTOR_STORE", "pgvector"),
+		MemoryVectorLimit:     getEnvInt("MEMORY_VECTOR_LIMIT", 100000),
+		VectorIndex:           getEnv("VECTOR_INDEX", "hnsw"),
+		HNSWM:                 getEnvInt("HNSW_M", 16),
+		HNSWEfConstruction:    getEnvInt("HNSW_EF_CONSTRUCTION", 64),
+		IVFFlatLists:          getEnvInt("IVFFLAT_LISTS", 100),
+		VectorSearchRecall:    getEnv("VECTOR_SEARCH_RECALL", "balanced"),
+		HNSWEfSearch:          getEnvInt("HNSW_EF_SEARCH", 0),
+		IVFFlatProbes:         getEnvInt("IVFFLAT_PROBES", 0),
+		VectorIterativeScan:   getEnv("VECTOR_ITERATIVE_SCAN", "auto"),
+		MMRLambda:             getEnvFloat("MMR_LAMBDA", 1),
+		DownloadURLTTL:        getEnvDuration("DOWNLOAD_URL_TTL", time.Hour),
+		TaskURLTTL:            getEnvDuration("TASK_URL_TTL", 2*time.Hour),
+		TrashRetention:        getEnvDuration("TRASH_RETENTION", 7*24*time.Hour),
+		DeletionInterval:      getEnvDuration("DELETION_INTERVAL", time.Minute),
+		ReplacedChunksGrace:   getEnvDuration("REPLACED_CHUNKS_GRACE", 10*time.Minute),
+		JanitorInterval:       getEnvDuration("JANITOR_INTERVAL", 10*time.Minute),
+		AbandonedUploadAfter:  getEnvDuration("ABANDONED_UPLOAD_AFTER", time.Hour),
+		StaleProcessingAfter:  getEnvDuration("STALE_PROCESSING_AFTER", 2*time.Hour),
+		LocalStorageDir:       getEnv("LOCAL_STORAGE_DIR", "./data/blobs"),
+		StorageSigningKey:     os.Getenv("STORAGE_SIGNING_KEY"),
+		PublicBaseURL:         getEnv("PUBLIC_BASE_URL", "http://localhost:"+httpPort),
+	}
+}
+
+func getEnv(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
 	}
+	return v
 }
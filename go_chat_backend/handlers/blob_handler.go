@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/storage"
+	"mime"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BlobHandler STORAGE_TYPE=local 时代替 MinIO/S3 处理签名上传和下载
+type BlobHandler struct {
+	store *storage.LocalStore
+}
+
+func NewBlobHandler(store *storage.LocalStore) *BlobHandler {
+	return &BlobHandler{store: store}
+}
+
+// Upload 接收与 S3 presigned POST 相同格式的表单：签名字段 + file
+func (h *BlobHandler) Upload(c *fiber.Ctx) error {
+	fields := map[string]string{}
+	for _, name := range []string{"key", "Content-Type", "max_size", "expires", "signature"} {
+		fields[name] = c.FormValue(name)
+	}
+	maxSize, err := h.store.VerifyUpload(fields)
+	if err != nil {
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+	// 签名限定了 Content-Type，上传的文件声明了其他类型时拒绝
+	if partType := fh.Header.Get(fiber.HeaderContentType); partType != "" && !sameMediaType(partType, fields["Content-Type"]) {
+		return c.Status(403).JSON(fiber.Map{"error": "content type does not match the signed upload"})
+	}
+	if fh.Size == 0 || (maxSize > 0 && fh.Size > maxSize) {
+		return c.Status(413).JSON(fiber.Map{"error": "file size out of range"})
+	}
+	f, err := fh.Open()
+	if err != nil {
+		logging.Logger.Error("fail Upload", "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	defer f.Close()
+	if err := h.store.Put(c.Context(), fields["key"], f, fh.Size, fields["Content-Type"]); err != nil {
+		logging.Logger.Error("fail Upload", "error", err, "key", fields["key"])
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to store file"})
+	}
+	return c.SendStatus(204)
+}
+
+// sameMediaType 比较媒体类型，忽略大小写和参数（如 charset）
+func sameMediaType(a, b string) bool {
+	ta, _, errA := mime.ParseMediaType(a)
+	tb, _, errB := mime.ParseMediaType(b)
+	return errA == nil && errB == nil && ta == tb
+}
+
+func (h *BlobHandler) Download(c *fiber.Ctx) error {
+	key := c.Query("key")
+	if err := h.store.VerifyDownload(key, c.Query("expires"), c.Query("signature")); err != nil {
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	}
+	info, err := h.store.Stat(c.Context(), key)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "object not found"})
+	}
+	if err != nil {
+		logging.Logger.Error("fail Download", "error", err, "key", key)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	r, err := h.store.Open(c.Context(), key)
+	if err != nil {
+		logging.Logger.Error("fail Download", "error", err, "key", key)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	if info.ContentType != "" {
+		c.Set(fiber.HeaderContentType, info.ContentType)
+	}
+	// SendStream 读完后关闭 r
+	return c.SendStream(r, int(info.Size))
+}
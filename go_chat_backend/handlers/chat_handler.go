@@ -30,3 +30,21 @@ func (h *ChatHandler) AskQuestions(c *fiber.Ctx) error {
 	}
 	return c.JSON(ans)
 }
+
+func (h *ChatHandler) DryRunRetrieval(c *fiber.Ctx) error {
+	docID := c.Params("doc_id")
+	var req models.ChatReq
+	if err := c.BodyParser(&req); err != nil {
+		logging.Logger.Error("fail Parsing Requests", "error", err)
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if req.Question == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "question is required"})
+	}
+	res, err := h.chatService.DryRunRetrieval(c.Context(), docID, req)
+	if err != nil {
+		logging.Logger.Error("fail DryRunRetrieval", "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to run retrieval"})
+	}
+	return c.JSON(res)
+}
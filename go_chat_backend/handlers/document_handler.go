@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"go_chat_backend/models"
 	"go_chat_backend/pkg/logging"
 	"go_chat_backend/services"
@@ -13,13 +14,21 @@ type DocHandler struct {
 	documentService  *services.DocumentService
 	grpcService      *services.GRPCService
 	llmConfigService *services.LLMConfigService
+	deletionService  *services.DeletionService
+	reprocessService *services.ReprocessService
+	backfillService  *services.RagBackfillService
+	chunkService     *services.ChunkService
 }
 
-func NewDocHandler(documentService *services.DocumentService, grpcService *services.GRPCService, llmConfigService *services.LLMConfigService) *DocHandler {
+func NewDocHandler(documentService *services.DocumentService, grpcService *services.GRPCService, llmConfigService *services.LLMConfigService, deletionService *services.DeletionService, reprocessService *services.ReprocessService, backfillService *services.RagBackfillService, chunkService *services.ChunkService) *DocHandler {
 	return &DocHandler{
 		documentService:  documentService,
 		grpcService:      grpcService,
 		llmConfigService: llmConfigService,
+		deletionService:  deletionService,
+		reprocessService: reprocessService,
+		backfillService:  backfillService,
+		chunkService:     chunkService,
 	}
 }
 
@@ -63,27 +72,12 @@ func (h *DocHandler) ConfirmUpload(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Document not found"})
 	}
 
-	// 保存用户的 LLM 配置到缓存（30分钟有效期）
-	if req.ApiKey != "" && req.Model != "" && req.Provider != "" {
-		llmConfig := &services.LLMConfig{
-			APIKey:   req.ApiKey,
-			Model:    req.Model,
-			Provider: req.Provider,
-			UserID:   docInfo.UserID,
-		}
-		if err := h.llmConfigService.SetUserLLMConfig(ctx, docInfo.UserID, llmConfig); err != nil {
-			logging.Logger.Error("fail to save LLM config", "error", err, "userID", docInfo.UserID)
-		} else {
-			logging.Logger.Info("LLM config saved",
-				"userID", docInfo.UserID,
-				"provider", req.Provider,
-				"model", req.Model,
-				"apiKey", services.MaskAPIKey(req.ApiKey),
-			)
-		}
-	}
+	h.saveLLMConfig(ctx, docInfo.UserID, req.ApiKey, req.Model, req.Provider)
 
 	res, err := h.documentService.ConfirmUpload(c.Context(), req)
+	if code := services.UploadErrorCode(err); code != "" {
+		return c.Status(uploadErrorStatus(err)).JSON(fiber.Map{"error": err.Error(), "code": code})
+	}
 	if err != nil {
 		logging.Logger.Error("fail ConfirmUpload", "error", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to confirm upload"})
@@ -91,6 +85,76 @@ func (h *DocHandler) ConfirmUpload(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+// DirectUpload multipart 直接上传，供无法走 presigned POST + confirm 的客户端使用
+func (h *DocHandler) DirectUpload(c *fiber.Ctx) error {
+	var req models.DirectUploadReq
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if req.UserID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+	file, err := fh.Open()
+	if err != nil {
+		logging.Logger.Error("fail DirectUpload", "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	defer file.Close()
+
+	h.saveLLMConfig(context.Background(), req.UserID, req.ApiKey, req.Model, req.Provider)
+
+	res, err := h.documentService.DirectUpload(c.Context(), req, fh.Filename, fh.Size, file)
+	if code := services.UploadErrorCode(err); code != "" {
+		return c.Status(uploadErrorStatus(err)).JSON(fiber.Map{"error": err.Error(), "code": code})
+	}
+	if err != nil {
+		logging.Logger.Error("fail DirectUpload", "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to upload document"})
+	}
+	return c.Status(201).JSON(res)
+}
+
+// uploadErrorStatus 上传校验错误对应的 HTTP 状态码
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUploadMissing):
+		return 409
+	case errors.Is(err, services.ErrFileTooLarge):
+		return 413
+	case errors.Is(err, services.ErrNotPDF), errors.Is(err, services.ErrEncryptedPDF):
+		return 415
+	default:
+		return 422
+	}
+}
+
+// saveLLMConfig 保存用户的 LLM 配置到缓存（30分钟有效期）
+func (h *DocHandler) saveLLMConfig(ctx context.Context, userID, apiKey, model, provider string) {
+	if apiKey == "" || model == "" || provider == "" {
+		return
+	}
+	llmConfig := &services.LLMConfig{
+		APIKey:   apiKey,
+		Model:    model,
+		Provider: provider,
+		UserID:   userID,
+	}
+	if err := h.llmConfigService.SetUserLLMConfig(ctx, userID, llmConfig); err != nil {
+		logging.Logger.Error("fail to save LLM config", "error", err, "userID", userID)
+		return
+	}
+	logging.Logger.Info("LLM config saved",
+		"userID", userID,
+		"provider", provider,
+		"model", model,
+		"apiKey", services.MaskAPIKey(apiKey),
+	)
+}
+
 func (h *DocHandler) GetToc(c *fiber.Ctx) error {
 	docID := c.Params("doc_id")
 	res, err := h.documentService.GetSections(c.Context(), docID)
@@ -100,3 +164,209 @@ func (h *DocHandler) GetToc(c *fiber.Ctx) error {
 	}
 	return c.JSON(res)
 }
+
+func (h *DocHandler) UpdateRetrievalSettings(c *fiber.Ctx) error {
+	docID := c.Params("doc_id")
+	var req models.RetrievalSettingsReq
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	userID := c.Query("user_id")
+	if userID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	err := h.documentService.UpdateRetrievalSettings(c.Context(), docID, userID, req)
+	if errors.Is(err, services.ErrDocumentNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Document not found"})
+	}
+	if errors.Is(err, services.ErrInvalidRetrievalSettings) {
+		return c.Status(400).JSON(fiber.Map{"error": "Failed to update retrieval settings", "details": err.Error()})
+	}
+	if err != nil {
+		logging.Logger.Error("fail UpdateRetrievalSettings", "error", err, "docID", docID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update retrieval settings"})
+	}
+	return c.JSON(fiber.Map{"doc_id": docID, "neighbor_window": req.NeighborWindow})
+}
+
+func (h *DocHandler) ListDocuments(c *fiber.Ctx) error {
+	var req models.DocumentListReq
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid query"})
+	}
+	if req.UserID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	res, err := h.documentService.ListDocuments(c.Context(), req)
+	if errors.Is(err, services.ErrInvalidListQuery) {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid query", "details": err.Error()})
+	}
+	if err != nil {
+		logging.Logger.Error("fail ListDocuments", "error", err, "userID", req.UserID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list documents"})
+	}
+	return c.JSON(res)
+}
+
+func (h *DocHandler) GetDocument(c *fiber.Ctx) error {
+	docID := c.Params("doc_id")
+	userID := c.Query("user_id")
+	if userID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	res, err := h.documentService.GetDocumentDetail(c.Context(), docID, userID)
+	if errors.Is(err, services.ErrDocumentNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Document not found"})
+	}
+	if err != nil {
+		logging.Logger.Error("fail GetDocument", "error", err, "docID", docID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to get document"})
+	}
+	return c.JSON(res)
+}
+
+// DeleteDocument 移入回收站；permanent=true 时立即清理
+func (h *DocHandler) DeleteDocument(c *fiber.Ctx) error {
+	docID := c.Params("doc_id")
+	userID := c.Query("user_id")
+	if userID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	job, err := h.deletionService.DeleteDocument(c.Context(), docID, userID, c.QueryBool("permanent"))
+	if errors.Is(err, services.ErrDocumentNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Document not found"})
+	}
+	if err != nil {
+		logging.Logger.Error("fail DeleteDocument", "error", err, "docID", docID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete document"})
+	}
+	return c.Status(202).JSON(job)
+}
+
+func (h *DocHandler) RestoreDocument(c *fiber.Ctx) error {
+	docID := c.Params("doc_id")
+	userID := c.Query("user_id")
+	if userID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	err := h.deletionService.RestoreDocument(c.Context(), docID, userID)
+	if errors.Is(err, services.ErrDocumentNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Document not found in trash"})
+	}
+	if errors.Is(err, services.ErrRestoreExpired) {
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		logging.Logger.Error("fail RestoreDocument", "error", err, "docID", docID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to restore document"})
+	}
+	return c.JSON(fiber.Map{"doc_id": docID, "status": "restored"})
+}
+
+func (h *DocHandler) ListTrash(c *fiber.Ctx) error {
+	userID := c.Query("user_id")
+	if userID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	res, err := h.deletionService.ListTrash(c.Context(), userID)
+	if err != nil {
+		logging.Logger.Error("fail ListTrash", "error", err, "userID", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list trash"})
+	}
+	return c.JSON(res)
+}
+
+// RetrySummary 摘要生成失败的文档用已存储的 chunks 重新生成摘要
+func (h *DocHandler) RetrySummary(c *fiber.Ctx) error {
+	docID := c.Params("doc_id")
+	userID := c.Query("user_id")
+	if userID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	summary, err := h.documentService.RetrySummary(c.Context(), docID, userID, h.chunkService)
+	if errors.Is(err, services.ErrDocumentNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Document not found"})
+	}
+	if errors.Is(err, services.ErrSummaryNotMissing) || errors.Is(err, models.ErrInvalidStatusTransition) {
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		logging.Logger.Error("fail RetrySummary", "error", err, "docID", docID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate summary"})
+	}
+	return c.JSON(fiber.Map{"doc_id": docID, "status": models.StatusCompleted, "summary": summary})
+}
+
+// ReprocessDocument 用存储中的原文件重新执行 ETL，完成后切换到新的 chunks，对话保留
+func (h *DocHandler) ReprocessDocument(c *fiber.Ctx) error {
+	docID := c.Params("doc_id")
+	var req models.ReprocessReq
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+	}
+	if req.UserID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	res, err := h.reprocessService.Reprocess(c.Context(), docID, req)
+	if errors.Is(err, services.ErrDocumentNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Document not found"})
+	}
+	if errors.Is(err, services.ErrDocumentBusy) || errors.Is(err, services.ErrDocumentNotCompleted) {
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		logging.Logger.Error("fail ReprocessDocument", "error", err, "docID", docID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to reprocess document"})
+	}
+	return c.Status(202).JSON(res)
+}
+
+// UpdateRagMode 切换 RAG 模式；需要回填向量时返回 202，进度通过文档事件推送
+func (h *DocHandler) UpdateRagMode(c *fiber.Ctx) error {
+	docID := c.Params("doc_id")
+	var req models.RagModeReq
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if req.UserID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	res, err := h.backfillService.SwitchRagMode(c.Context(), docID, req)
+	if errors.Is(err, services.ErrDocumentNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Document not found"})
+	}
+	if errors.Is(err, services.ErrDocumentBusy) {
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		logging.Logger.Error("fail UpdateRagMode", "error", err, "docID", docID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update RAG mode"})
+	}
+	if res.Status == "backfilling" {
+		return c.Status(202).JSON(res)
+	}
+	return c.JSON(res)
+}
+
+// DownloadDocument 返回新生成的下载链接；redirect=true 时直接重定向
+func (h *DocHandler) DownloadDocument(c *fiber.Ctx) error {
+	docID := c.Params("doc_id")
+	userID := c.Query("user_id")
+	if userID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id is required"})
+	}
+	res, err := h.documentService.GetDownloadURL(c.Context(), docID, userID)
+	if errors.Is(err, services.ErrDocumentNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Document not found"})
+	}
+	if err != nil {
+		logging.Logger.Error("fail DownloadDocument", "error", err, "docID", docID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate download URL"})
+	}
+	if c.QueryBool("redirect") {
+		return c.Redirect(res.URL, fiber.StatusFound)
+	}
+	return c.JSON(res)
+}
@@ -5,6 +5,7 @@ import (
 	"go_chat_backend/config"
 	"go_chat_backend/middleware"
 	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/storage"
 	"go_chat_backend/routes"
 	"os"
 	"os/signal"
@@ -36,21 +37,40 @@ func main() {
 	// https server
 	httpServer := fiber.New(fiber.Config{
 		AppName: "CogniCore API",
+		// PDF 直接上传到本服务的路由（本地存储的签名上传和 direct 上传）需要留出 multipart 开销，
+		// 其他路由由 middleware.BodyLimit 限制为默认大小
+		BodyLimit:         int(cfg.MaxFileSize) + 1024*1024,
+		StreamRequestBody: true,
 	})
 
 	// middleware
 	httpServer.Use(middleware.Logger())
+	httpServer.Use(middleware.BodyLimit(fiber.DefaultBodyLimit, storage.LocalUploadPath, "/api/pdf/direct"))
 	httpServer.Use(middleware.CORS())
 	routes.RegisterHealthRoutes(httpServer)
 	routes.RegisterDocumentRoutes(httpServer, app.Handlers.DocHandler)
 	routes.SetupWebSocketRoutes(httpServer, app.Handlers.WSHandler)
 	routes.RegisterChatRoutes(httpServer, app.Handlers.ChatHandler)
+	if app.Handlers.BlobHandler != nil {
+		routes.RegisterBlobRoutes(httpServer, app.Handlers.BlobHandler)
+	}
 
 	go func() {
 		if err := httpServer.Listen(":" + cfg.HttpPort); err != nil {
 			logging.Logger.Error("fail to listen", "error", err)
 		}
 	}()
+	// 运行时指标只在内部地址上提供
+	var metricsServer *fiber.App
+	if cfg.MetricsAddr != "" {
+		metricsServer = fiber.New(fiber.Config{AppName: "CogniCore metrics", DisableStartupMessage: true})
+		routes.RegisterMetricsRoutes(metricsServer)
+		go func() {
+			if err := metricsServer.Listen(cfg.MetricsAddr); err != nil {
+				logging.Logger.Error("fail to listen", "error", err, "addr", cfg.MetricsAddr)
+			}
+		}()
+	}
 	logging.Logger.Info("Application started",
 		"http_port", cfg.HttpPort,
 		"grpc_port", cfg.GoGrpcIngestPort,
@@ -65,6 +85,11 @@ func main() {
 		logging.Logger.Error("fail to shutdown http server", "error", err)
 		return
 	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(); err != nil {
+			logging.Logger.Error("fail to shutdown metrics server", "error", err)
+		}
+	}
 	err = app.Shutdown()
 	if err != nil {
 		logging.Logger.Error("fail to shutdown app", "error", err)
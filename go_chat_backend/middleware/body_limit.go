@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit 限制 exempt 以外路由的请求体大小。服务的 BodyLimit 按最大的上传设置，并以流的方式读取请求体
+// （StreamRequestBody）：声明的 Content-Length 超过 limit 的请求在读取请求体之前拒绝，没有声明长度的读取后再检查
+func BodyLimit(limit int, exempt ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if slices.Contains(exempt, c.Path()) {
+			return c.Next()
+		}
+		if n := c.Request().Header.ContentLength(); n > limit || (n < 0 && len(c.Body()) > limit) {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "request body too large"})
+		}
+		return c.Next()
+	}
+}
@@ -18,14 +18,84 @@ type UploadResp struct {
 }
 
 type ConfirmUploadReq struct {
-	DocId    string `json:"doc_id"`
-	ApiKey   string `json:"api_key"`
-	Provider string `json:"provider"`
-	Model    string `json:"model"`
-	RagMode  string `json:"rag_mode"`
+	DocId          string `json:"doc_id"`
+	ApiKey         string `json:"api_key"`
+	Provider       string `json:"provider"`
+	Model          string `json:"model"`
+	RagMode        string `json:"rag_mode"`
+	NeighborWindow int32  `json:"neighbor_window"`
+}
+
+// DirectUploadReq POST /api/pdf/direct 的表单字段（文件字段名为 file）
+type DirectUploadReq struct {
+	UserID         string `form:"user_id"`
+	ApiKey         string `form:"api_key"`
+	Provider       string `form:"provider"`
+	Model          string `form:"model"`
+	RagMode        string `form:"rag_mode"`
+	NeighborWindow int32  `form:"neighbor_window"`
 }
 type ConfirmUploadResp struct {
 	Message string `json:"message"`
 	DocId   string `json:"doc_id"`
 	Status  string `json:"status"`
 }
+
+type RetrievalSettingsReq struct {
+	NeighborWindow int32 `json:"neighbor_window"`
+}
+
+// DocumentListReq GET /api/pdf 的查询参数
+type DocumentListReq struct {
+	UserID        string `query:"user_id"`
+	Status        string `query:"status"`
+	Filename      string `query:"filename"`       // 文件名模糊匹配
+	CreatedAfter  string `query:"created_after"`  // RFC3339 或 2006-01-02
+	CreatedBefore string `query:"created_before"` // RFC3339 或 2006-01-02
+	Page          int    `query:"page"`
+	PageSize      int    `query:"page_size"`
+}
+
+type DocumentListResp struct {
+	Documents []*DocumentMeta `json:"documents"`
+	Total     int64           `json:"total"`
+	Page      int             `json:"page"`
+	PageSize  int             `json:"page_size"`
+}
+
+// DocumentDetailResp 文档详情：元数据 + 根节点摘要 + 新生成的下载链接
+type DocumentDetailResp struct {
+	*DocumentMeta
+	Summary     string    `json:"summary"`
+	DownloadURL string    `json:"download_url"`
+	URLExpires  time.Time `json:"download_url_expires"`
+}
+
+type ReprocessReq struct {
+	UserID            string `json:"user_id"`
+	RegenerateSummary bool   `json:"regenerate_summary"`
+}
+
+type ReprocessResp struct {
+	DocID        string `json:"doc_id"`
+	GenerationID string `json:"generation_id"`
+	Status       string `json:"status"`
+}
+
+type RagModeReq struct {
+	UserID  string `json:"user_id"`
+	RagMode bool   `json:"rag_mode"`
+}
+
+type RagModeResp struct {
+	DocID   string `json:"doc_id"`
+	RagMode bool   `json:"rag_mode"`
+	Status  string `json:"status"` // "updated" 或 "backfilling"
+	Missing int64  `json:"missing_embeddings,omitempty"`
+}
+
+type DownloadResp struct {
+	DocID     string    `json:"doc_id"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
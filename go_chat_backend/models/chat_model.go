@@ -27,11 +27,13 @@ type ChatReq struct {
 	CreatedAt time.Time
 	Provider  string
 	APIKey    string
+	Debug     bool // 返回模型实际看到的 prompt 和检索信息
 }
 
 type ChatRes struct {
-	ID       string        `json:"id"`
-	Answer   string        `json:"answer"`
-	Question string        `json:"question"`
-	Tree     *ChatTreeNode `json:"tree"`
+	ID       string          `json:"id"`
+	Answer   string          `json:"answer"`
+	Question string          `json:"question"`
+	Tree     *ChatTreeNode   `json:"tree"`
+	Debug    *RetrievalDebug `json:"debug,omitempty"`
 }
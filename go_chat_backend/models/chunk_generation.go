@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ChunkGeneration 文档的一次重新处理
+// 新的 chunks 以 generation ID 作为 file_id 写入，处理完成后文档的 ChunkSourceID 原子地切换到该 ID，
+// 之前的 chunks 记录在 ReplacedChunkSet，宽限期过后由删除任务在没有其他文档引用时删除；
+// ChatNode 仍挂在文档 ID 上，不受影响
+type ChunkGeneration struct {
+	ID                string     `gorm:"column:id;type:varchar(255);primaryKey" json:"id"`
+	DocID             string     `gorm:"column:doc_id;type:varchar(255);not null;index:idx_generation_doc" json:"doc_id"`
+	Status            string     `gorm:"column:status;type:varchar(50);index:idx_generation_status" json:"status"`
+	RegenerateSummary bool       `gorm:"column:regenerate_summary;type:boolean;default:false" json:"regenerate_summary"`
+	TotalPages        int32      `gorm:"column:total_pages;type:int" json:"total_pages"`
+	EstimatedChunks   int32      `gorm:"column:estimated_chunks;type:int" json:"estimated_chunks"`
+	ChunksReceived    int32      `gorm:"column:chunks_received;type:int;default:0" json:"chunks_received"`
+	ChunksStored      int32      `gorm:"column:chunks_stored;type:int;default:0" json:"chunks_stored"`
+	Error             string     `gorm:"column:error;type:text" json:"error,omitempty"`
+	CreatedAt         time.Time  `gorm:"column:created_at;type:timestamp" json:"created_at"`
+	CompletedAt       *time.Time `gorm:"column:completed_at;type:timestamp" json:"completed_at,omitempty"`
+	// ReplacedChunkSet 切换前文档使用的 chunks（file_id），ReplacedPurgeAfter 之后删除，删除后清空
+	ReplacedChunkSet   string     `gorm:"column:replaced_chunk_set;type:varchar(255)" json:"replaced_chunk_set,omitempty"`
+	ReplacedPurgeAfter *time.Time `gorm:"column:replaced_purge_after;type:timestamp;index:idx_generation_replaced_purge" json:"replaced_purge_after,omitempty"`
+}
+
+// TableName 指定表名
+func (ChunkGeneration) TableName() string {
+	return "chunk_generations"
+}
+
+// 重新处理状态
+const (
+	GenerationProcessing = "processing"
+	GenerationActive     = "active"  // 文档当前使用的 chunks
+	GenerationRetired    = "retired" // 已被更新的 generation 取代
+	GenerationFailed     = "failed"
+)
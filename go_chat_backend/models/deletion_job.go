@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// DeletionJob 文档删除任务
+// 文档先进入回收站（软删除），PurgeAfter 之后后台任务按 DeletionSteps 的顺序逐步清理；
+// Step 记录下一个要执行的步骤，某一步失败后下次从该步骤继续
+type DeletionJob struct {
+	DocID      string    `gorm:"column:doc_id;type:varchar(255);primaryKey" json:"doc_id"`
+	UserID     string    `gorm:"column:user_id;type:varchar(255);index:idx_deletion_user" json:"user_id"`
+	Filename   string    `gorm:"column:filename;type:varchar(512)" json:"filename"`
+	FileKey    string    `gorm:"column:file_key;type:varchar(255)" json:"file_key"`
+	ChunkSetID string    `gorm:"column:chunk_set_id;type:varchar(255)" json:"chunk_set_id"` // 文档 chunks 的 file_id，可能与其他文档共用
+	Step       string    `gorm:"column:step;type:varchar(50)" json:"step"`
+	Status     string    `gorm:"column:status;type:varchar(50);index:idx_deletion_status" json:"status"`
+	Attempts   int       `gorm:"column:attempts;type:int;default:0" json:"attempts"`
+	LastError  string    `gorm:"column:last_error;type:text" json:"last_error,omitempty"`
+	PurgeAfter time.Time `gorm:"column:purge_after;type:timestamp" json:"purge_after"`
+	CreatedAt  time.Time `gorm:"column:created_at;type:timestamp" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;type:timestamp" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DeletionJob) TableName() string {
+	return "deletion_jobs"
+}
+
+// 删除任务状态
+const (
+	DeletionPending = "pending" // 在回收站中，可以恢复
+	DeletionRunning = "running"
+	DeletionFailed  = "failed" // 某一步失败，等待重试
+	DeletionDone    = "done"
+)
+
+// 删除步骤，按顺序执行
+// 缓存先于 chat_nodes 清理：对话历史的缓存 key 需要节点 ID
+const (
+	DeleteStepCache     = "cache"
+	DeleteStepChatNodes = "chat_nodes"
+	DeleteStepChunks    = "chunks"
+	DeleteStepObject    = "object"
+	DeleteStepMeta      = "meta"
+)
+
+var DeletionSteps = []string{
+	DeleteStepCache,
+	DeleteStepChatNodes,
+	DeleteStepChunks,
+	DeleteStepObject,
+	DeleteStepMeta,
+}
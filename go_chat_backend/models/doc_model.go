@@ -13,7 +13,6 @@ type EtlTask struct {
 	FileName  string
 	UserID    string
 	CreatedAt time.Time
-	URL       string
 	RagMode   string
 }
 
@@ -23,29 +22,56 @@ type DocumentMeta struct {
 
 	// 基本信息字段
 	UserID          string         `gorm:"column:user_id;type:varchar(255);not null;index:idx_user_id" json:"user_id"`
-	URL             string         `gorm:"column:url;type:text;not null" json:"url"`
 	Filename        string         `gorm:"column:filename;type:varchar(512);not null" json:"filename"`
 	TotalPages      int32          `gorm:"column:total_pages;type:int" json:"total_pages"`
 	EstimatedChunks int32          `gorm:"column:estimated_chunks;type:int" json:"estimated_chunks"`
-	FileHash        string         `gorm:"column:file_hash;type:varchar(64);uniqueIndex:idx_file_hash" json:"file_hash"`
+	FileHash        string         `gorm:"column:file_hash;type:varchar(64);index:idx_file_hash" json:"file_hash"`
 	FileSize        int64          `gorm:"column:file_size;type:bigint" json:"file_size"`
 	CreatedAt       time.Time      `gorm:"column:created_at;type:timestamp" json:"created_at"`
 	FileKey         string         `gorm:"column:file_key;type:varchar(255);not null;index:idx_file_key" json:"file_key"`
 	Root            string         `gorm:"column:root;type:varchar(255);index:idx_root" json:"root"`
 	Sections        pq.StringArray `gorm:"column:sections;type:text[]" json:"sections"`
+	// chunks 所在的 file_id：内容相同的文档共用一份 chunks 时指向源文档，重新处理后指向 ChunkGeneration；
+	// 为空表示使用文档自己的 file_id
+	ChunkSourceID string `gorm:"column:chunk_source_id;type:varchar(255);index:idx_chunk_source" json:"chunk_source_id,omitempty"`
 
 	// RAG 配置字段
 	RagMode bool `gorm:"column:rag_mode;type:boolean;default:false" json:"rag_mode"`
+	// 命中 chunk 前后各扩展的相邻 chunk 数量（同一章节内）
+	NeighborWindow int32 `gorm:"column:neighbor_window;type:int;default:0" json:"neighbor_window"`
+	// 文档 chunks 当前使用的 embedding 模型（name@version），与激活模型不同时由后台任务重新向量化
+	EmbeddingModel string `gorm:"column:embedding_model;type:varchar(255)" json:"embedding_model"`
+	// 开启 RAG 时的向量回填：running 期间 RagBackfillAt 作为心跳，实例中断、心跳超时的回填由后台任务接管继续；
+	// 关闭 RAG 时清空，进行中的回填随之停止且不会再开启 RAG
+	RagBackfillStatus string     `gorm:"column:rag_backfill_status;type:varchar(20);index:idx_rag_backfill" json:"rag_backfill_status,omitempty"`
+	RagBackfillAt     *time.Time `gorm:"column:rag_backfill_at;type:timestamp" json:"rag_backfill_at,omitempty"`
 
 	// 状态追踪字段
 	Status         string `gorm:"column:status;type:varchar(50);default:'processing';index:idx_status" json:"status"`
 	ChunksReceived int32  `gorm:"column:chunks_received;type:int;default:0" json:"chunks_received"`
 	ChunksStored   int32  `gorm:"column:chunks_stored;type:int;default:0" json:"chunks_stored"`
 	ChunksFailed   int32  `gorm:"column:chunks_failed;type:int;default:0" json:"chunks_failed"`
+	// ingest 流正常结束（EOF）时 ETL 发送的 chunk 总数（chunk_index 为 0..ChunksTotal-1）；
+	// 0 表示流没有正常结束，此时不接受单个 chunk 重试
+	ChunksTotal   int32  `gorm:"column:chunks_total;type:int;default:0" json:"chunks_total"`
+	FailureReason string `gorm:"column:failure_reason;type:text" json:"failure_reason,omitempty"`
 
 	// 时间戳字段
 	StartedAt   *time.Time `gorm:"column:started_at;type:timestamp;default:now()" json:"started_at"`
 	CompletedAt *time.Time `gorm:"column:completed_at;type:timestamp" json:"completed_at,omitempty"`
+	// 软删除：进入回收站的文档不再出现在查询中，清理前可以恢复
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index:idx_deleted_at" json:"deleted_at,omitempty"`
+}
+
+// DocumentFilter 文档列表查询条件，零值字段不过滤
+type DocumentFilter struct {
+	UserID        string
+	Status        string
+	Filename      string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Offset        int
+	Limit         int
 }
 
 // TableName 指定表名
@@ -60,6 +86,18 @@ const (
 	StatusFailed     = "failed"
 )
 
+// StatusCompletedWithoutSummary 处理完成但摘要生成失败，chunks 可用
+const StatusCompletedWithoutSummary = "completed_without_summary"
+
+// StatusFinalizing chunks 已全部存储，正在生成摘要；只有转换到该状态的请求执行收尾
+const StatusFinalizing = "finalizing"
+
+// RAG 回填状态，空表示没有回填
+const (
+	RagBackfillRunning = "running"
+	RagBackfillFailed  = "failed"
+)
+
 // BeforeCreate GORM 钩子：创建前设置默认值
 func (d *DocumentMeta) BeforeCreate(tx *gorm.DB) error {
 	if d.Status == "" {
@@ -75,6 +113,14 @@ func (d *DocumentMeta) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// ChunkFileID chunks 表中保存该文档 chunks 使用的 file_id
+func (d *DocumentMeta) ChunkFileID() string {
+	if d.ChunkSourceID != "" {
+		return d.ChunkSourceID
+	}
+	return d.FileID
+}
+
 // IsCompleted 检查文档是否已完成
 func (d *DocumentMeta) IsCompleted() bool {
 	return d.Status == StatusCompleted
@@ -85,9 +131,9 @@ func (d *DocumentMeta) IsFailed() bool {
 	return d.Status == StatusFailed
 }
 
-// IsProcessing 检查文档是否正在处理
+// IsProcessing 检查文档是否正在处理（包括收尾）
 func (d *DocumentMeta) IsProcessing() bool {
-	return d.Status == StatusProcessing
+	return d.Status == StatusProcessing || d.Status == StatusFinalizing
 }
 
 // MarkAsCompleted 标记文档为已完成
@@ -117,15 +163,18 @@ type Chunk struct {
 	ChunkID string `gorm:"column:chunk_id;type:varchar(255);primaryKey" json:"chunk_id"`
 
 	// 外键字段
-	FileID string `gorm:"column:file_id;type:varchar(255);not null;index:idx_file_id" json:"file_id"`
+	FileID string `gorm:"column:file_id;type:varchar(255);not null;index:idx_file_id;uniqueIndex:idx_chunk_file_index,priority:1" json:"file_id"`
 
 	// 基本信息字段
-	ChunkIndex int32  `gorm:"column:chunk_index;type:int;not null" json:"chunk_index"`
+	// 同一文件的 chunk_index 唯一，重试的流按 (file_id, chunk_index) 覆盖写入
+	ChunkIndex int32  `gorm:"column:chunk_index;type:int;not null;uniqueIndex:idx_chunk_file_index,priority:2" json:"chunk_index"`
 	Chapter    string `gorm:"column:chapter;type:varchar(512)" json:"chapter"`
 	ChunkText  string `gorm:"column:chunk_text;type:text;not null" json:"chunk_text"`
 
-	// 向量字段（pgvector）
-	EmbeddingVector pgvector.Vector `gorm:"column:embedding_vector;type:vector(384)" json:"embedding_vector"`
+	// 向量字段（pgvector），不固定维度，维度由 EmbeddingModel 决定
+	EmbeddingVector pgvector.Vector `gorm:"column:embedding_vector;type:vector" json:"embedding_vector"`
+	// 生成向量的模型（name@version），检索时只比较同一模型的向量
+	EmbeddingModel string `gorm:"column:embedding_model;type:varchar(255);index:idx_chunk_embedding_model" json:"embedding_model"`
 
 	// 时间戳字段
 	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;default:now()" json:"created_at"`
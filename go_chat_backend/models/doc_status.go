@@ -0,0 +1,64 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrInvalidStatusTransition 状态机不允许的文档状态转换
+var ErrInvalidStatusTransition = errors.New("invalid document status transition")
+
+// statusTransitions 文档状态机，key 为当前状态，value 为允许转换到的状态
+//
+//	processing -> finalizing / failed
+//	processing -> processing               ETL 重新开始同一个文档的流
+//	processing -> completed / completed_without_summary
+//	                                       复用内容相同文档的处理结果（源文档没有摘要或复制失败时没有摘要）
+//	finalizing -> completed / completed_without_summary / failed
+//	failed -> processing                   ETL 重试
+//	failed -> finalizing                   单个 chunk 重试补齐后开始收尾
+//	completed_without_summary -> completed 补上摘要（POST /api/pdf/:doc_id/summary，或带 regenerate_summary 重新处理）
+var statusTransitions = map[string][]string{
+	StatusProcessing:              {StatusProcessing, StatusFinalizing, StatusCompleted, StatusCompletedWithoutSummary, StatusFailed},
+	StatusFinalizing:              {StatusCompleted, StatusCompletedWithoutSummary, StatusFailed},
+	StatusFailed:                  {StatusProcessing, StatusFinalizing},
+	StatusCompletedWithoutSummary: {StatusCompleted},
+}
+
+// ActiveStatuses 还在处理、尚未到达终态的状态
+var ActiveStatuses = []string{StatusProcessing, StatusFinalizing}
+
+// CompletedStatuses 处理完成、chunks 可用的状态
+var CompletedStatuses = []string{StatusCompleted, StatusCompletedWithoutSummary}
+
+// CheckStatusTransition 检查 from -> to 是否合法
+func CheckStatusTransition(from, to string) error {
+	if !slices.Contains(statusTransitions[from], to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, from, to)
+	}
+	return nil
+}
+
+// StatusTransitionFields 转换到 to 时一起更新的字段：
+// 进入 processing 时记录开始时间并清空上次的结果，进入终态时记录完成时间，失败时记录原因；
+// finalizing 只更新状态
+func StatusTransitionFields(to string, now time.Time, reason string) map[string]interface{} {
+	fields := map[string]interface{}{"status": to}
+	switch to {
+	case StatusProcessing:
+		fields["started_at"] = now
+		fields["chunks_total"] = 0
+		fields["completed_at"] = nil
+		fields["failure_reason"] = ""
+	case StatusFinalizing:
+	case StatusFailed:
+		fields["completed_at"] = now
+		fields["failure_reason"] = reason
+	default:
+		fields["completed_at"] = now
+		fields["failure_reason"] = ""
+	}
+	return fields
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// EmbeddingModel 已登记的 embedding 模型
+// 同一时刻只有一个激活模型，新写入的 chunk 和查询都使用它
+type EmbeddingModel struct {
+	ID        string    `gorm:"column:id;type:varchar(255);primaryKey" json:"id"` // name@version
+	Name      string    `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	Version   string    `gorm:"column:version;type:varchar(64);not null" json:"version"`
+	Dimension int       `gorm:"column:dimension;type:int;not null" json:"dimension"`
+	Active    bool      `gorm:"column:active;type:boolean;default:false" json:"active"`
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;default:now()" json:"created_at"`
+}
+
+// TableName 指定表名
+func (EmbeddingModel) TableName() string {
+	return "embedding_models"
+}
+
+// EmbeddingModelID 由模型名和版本组成模型 ID
+func EmbeddingModelID(name, version string) string {
+	return name + "@" + version
+}
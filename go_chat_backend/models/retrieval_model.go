@@ -0,0 +1,79 @@
+package models
+
+// RetrievedPassage 检索得到的一段连续上下文
+// 由命中的 chunk 及其同章节相邻 chunk 合并而成
+type RetrievedPassage struct {
+	FileID      string   `json:"file_id"`
+	Chapter     string   `json:"chapter"`
+	StartIndex  int32    `json:"start_index"`
+	EndIndex    int32    `json:"end_index"`
+	ChunkIDs    []string `json:"chunk_ids"`
+	HitChunkIDs []string `json:"hit_chunk_ids"`
+	Text        string   `json:"text"`
+}
+
+// ScoredChunk 带分数的 chunk（相似度或重排序分数）
+type ScoredChunk struct {
+	Chunk *Chunk  `json:"chunk"`
+	Score float64 `json:"score"`
+}
+
+// RetrievalResult 检索结果及调试信息
+type RetrievalResult struct {
+	Passages []*RetrievedPassage `json:"passages"`
+	Trace    *RetrievalTrace     `json:"trace"`
+}
+
+// RetrievalTrace 记录一次检索中每个候选的打分与选择过程
+type RetrievalTrace struct {
+	Queries        []string          `json:"queries"`
+	Reranker       string            `json:"reranker"`
+	RerankFallback bool              `json:"rerank_fallback"`
+	MMRLambda      float64           `json:"mmr_lambda,omitempty"`
+	Candidates     []*CandidateTrace `json:"candidates"`
+	Timings        RetrievalTimings  `json:"timings"`
+}
+
+// RetrievalTimings 检索各阶段耗时（毫秒）
+type RetrievalTimings struct {
+	RewriteMs   int64 `json:"rewrite_ms"`
+	EmbeddingMs int64 `json:"embedding_ms"`
+	SearchMs    int64 `json:"search_ms"`
+	RerankMs    int64 `json:"rerank_ms"`
+	ExpandMs    int64 `json:"expand_ms"`
+}
+
+// CandidateTrace 单个候选 chunk 的打分
+type CandidateTrace struct {
+	ChunkID     string   `json:"chunk_id"`
+	ChunkIndex  int32    `json:"chunk_index"`
+	Chapter     string   `json:"chapter"`
+	Similarity  float64  `json:"similarity"`
+	RerankScore *float64 `json:"rerank_score,omitempty"`
+	MMRScore    *float64 `json:"mmr_score,omitempty"`
+	Selected    bool     `json:"selected"`
+	// SelectionRank 被选中的顺序（从 1 开始），未选中为 0
+	SelectionRank int `json:"selection_rank"`
+}
+
+// RetrievalDebug 模型实际看到的上下文，用于排查答案错误来自检索还是模型
+type RetrievalDebug struct {
+	RagMode        bool                `json:"rag_mode"`
+	Prompt         string              `json:"prompt"`
+	Messages       []ChatMessage       `json:"messages,omitempty"`
+	SectionContext string              `json:"section_context,omitempty"`
+	Passages       []*RetrievedPassage `json:"passages"`
+	Trace          *RetrievalTrace     `json:"trace,omitempty"`
+	RetrievalError string              `json:"retrieval_error,omitempty"` // 检索失败时的原因，prompt 中没有检索上下文
+	Tokens         TokenEstimate       `json:"tokens"`
+	LLMMs          int64               `json:"llm_ms"`
+	TotalMs        int64               `json:"total_ms"`
+}
+
+// TokenEstimate prompt 各部分的 token 估算
+type TokenEstimate struct {
+	Prompt           int `json:"prompt"`
+	SectionContext   int `json:"section_context"`
+	RetrievedContext int `json:"retrieved_context"`
+	History          int `json:"history"`
+}
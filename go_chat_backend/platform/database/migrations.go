@@ -0,0 +1,145 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"go_chat_backend/pkg/logging"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrMigrationIrreversible 迁移没有回滚步骤
+var ErrMigrationIrreversible = errors.New("migration cannot be rolled back")
+
+// schemaMigration 已执行的一次性迁移
+type schemaMigration struct {
+	ID        string    `gorm:"column:id;primaryKey;type:varchar(128)"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// migration AutoMigrate 无法表达的结构变更（删除列、修改索引）；Up 与执行记录在同一个事务中提交，
+// 每个迁移只执行一次。Down 为 nil 表示不可回滚
+type migration struct {
+	ID   string
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error
+}
+
+// migrations 按顺序执行，已发布的迁移不要修改或删除
+var migrations = []migration{
+	{
+		// 早期版本的 idx_file_hash 是唯一索引，相同内容无法被多个文档上传；重建为普通索引。
+		// 之后可能已有重复的 file_hash，无法恢复唯一索引
+		ID: "0001_non_unique_file_hash_index",
+		Up: func(tx *gorm.DB) error {
+			var unique bool
+			err := tx.Raw(
+				"SELECT i.indisunique FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid WHERE c.relname = 'idx_file_hash'",
+			).Scan(&unique).Error
+			if err != nil || !unique {
+				return err
+			}
+			if err := tx.Exec("DROP INDEX idx_file_hash").Error; err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX idx_file_hash ON document_meta (file_hash)").Error
+		},
+	},
+	{
+		// 早期版本在 document_meta.url 保存上传时生成的 presigned URL，15 分钟后即失效；file_key 始终与其一同写入，
+		// 链接改为按需生成后不再写入该列（NOT NULL 会让新记录无法写入）。回滚恢复为可空的列，旧链接无法恢复
+		ID: "0002_drop_document_url",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn("document_meta", "url") {
+				return nil
+			}
+			return tx.Exec("ALTER TABLE document_meta DROP COLUMN url").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE document_meta ADD COLUMN IF NOT EXISTS url text").Error
+		},
+	},
+}
+
+// runMigrations 执行尚未执行的迁移；在 AutoMigrate 之后调用，迁移可以依赖最新的表结构
+func (db *DB) runMigrations() error {
+	if err := db.database.AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if applied[m.ID] {
+			continue
+		}
+		logging.Logger.Info("applying migration", "migration", m.ID)
+		err := db.database.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// RollbackMigration 撤销一个已执行的迁移并删除其执行记录，下次启动时会重新执行
+func (db *DB) RollbackMigration(id string) error {
+	for _, m := range migrations {
+		if m.ID != id {
+			continue
+		}
+		if m.Down == nil {
+			return fmt.Errorf("%w: %s", ErrMigrationIrreversible, id)
+		}
+		return db.database.Transaction(func(tx *gorm.DB) error {
+			res := tx.Where("id = ?", id).Delete(&schemaMigration{})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return fmt.Errorf("migration %s has not been applied", id)
+			}
+			return m.Down(tx)
+		})
+	}
+	return fmt.Errorf("unknown migration %s", id)
+}
+
+// MigrationStatus 按执行顺序返回所有迁移及其是否已执行
+func (db *DB) MigrationStatus() ([]string, map[string]bool, error) {
+	if err := db.database.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, nil, err
+	}
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]string, len(migrations))
+	for i, m := range migrations {
+		ids[i] = m.ID
+	}
+	return ids, applied, nil
+}
+
+func (db *DB) appliedMigrations() (map[string]bool, error) {
+	var rows []schemaMigration
+	if err := db.database.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		applied[r.ID] = true
+	}
+	return applied, nil
+}
@@ -11,7 +11,8 @@ import (
 )
 
 type DB struct {
-	database *gorm.DB
+	database    *gorm.DB
+	vectorIndex VectorIndexConfig
 }
 
 func InitPostgres(cfg *config.Config) (*DB, error) {
@@ -38,7 +39,10 @@ func InitPostgres(cfg *config.Config) (*DB, error) {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	DB := &DB{database: db}
+	DB := &DB{database: db, vectorIndex: NewVectorIndexConfig(cfg)}
+	if cfg.VectorIterativeScan == "auto" {
+		DB.vectorIndex.IterativeScan = DB.iterativeScanSupported()
+	}
 	fmt.Println("Connected to Postgres")
 
 	return DB, nil
@@ -56,9 +60,26 @@ func (db *DB) AutoMigrate() error {
 		logging.Logger.Error("auto migration failed", "error", err)
 		return err
 	}
+	if err := db.database.AutoMigrate(&models.EmbeddingModel{}); err != nil {
+		logging.Logger.Error("auto migration failed", "error", err)
+		return err
+	}
+	if err := db.database.AutoMigrate(&models.ChunkGeneration{}); err != nil {
+		logging.Logger.Error("auto migration failed", "error", err)
+		return err
+	}
+	if err := db.database.AutoMigrate(&models.DeletionJob{}); err != nil {
+		logging.Logger.Error("auto migration failed", "error", err)
+		return err
+	}
+	if err := db.runMigrations(); err != nil {
+		logging.Logger.Error("schema migration failed", "error", err)
+		return err
+	}
 
 	return nil
 }
+
 func (db *DB) Close() error {
 	sqlDB, err := db.database.DB()
 	if err != nil {
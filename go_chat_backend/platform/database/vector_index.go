@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go_chat_backend/config"
+	"go_chat_backend/pkg/logging"
+	"math"
+	"strings"
+)
+
+// VectorIndexConfig chunks.embedding_vector 上的 ANN 索引参数
+//
+// embedding_vector 不固定维度，所以每个 embedding 模型单独建一个部分索引：
+// 索引表达式把向量转换为该模型的维度，WHERE embedding_model = 模型 ID。
+// 查询必须使用同样的表达式和条件才能命中索引（见 VectorExpr）。
+type VectorIndexConfig struct {
+	Type           string // "hnsw", "ivfflat" 或 "none"
+	M              int    // hnsw: 每层最大连接数
+	EfConstruction int    // hnsw: 构建时候选列表大小
+	Lists          int    // ivfflat: 聚类数
+	Recall         string // "fast", "balanced", "accurate"
+	EfSearch       int    // hnsw: 查询时候选列表大小，0 表示按 Recall 推导
+	Probes         int    // ivfflat: 查询时探测的聚类数，0 表示按 Recall 推导
+	// IterativeScan 索引扫描结果被过滤后不足 limit 时继续扫描（pgvector >= 0.8）。
+	// 只有开启时按文件的检索才走 ANN 索引，否则索引只返回全局最近的 ef_search 行，按 file_id 过滤后所剩无几
+	IterativeScan bool
+}
+
+func NewVectorIndexConfig(cfg *config.Config) VectorIndexConfig {
+	return VectorIndexConfig{
+		Type:           strings.ToLower(cfg.VectorIndex),
+		M:              cfg.HNSWM,
+		EfConstruction: cfg.HNSWEfConstruction,
+		Lists:          cfg.IVFFlatLists,
+		Recall:         cfg.VectorSearchRecall,
+		EfSearch:       cfg.HNSWEfSearch,
+		Probes:         cfg.IVFFlatProbes,
+		IterativeScan:  cfg.VectorIterativeScan == "true",
+	}
+}
+
+// FilteredSearch 按文件（或其他条件）过滤的检索能否走 ANN 索引
+func (c VectorIndexConfig) FilteredSearch() bool {
+	return c.Type != "none" && c.Type != "" && c.IterativeScan
+}
+
+// iterativeScanSupported 安装的 pgvector 是否支持迭代扫描（>= 0.8）
+func (db *DB) iterativeScanSupported() bool {
+	var version string
+	err := db.database.Raw("SELECT extversion FROM pg_extension WHERE extname = 'vector'").Scan(&version).Error
+	if err != nil {
+		logging.Logger.Error("fail to read pgvector version", "error", err)
+		return false
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(version, "%d.%d", &major, &minor); err != nil {
+		return false
+	}
+	return major > 0 || minor >= 8
+}
+
+// SearchSettings 每次向量查询前在事务内执行的 SET LOCAL 语句
+func (c VectorIndexConfig) SearchSettings() []string {
+	switch c.Type {
+	case "hnsw":
+		ef := c.EfSearch
+		if ef <= 0 {
+			switch c.Recall {
+			case "fast":
+				ef = 40
+			case "accurate":
+				ef = 200
+			default:
+				ef = 100
+			}
+		}
+		settings := []string{fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", ef)}
+		if c.IterativeScan {
+			settings = append(settings, "SET LOCAL hnsw.iterative_scan = relaxed_order")
+		}
+		return settings
+	case "ivfflat":
+		probes := c.Probes
+		if probes <= 0 {
+			lists := max(c.Lists, 1)
+			switch c.Recall {
+			case "fast":
+				probes = 1
+			case "accurate":
+				probes = max(lists/4, 1)
+			default:
+				probes = max(int(math.Sqrt(float64(lists))), 1)
+			}
+		}
+		settings := []string{fmt.Sprintf("SET LOCAL ivfflat.probes = %d", probes)}
+		if c.IterativeScan {
+			settings = append(settings, "SET LOCAL ivfflat.iterative_scan = relaxed_order")
+		}
+		return settings
+	default:
+		return nil
+	}
+}
+
+// VectorExpr 与索引表达式一致的向量列表达式
+func VectorExpr(dimension int) string {
+	return fmt.Sprintf("embedding_vector::vector(%d)", dimension)
+}
+
+// VectorIndexName 模型对应的索引名（模型 ID 可能含有不能用于标识符的字符）
+func VectorIndexName(model string) string {
+	sum := sha1.Sum([]byte(model))
+	return "idx_chunks_vec_" + hex.EncodeToString(sum[:6])
+}
+
+func (c VectorIndexConfig) createStatement(name, model string, dimension int) (string, error) {
+	var method, with string
+	switch c.Type {
+	case "hnsw":
+		method = "hnsw"
+		with = fmt.Sprintf("m = %d, ef_construction = %d", c.M, c.EfConstruction)
+	case "ivfflat":
+		method = "ivfflat"
+		with = fmt.Sprintf("lists = %d", c.Lists)
+	default:
+		return "", fmt.Errorf("unknown vector index type %q", c.Type)
+	}
+	// CONCURRENTLY 不阻塞写入；model 来自注册表，这里仍按 SQL 字面量转义
+	return fmt.Sprintf(
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON chunks USING %s ((%s) vector_cosine_ops) WITH (%s) WHERE embedding_model = '%s'",
+		name, method, VectorExpr(dimension), with, strings.ReplaceAll(model, "'", "''"),
+	), nil
+}
+
+// VectorIndex 当前索引配置
+func (db *DB) VectorIndex() VectorIndexConfig {
+	return db.vectorIndex
+}
+
+// EnsureVectorIndex 模型的索引不存在时创建；已存在则保持不变（参数变化需要 RebuildVectorIndex）。
+// 大表上建索引耗时很长，服务启动时在后台调用，也可以提前用 cmd/vectorindex 执行
+func (db *DB) EnsureVectorIndex(ctx context.Context, model string, dimension int) error {
+	if db.vectorIndex.Type == "none" || db.vectorIndex.Type == "" {
+		return nil
+	}
+	name := VectorIndexName(model)
+	if err := db.dropInvalidIndex(ctx, name); err != nil {
+		return err
+	}
+	stmt, err := db.vectorIndex.createStatement(name, model, dimension)
+	if err != nil {
+		return err
+	}
+	if err := db.database.WithContext(ctx).Exec(stmt).Error; err != nil {
+		logging.Logger.Error("fail to create vector index", "error", err, "model", model)
+		return err
+	}
+	return nil
+}
+
+// RebuildVectorIndex 按当前配置并发重建模型的索引：
+// 先以临时名建新索引，再删除旧索引并改名，整个过程不阻塞读写
+func (db *DB) RebuildVectorIndex(ctx context.Context, model string, dimension int) error {
+	name := VectorIndexName(model)
+	tmp := name + "_new"
+	gdb := db.database.WithContext(ctx)
+
+	// 上次重建中断留下的临时索引
+	if err := gdb.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + tmp).Error; err != nil {
+		return err
+	}
+	if db.vectorIndex.Type == "none" || db.vectorIndex.Type == "" {
+		return gdb.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + name).Error
+	}
+
+	stmt, err := db.vectorIndex.createStatement(tmp, model, dimension)
+	if err != nil {
+		return err
+	}
+	logging.Logger.Info("building vector index", "index", tmp, "model", model, "type", db.vectorIndex.Type)
+	if err := gdb.Exec(stmt).Error; err != nil {
+		return fmt.Errorf("failed to build index: %w", err)
+	}
+	if err := gdb.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + name).Error; err != nil {
+		return fmt.Errorf("failed to drop old index: %w", err)
+	}
+	if err := gdb.Exec(fmt.Sprintf("ALTER INDEX %s RENAME TO %s", tmp, name)).Error; err != nil {
+		return fmt.Errorf("failed to rename index: %w", err)
+	}
+	logging.Logger.Info("vector index rebuilt", "index", name, "model", model)
+	return nil
+}
+
+// dropInvalidIndex 并发建索引中断会留下 INVALID 索引，IF NOT EXISTS 会跳过它，需要先删掉
+func (db *DB) dropInvalidIndex(ctx context.Context, name string) error {
+	var invalid bool
+	err := db.database.WithContext(ctx).Raw(
+		"SELECT NOT i.indisvalid FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid WHERE c.relname = ?", name,
+	).Scan(&invalid).Error
+	if err != nil || !invalid {
+		return err
+	}
+	logging.Logger.Warn("dropping invalid vector index", "index", name)
+	return db.database.WithContext(ctx).Exec("DROP INDEX CONCURRENTLY IF EXISTS " + name).Error
+}
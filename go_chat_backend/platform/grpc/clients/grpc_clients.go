@@ -16,9 +16,11 @@ import (
 type GrpcClients struct {
 	// connections
 	embeddingConn *grpc.ClientConn
+	rerankConn    *grpc.ClientConn
 
 	// servers
 	EmbeddingClient pb.EmbeddingServiceClient
+	RerankClient    pb.RerankServiceClient
 }
 
 func NewGrpcClients(cfg *config.Config) *GrpcClients {
@@ -31,6 +33,18 @@ func NewGrpcClients(cfg *config.Config) *GrpcClients {
 	clients.embeddingConn = embeddingConn
 	clients.EmbeddingClient = pb.NewEmbeddingServiceClient(embeddingConn)
 
+	// rerank service shares the embedding connection unless it has its own address
+	rerankConn := embeddingConn
+	if cfg.GrpcRerankAddr != "" && cfg.GrpcRerankAddr != cfg.GrpcEmbeddingAddr {
+		rerankConn, err = createGrpcConnection(cfg.GrpcRerankAddr)
+		if err != nil {
+			logging.Logger.Error("fail createGrpcConnection", "error", err)
+			return nil
+		}
+		clients.rerankConn = rerankConn
+	}
+	clients.RerankClient = pb.NewRerankServiceClient(rerankConn)
+
 	return clients
 }
 
@@ -80,6 +94,14 @@ func (c *GrpcClients) Close() error {
 		}
 	}
 
+	if c.rerankConn != nil {
+		if err := c.rerankConn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close Rerank connection: %w", err))
+		} else {
+			logging.Logger.Info("Rerank connection closed")
+		}
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("errors closing connections: %v", errs)
 	}
@@ -2,6 +2,7 @@ package servers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go_chat_backend/config"
 	"go_chat_backend/models"
@@ -14,29 +15,35 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 type IngestService struct {
 	port string
 	pb.UnimplementedIngestServiceServer
-	chunkService    *services.ChunkService
-	documentService *services.DocumentService
-	eventPublisher  *events.EventPublisher
-	server          *grpc.Server
-	listener        net.Listener
+	chunkService     *services.ChunkService
+	documentService  *services.DocumentService
+	reprocessService *services.ReprocessService
+	eventPublisher   *events.EventPublisher
+	server           *grpc.Server
+	listener         net.Listener
 }
 
 func NewIngestService(
 	cfg *config.Config,
 	chunkService *services.ChunkService,
 	documentService *services.DocumentService,
+	reprocessService *services.ReprocessService,
 	eventPublisher *events.EventPublisher,
 ) *IngestService {
 	return &IngestService{
-		port:            cfg.GoGrpcIngestPort,
-		chunkService:    chunkService,
-		documentService: documentService,
-		eventPublisher:  eventPublisher,
+		port:             cfg.GoGrpcIngestPort,
+		chunkService:     chunkService,
+		documentService:  documentService,
+		reprocessService: reprocessService,
+		eventPublisher:   eventPublisher,
 	}
 }
 func (s *IngestService) Start() error {
@@ -71,108 +78,69 @@ func (s *IngestService) IngestDocument(stream pb.IngestService_IngestDocumentSer
 	logging.Logger.Info("start getting stream")
 
 	var fileId string
-	var chunksReceived, chunksStored, chunksFailed int32
+	var chunksReceived int32
 	var metadata *pb.DocumentMetadata
+	// 重新处理时 fileId 是 generation ID
+	var generation *models.ChunkGeneration
 	timeStart := time.Now()
+	// chunks 批量写入，计数由 writer 记录；任何情况下结束前都写入已收到的 chunks
+	writer := s.chunkService.NewBatchWriter()
+	defer writer.Flush()
+	// 流结束时（包括出错提前返回）清理处理上下文
+	defer func() {
+		if fileId != "" {
+			s.chunkService.CleanupContext(fileId)
+		}
+	}()
 
 	for {
 		req, err := stream.Recv()
+		if err != nil {
+			writer.Flush()
+		}
+		if err == io.EOF && generation != nil {
+			return s.finishReprocess(stream, generation, metadata, writer, chunksReceived, timeStart)
+		}
 		if err == io.EOF {
-			// processed all chunks
-			if chunksFailed == 0 {
-				// 从 metadata 获取 userID 并生成摘要
-				summary, err := s.documentService.GenerateDocumentSummary(fileId, metadata.UserId, s.chunkService)
-				// failed to get summary
-				if err != nil {
-					logging.Logger.Error("fail GenerateDocumentSummary", "error", err)
-					// 即使 summary 失败也继续处理
-					err = s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
-						Type:    models.EventDocumentCompleted,
-						DocID:   fileId,
-						UserID:  metadata.UserId,
-						Status:  "completed_without_summary",
-						Message: "Document processed but summary generation failed: " + err.Error(),
-					})
-					if err != nil {
-						return fmt.Errorf("failed to publish event: %w", err)
-					}
-				}
-				// succeed - 从 ChunkService 的上下文获取 sections
-				sections := s.chunkService.GetSections(fileId)
-
-				// 更新数据库中的 sections
-				if len(sections) > 0 {
-					if err := s.documentService.UpdateSections(context.Background(), fileId, sections); err != nil {
-						logging.Logger.Error("fail UpdateSections", "error", err)
-					}
-				}
-
-				err = s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
-					Type:     models.EventDocumentCompleted,
-					DocID:    fileId,
-					UserID:   metadata.UserId,
-					Status:   "completed",
-					Message:  "completed",
-					Summary:  summary,
-					Sections: sections,
-					Progress: &models.ProgressInfo{
-						ChunksFailed:   chunksFailed,
-						ChunksReceived: chunksReceived,
-						ChunksStored:   chunksStored,
-						Percentage:     100,
-					},
-				})
-				if err != nil {
-					return err
-				}
-
-				// 清理文档处理上下文
-				s.chunkService.CleanupContext(fileId)
-			} else {
-				// cannot process all chunks
-				err := s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
-					Type:    models.EventDocumentFailed,
-					DocID:   fileId,
-					UserID:  metadata.UserId,
-					Status:  "failed",
-					Message: fmt.Sprintf("Processing failed: %d chunks failed", chunksFailed),
-					Progress: &models.ProgressInfo{
-						ChunksReceived: chunksReceived,
-						ChunksStored:   chunksStored,
-						ChunksFailed:   chunksFailed,
-					},
-				})
-				if err != nil {
-					return err
-				}
-			}
-			// send response
-			response := &pb.IngestResponse{
-				Success:          chunksFailed == 0,
-				Message:          fmt.Sprintf("finish %d chunks", chunksReceived),
-				ChunksReceived:   chunksReceived,
-				ChunksStored:     chunksStored,
-				ChunksFailed:     chunksFailed,
-				ProcessingTimeMs: time.Since(timeStart).Milliseconds(),
-				FileId:           fileId,
+			if metadata == nil {
+				return fmt.Errorf("stream ended before metadata")
 			}
-			return stream.SendAndClose(response)
+			return s.finishIngest(stream, metadata, writer, chunksReceived, timeStart)
 		}
 		// cannot finish processing
 		if err != nil {
 			logging.Logger.Error("fail IngestDocument", "error", err)
+			if metadata != nil && generation == nil {
+				s.failIngest(metadata, writer, chunksReceived, "ingest stream aborted: "+err.Error())
+			}
 			return err
 		}
 		switch requestType := req.RequestType.(type) {
 		case *pb.IngestRequest_Metadata:
 			metadata = requestType.Metadata
 			fileId = metadata.FileId
-			if err := s.chunkService.ProcessDocumentMetadata(metadata); err != nil {
+			generation, err = s.reprocessService.Generation(stream.Context(), fileId)
+			if err != nil {
 				return err
 			}
+			if generation != nil {
+				s.chunkService.ProcessGenerationMetadata(metadata)
+			} else {
+				// 重试或重新开始的流会清空上次的计数和结果；已完成的文档不接受新的流
+				if err := s.documentService.TransitionStatus(stream.Context(), fileId, models.StatusProcessing, ""); err != nil {
+					logging.Logger.Error("fail TransitionStatus", "error", err, "docID", fileId)
+					return err
+				}
+				if err := s.documentService.RecordProgress(stream.Context(), fileId, 0, 0, 0); err != nil {
+					return err
+				}
+				if err := s.chunkService.ProcessDocumentMetadata(metadata); err != nil {
+					return err
+				}
+			}
 			err := s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
 				Type:    models.EventDocumentProcessing,
-				DocID:   fileId,
+				DocID:   eventDocID(fileId, generation),
 				UserID:  metadata.UserId,
 				Status:  "processing",
 				Message: "Document processing started",
@@ -198,20 +166,24 @@ func (s *IngestService) IngestDocument(stream pb.IngestService_IngestDocumentSer
 				"chunk_index", chunk.ChunkIndex,
 				"text_length", len(chunk.ChunkText),
 			)
-			if err := s.chunkService.ProcessChunk(chunk); err != nil {
-				chunksFailed++
+			if err := writer.Add(chunk); err != nil {
 				logging.Logger.Error("fail processChunk", "error", err)
-			} else {
-				chunksStored++
 			}
 			if chunksReceived%10 == 0 && metadata != nil {
+				// 还在缓冲区中的 chunks 不计入已存储
+				chunksStored, chunksFailed := writer.Stats()
+				if generation == nil {
+					if err := s.documentService.RecordProgress(stream.Context(), fileId, chunksReceived, chunksStored, chunksFailed); err != nil {
+						logging.Logger.Error("fail RecordProgress", "error", err, "docID", fileId)
+					}
+				}
 				percentage := 0
 				if metadata.EstimatedChunks > 0 {
 					percentage = int(float32(chunksStored) / float32(metadata.EstimatedChunks) * 100)
 				}
 				err := s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
 					Type:    models.EventDocumentProcessing,
-					DocID:   fileId,
+					DocID:   eventDocID(fileId, generation),
 					UserID:  metadata.UserId,
 					Status:  "processing",
 					Message: fmt.Sprintf("Processing: %d/%d chunks", chunksStored, metadata.EstimatedChunks),
@@ -231,3 +203,409 @@ func (s *IngestService) IngestDocument(stream pb.IngestService_IngestDocumentSer
 		}
 	}
 }
+
+// finishIngest 文档的流结束：按实际已存储的 chunks 持久化计数，全部成功时转换到 finalizing 并生成摘要，
+// 最终为 completed（摘要失败时为 completed_without_summary），否则转换到 failed
+func (s *IngestService) finishIngest(stream pb.IngestService_IngestDocumentServer, metadata *pb.DocumentMetadata, writer *services.ChunkBatchWriter, chunksReceived int32, timeStart time.Time) error {
+	ctx := context.Background()
+	fileId := metadata.FileId
+	chunksStored, chunksFailed := writer.Stats()
+	// 续传的流只发送缺失的 chunks，已存储数包括之前的流写入的部分
+	resumed := false
+	if stored, err := s.chunkService.CountStored(ctx, fileId); err != nil {
+		logging.Logger.Error("fail CountStored", "error", err, "docID", fileId)
+	} else if stored > chunksStored {
+		resumed = true
+		chunksStored, chunksReceived = stored, stored+chunksFailed
+	}
+	if err := s.documentService.RecordProgress(ctx, fileId, chunksReceived, chunksStored, chunksFailed); err != nil {
+		logging.Logger.Error("fail RecordProgress", "error", err, "docID", fileId)
+	}
+	// 流正常结束，chunksReceived 即 ETL 发送的总数；之后的单个 chunk 重试按它判断是否已补齐
+	if err := s.documentService.RecordChunksTotal(ctx, fileId, chunksReceived); err != nil {
+		logging.Logger.Error("fail RecordChunksTotal", "error", err, "docID", fileId)
+	}
+	progress := &models.ProgressInfo{
+		ChunksReceived: chunksReceived,
+		ChunksStored:   chunksStored,
+		ChunksFailed:   chunksFailed,
+		TotalChunks:    metadata.EstimatedChunks,
+	}
+	response := &pb.IngestResponse{
+		Success:        chunksFailed == 0,
+		Message:        fmt.Sprintf("finish %d chunks", chunksReceived),
+		ChunksReceived: chunksReceived,
+		ChunksStored:   chunksStored,
+		ChunksFailed:   chunksFailed,
+		FileId:         fileId,
+	}
+
+	var event *models.DocumentEvent
+	if chunksFailed > 0 {
+		// cannot process all chunks
+		reason := failedChunksReason(writer)
+		s.finishStatus(ctx, fileId, models.StatusFailed, reason)
+		event = s.failedEvent(fileId, metadata.UserId, reason, progress)
+	} else if err := s.documentService.TransitionStatus(ctx, fileId, models.StatusFinalizing, ""); err != nil {
+		// 条件转换保证同一文档的流和单个 chunk 重试中只有一个执行收尾
+		logging.Logger.Info("document completion already claimed", "docID", fileId, "error", err)
+		response.Message = "chunks stored, document is finalized by another request"
+		response.ProcessingTimeMs = time.Since(timeStart).Milliseconds()
+		return stream.SendAndClose(response)
+	} else if err := s.resumeContext(ctx, fileId, resumed); err != nil {
+		reason := "failed to load stored chunks: " + err.Error()
+		s.finishStatus(ctx, fileId, models.StatusFailed, reason)
+		event = s.failedEvent(fileId, metadata.UserId, reason, progress)
+		response.Success = false
+	} else {
+		event = s.completeDocument(ctx, fileId, metadata.UserId, progress)
+	}
+	if err := s.eventPublisher.PublishDocumentEvent(event); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	// send response
+	response.ProcessingTimeMs = time.Since(timeStart).Milliseconds()
+	return stream.SendAndClose(response)
+}
+
+// resumeContext 续传的流只带来了缺失的 chunks，或上下文已超时清理时，从已存储的 chunks 重建上下文，
+// sections 覆盖整个文档
+func (s *IngestService) resumeContext(ctx context.Context, fileID string, resumed bool) error {
+	if !resumed && s.chunkService.HasContext(fileID) {
+		return nil
+	}
+	if err := s.chunkService.RebuildContext(ctx, fileID); err != nil {
+		logging.Logger.Error("fail RebuildContext", "error", err, "docID", fileID)
+		return err
+	}
+	return nil
+}
+
+// failedEvent 文档处理失败的事件
+func (s *IngestService) failedEvent(fileId, userID, reason string, progress *models.ProgressInfo) *models.DocumentEvent {
+	return &models.DocumentEvent{
+		Type:     models.EventDocumentFailed,
+		DocID:    fileId,
+		UserID:   userID,
+		Status:   models.StatusFailed,
+		Message:  "Processing failed: " + reason,
+		Progress: progress,
+	}
+}
+
+// completeDocument 所有 chunks 都已存储：生成摘要（失败时为 completed_without_summary）、
+// 保存 sections 并转换到完成状态，返回要发布的完成事件；需要文档的处理上下文
+func (s *IngestService) completeDocument(ctx context.Context, fileId, userID string, progress *models.ProgressInfo) *models.DocumentEvent {
+	docStatus, message := models.StatusCompleted, "completed"
+	if err := s.chunkService.InvalidateVectors(ctx, fileId); err != nil {
+		logging.Logger.Error("fail InvalidateVectors", "error", err, "docID", fileId)
+	}
+	// 从 metadata 获取 userID 并生成摘要，摘要失败也继续处理
+	summary, err := s.documentService.GenerateDocumentSummary(fileId, userID, s.chunkService)
+	if err != nil {
+		logging.Logger.Error("fail GenerateDocumentSummary", "error", err)
+		docStatus = models.StatusCompletedWithoutSummary
+		message = "Document processed but summary generation failed: " + err.Error()
+	}
+	// 从 ChunkService 的上下文获取 sections 并更新数据库
+	sections := s.chunkService.GetSections(fileId)
+	if len(sections) > 0 {
+		if err := s.documentService.UpdateSections(ctx, fileId, sections); err != nil {
+			logging.Logger.Error("fail UpdateSections", "error", err)
+		}
+	}
+	s.finishStatus(ctx, fileId, docStatus, "")
+	progress.Percentage = 100
+	return &models.DocumentEvent{
+		Type:     models.EventDocumentCompleted,
+		DocID:    fileId,
+		UserID:   userID,
+		Status:   docStatus,
+		Message:  message,
+		Summary:  summary,
+		Sections: sections,
+		Progress: progress,
+	}
+}
+
+// IngestSingleChunk 单个 chunk 重试，按 (file_id, chunk_index) 幂等写入
+// 文档处理中时流负责计数和收尾，这里只写入 chunk；流正常结束但有 chunk 失败（failed 且记录了 ChunksTotal）时
+// 按已存储的 chunks 重新计数，chunk_index 补齐 0..ChunksTotal-1 后执行与流结束相同的收尾。
+// 流中断、上传校验未通过、被 janitor 标记失败的文档没有 ChunksTotal，只能重新发送整个文档
+func (s *IngestService) IngestSingleChunk(ctx context.Context, chunk *pb.TextChunk) (*pb.IngestResponse, error) {
+	timeStart := time.Now()
+	if chunk.FileId == "" {
+		return nil, status.Error(codes.InvalidArgument, "file_id is required")
+	}
+	doc, err := s.documentService.GetDocumentByID(ctx, chunk.FileId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 重新处理的 generation 不是文档，只能通过 IngestDocument 重新发送
+		return nil, status.Errorf(codes.NotFound, "document %s not found", chunk.FileId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load document: %v", err)
+	}
+	retrying := doc.Status == models.StatusFailed && doc.ChunksTotal > 0
+	if doc.Status != models.StatusProcessing && !retrying {
+		return nil, status.Errorf(codes.FailedPrecondition, "document %s is %s and does not accept single chunk retries", doc.FileID, doc.Status)
+	}
+	if retrying && (chunk.ChunkIndex < 0 || chunk.ChunkIndex >= doc.ChunksTotal) {
+		return nil, status.Errorf(codes.InvalidArgument, "chunk_index %d out of range [0, %d)", chunk.ChunkIndex, doc.ChunksTotal)
+	}
+
+	response := &pb.IngestResponse{FileId: doc.FileID, ChunksReceived: 1}
+	if err := s.chunkService.IngestSingleChunk(ctx, chunk); err != nil {
+		logging.Logger.Error("fail IngestSingleChunk", "error", err, "docID", doc.FileID, "chunk_index", chunk.ChunkIndex)
+		response.ChunksFailed = 1
+		response.Message = err.Error()
+		response.ProcessingTimeMs = time.Since(timeStart).Milliseconds()
+		return response, nil
+	}
+	response.Success = true
+	response.ChunksStored = 1
+	response.Message = "chunk stored"
+	if doc.Status == models.StatusProcessing {
+		response.ProcessingTimeMs = time.Since(timeStart).Milliseconds()
+		return response, nil
+	}
+
+	// 流已结束：按已存储的 chunk_index 与流结束时的总数重新计数
+	indexes, err := s.chunkService.StoredChunkIndexes(ctx, doc.FileID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list chunks: %v", err)
+	}
+	received, stored := doc.ChunksTotal, int32(len(indexes))
+	response.ChunksReceived, response.ChunksStored, response.ChunksFailed = received, stored, max(received-stored, 0)
+	if err := s.documentService.RecordProgress(ctx, doc.FileID, received, stored, response.ChunksFailed); err != nil {
+		logging.Logger.Error("fail RecordProgress", "error", err, "docID", doc.FileID)
+	}
+	if !coversChunks(indexes, received) {
+		response.Message = fmt.Sprintf("%d chunks still missing", received-stored)
+		response.ProcessingTimeMs = time.Since(timeStart).Milliseconds()
+		return response, nil
+	}
+
+	// 最后一个缺失的 chunk：条件转换保证并发重试时只有一个请求执行收尾
+	if err := s.documentService.TransitionStatus(ctx, doc.FileID, models.StatusFinalizing, ""); err != nil {
+		logging.Logger.Info("document completion already claimed", "docID", doc.FileID, "error", err)
+		response.ProcessingTimeMs = time.Since(timeStart).Milliseconds()
+		return response, nil
+	}
+	if err := s.chunkService.RebuildContext(ctx, doc.FileID); err != nil {
+		logging.Logger.Error("fail RebuildContext", "error", err, "docID", doc.FileID)
+		s.finishStatus(ctx, doc.FileID, models.StatusFailed, "failed to load stored chunks: "+err.Error())
+		return nil, status.Errorf(codes.Internal, "failed to load stored chunks: %v", err)
+	}
+	defer s.chunkService.CleanupContext(doc.FileID)
+	event := s.completeDocument(context.Background(), doc.FileID, doc.UserID, &models.ProgressInfo{
+		ChunksReceived: received,
+		ChunksStored:   stored,
+		TotalChunks:    doc.EstimatedChunks,
+	})
+	if err := s.eventPublisher.PublishDocumentEvent(event); err != nil {
+		logging.Logger.Error("fail PublishDocumentEvent", "error", err)
+	}
+	response.Message = "document completed"
+	response.ProcessingTimeMs = time.Since(timeStart).Milliseconds()
+	return response, nil
+}
+
+// coversChunks 已存储的 chunk_index（升序、不重复）是否正好为 0..total-1
+func coversChunks(indexes []int32, total int32) bool {
+	if total <= 0 || int32(len(indexes)) != total {
+		return false
+	}
+	for i, idx := range indexes {
+		if idx != int32(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetStoredChunks 返回文件已存储的 chunk_index，流中断后 ETL 先查询再只发送缺失的 chunks；
+// file_id 可以是文档 ID 或重新处理的 generation ID
+func (s *IngestService) GetStoredChunks(ctx context.Context, req *pb.StoredChunksRequest) (*pb.StoredChunksResponse, error) {
+	if req.FileId == "" {
+		return nil, status.Error(codes.InvalidArgument, "file_id is required")
+	}
+	response := &pb.StoredChunksResponse{FileId: req.FileId}
+	gen, err := s.reprocessService.Generation(ctx, req.FileId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load generation: %v", err)
+	}
+	if gen != nil {
+		response.Status = gen.Status
+	} else {
+		doc, err := s.documentService.GetDocumentByID(ctx, req.FileId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "document %s not found", req.FileId)
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to load document: %v", err)
+		}
+		response.Status = doc.Status
+	}
+
+	indexes, err := s.chunkService.StoredChunkIndexes(ctx, req.FileId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list chunks: %v", err)
+	}
+	response.ChunkIndexes = indexes
+	return response, nil
+}
+
+// GetTaskURL 为 ETL worker 生成任务文件的下载链接；worker 从队列取出任务后调用，链接不会在排队期间过期。
+// file_id 可以是文档 ID 或重新处理的 generation ID（使用其文档的原文件）
+func (s *IngestService) GetTaskURL(ctx context.Context, req *pb.TaskURLRequest) (*pb.TaskURLResponse, error) {
+	if req.FileId == "" {
+		return nil, status.Error(codes.InvalidArgument, "file_id is required")
+	}
+	docID := req.FileId
+	gen, err := s.reprocessService.Generation(ctx, req.FileId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load generation: %v", err)
+	}
+	if gen != nil {
+		docID = gen.DocID
+	}
+	url, expires, err := s.documentService.TaskURL(ctx, docID)
+	if errors.Is(err, services.ErrDocumentNotFound) {
+		return nil, status.Errorf(codes.NotFound, "document %s not found", docID)
+	}
+	if err != nil {
+		logging.Logger.Error("fail TaskURL", "error", err, "docID", docID)
+		return nil, status.Errorf(codes.Internal, "failed to generate download URL: %v", err)
+	}
+	return &pb.TaskURLResponse{FileId: req.FileId, Url: url, ExpiresAt: expires.Unix()}, nil
+}
+
+// failIngest 流中途断开：持久化计数并把文档标记为失败
+func (s *IngestService) failIngest(metadata *pb.DocumentMetadata, writer *services.ChunkBatchWriter, chunksReceived int32, reason string) {
+	ctx := context.Background()
+	chunksStored, chunksFailed := writer.Stats()
+	if err := s.documentService.RecordProgress(ctx, metadata.FileId, chunksReceived, chunksStored, chunksFailed); err != nil {
+		logging.Logger.Error("fail RecordProgress", "error", err, "docID", metadata.FileId)
+	}
+	s.finishStatus(ctx, metadata.FileId, models.StatusFailed, reason)
+	err := s.eventPublisher.PublishDocumentEvent(s.failedEvent(metadata.FileId, metadata.UserId, reason, &models.ProgressInfo{
+		ChunksReceived: chunksReceived,
+		ChunksStored:   chunksStored,
+		ChunksFailed:   chunksFailed,
+		TotalChunks:    metadata.EstimatedChunks,
+	}))
+	if err != nil {
+		logging.Logger.Error("fail PublishDocumentEvent", "error", err)
+	}
+}
+
+// finishStatus 把文档转换到终态；文档已被其他路径（janitor、删除）处理时只记录日志
+func (s *IngestService) finishStatus(ctx context.Context, fileId, status, reason string) {
+	if err := s.documentService.TransitionStatus(ctx, fileId, status, reason); err != nil {
+		logging.Logger.Error("fail TransitionStatus", "error", err, "docID", fileId, "status", status)
+	}
+}
+
+// failedChunksReason 失败原因中列出写入失败的 chunk_index（最多 20 个），完整列表可通过 GetStoredChunks 推算
+func failedChunksReason(writer *services.ChunkBatchWriter) string {
+	indexes := writer.FailedIndexes()
+	if len(indexes) > 20 {
+		return fmt.Sprintf("%d chunks failed (chunk_index %v ...)", len(indexes), indexes[:20])
+	}
+	return fmt.Sprintf("%d chunks failed (chunk_index %v)", len(indexes), indexes)
+}
+
+// eventDocID 事件始终使用文档 ID，前端不感知 generation
+func eventDocID(fileID string, generation *models.ChunkGeneration) string {
+	if generation != nil {
+		return generation.DocID
+	}
+	return fileID
+}
+
+// finishReprocess 重新处理的流结束：全部成功时切换到新 generation（可选重新生成摘要），否则丢弃新 chunks
+func (s *IngestService) finishReprocess(stream pb.IngestService_IngestDocumentServer, gen *models.ChunkGeneration, metadata *pb.DocumentMetadata, writer *services.ChunkBatchWriter, chunksReceived int32, timeStart time.Time) error {
+	ctx := context.Background()
+	chunksStored, chunksFailed := writer.Stats()
+	// 与 finishIngest 相同：续传时按已存储的 chunks 计数，sections 从完整的 chunks 重建
+	resumed := false
+	if stored, err := s.chunkService.CountStored(ctx, gen.ID); err != nil {
+		logging.Logger.Error("fail CountStored", "error", err, "generation", gen.ID)
+	} else if stored > chunksStored {
+		resumed = true
+		chunksStored, chunksReceived = stored, stored+chunksFailed
+	}
+	progress := &models.ProgressInfo{
+		ChunksReceived: chunksReceived,
+		ChunksStored:   chunksStored,
+		ChunksFailed:   chunksFailed,
+		TotalChunks:    metadata.EstimatedChunks,
+	}
+
+	var switchErr error
+	if chunksFailed > 0 {
+		switchErr = errors.New(failedChunksReason(writer))
+		s.reprocessService.Fail(ctx, gen, switchErr.Error())
+	} else if err := s.resumeContext(ctx, gen.ID, resumed); err != nil {
+		switchErr = fmt.Errorf("failed to load stored chunks: %w", err)
+		s.reprocessService.Fail(ctx, gen, switchErr.Error())
+	} else {
+		switchErr = s.reprocessService.Complete(ctx, gen, metadata, s.chunkService.GetSections(gen.ID), chunksReceived, chunksStored)
+	}
+	if switchErr != nil {
+		err := s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
+			Type:     models.EventDocumentFailed,
+			DocID:    gen.DocID,
+			UserID:   metadata.UserId,
+			Status:   "failed",
+			Message:  "Reprocessing failed, previous chunks are still in use: " + switchErr.Error(),
+			Progress: progress,
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		// 重新处理不改变文档状态
+		status, summary := models.StatusCompleted, ""
+		if doc, err := s.documentService.GetDocumentByID(ctx, gen.DocID); err == nil {
+			status = doc.Status
+		}
+		if gen.RegenerateSummary {
+			var err error
+			summary, err = s.documentService.RegenerateDocumentSummary(gen.DocID, gen.ID, metadata.UserId, s.chunkService)
+			if err != nil {
+				logging.Logger.Error("fail RegenerateDocumentSummary", "error", err, "docID", gen.DocID)
+				status = models.StatusCompletedWithoutSummary
+			} else if err := s.documentService.SummaryRestored(ctx, gen.DocID); err != nil {
+				logging.Logger.Error("fail SummaryRestored", "error", err, "docID", gen.DocID)
+			} else {
+				status = models.StatusCompleted
+			}
+		}
+		progress.Percentage = 100
+		err := s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
+			Type:     models.EventDocumentCompleted,
+			DocID:    gen.DocID,
+			UserID:   metadata.UserId,
+			Status:   status,
+			Message:  "Document reprocessed",
+			Summary:  summary,
+			Sections: s.chunkService.GetSections(gen.ID),
+			Progress: progress,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return stream.SendAndClose(&pb.IngestResponse{
+		Success:          switchErr == nil,
+		Message:          fmt.Sprintf("finish %d chunks", chunksReceived),
+		ChunksReceived:   chunksReceived,
+		ChunksStored:     chunksStored,
+		ChunksFailed:     chunksFailed,
+		ProcessingTimeMs: time.Since(timeStart).Milliseconds(),
+		FileId:           gen.ID,
+	})
+}
@@ -394,6 +394,216 @@ func (x *IngestResponse) GetFileId() string {
 	return ""
 }
 
+// 查询已存储的 chunks（流中断后续传）
+type StoredChunksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"` // 文档 ID 或重新处理的 generation ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoredChunksRequest) Reset() {
+	*x = StoredChunksRequest{}
+	mi := &file_cognicore_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredChunksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredChunksRequest) ProtoMessage() {}
+
+func (x *StoredChunksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredChunksRequest.ProtoReflect.Descriptor instead.
+func (*StoredChunksRequest) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StoredChunksRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+type StoredChunksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	ChunkIndexes  []int32                `protobuf:"varint,2,rep,packed,name=chunk_indexes,json=chunkIndexes,proto3" json:"chunk_indexes,omitempty"` // 已存储的 chunk_index（升序），续传时跳过这些
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`                                         // 文档状态；completed 等终态时不需要再发送
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoredChunksResponse) Reset() {
+	*x = StoredChunksResponse{}
+	mi := &file_cognicore_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredChunksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredChunksResponse) ProtoMessage() {}
+
+func (x *StoredChunksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredChunksResponse.ProtoReflect.Descriptor instead.
+func (*StoredChunksResponse) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StoredChunksResponse) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *StoredChunksResponse) GetChunkIndexes() []int32 {
+	if x != nil {
+		return x.ChunkIndexes
+	}
+	return nil
+}
+
+func (x *StoredChunksResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+// 获取 ETL 任务原文件的下载链接
+type TaskURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"` // EtlTask.DocID：文档 ID 或重新处理的 generation ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskURLRequest) Reset() {
+	*x = TaskURLRequest{}
+	mi := &file_cognicore_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskURLRequest) ProtoMessage() {}
+
+func (x *TaskURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskURLRequest.ProtoReflect.Descriptor instead.
+func (*TaskURLRequest) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TaskURLRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+type TaskURLResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`                               // 签名下载链接
+	ExpiresAt     int64                  `protobuf:"varint,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // 过期时间（Unix 秒）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskURLResponse) Reset() {
+	*x = TaskURLResponse{}
+	mi := &file_cognicore_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskURLResponse) ProtoMessage() {}
+
+func (x *TaskURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskURLResponse.ProtoReflect.Descriptor instead.
+func (*TaskURLResponse) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TaskURLResponse) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *TaskURLResponse) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *TaskURLResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
 // Embedding 请求（Go 调用 Python 时使用）
 type EmbeddingRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -405,7 +615,7 @@ type EmbeddingRequest struct {
 
 func (x *EmbeddingRequest) Reset() {
 	*x = EmbeddingRequest{}
-	mi := &file_cognicore_proto_msgTypes[4]
+	mi := &file_cognicore_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -417,7 +627,7 @@ func (x *EmbeddingRequest) String() string {
 func (*EmbeddingRequest) ProtoMessage() {}
 
 func (x *EmbeddingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_cognicore_proto_msgTypes[4]
+	mi := &file_cognicore_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -430,7 +640,7 @@ func (x *EmbeddingRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbeddingRequest.ProtoReflect.Descriptor instead.
 func (*EmbeddingRequest) Descriptor() ([]byte, []int) {
-	return file_cognicore_proto_rawDescGZIP(), []int{4}
+	return file_cognicore_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *EmbeddingRequest) GetTaskId() string {
@@ -460,7 +670,7 @@ type EmbeddingResponse struct {
 
 func (x *EmbeddingResponse) Reset() {
 	*x = EmbeddingResponse{}
-	mi := &file_cognicore_proto_msgTypes[5]
+	mi := &file_cognicore_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -472,7 +682,7 @@ func (x *EmbeddingResponse) String() string {
 func (*EmbeddingResponse) ProtoMessage() {}
 
 func (x *EmbeddingResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_cognicore_proto_msgTypes[5]
+	mi := &file_cognicore_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -485,7 +695,7 @@ func (x *EmbeddingResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbeddingResponse.ProtoReflect.Descriptor instead.
 func (*EmbeddingResponse) Descriptor() ([]byte, []int) {
-	return file_cognicore_proto_rawDescGZIP(), []int{5}
+	return file_cognicore_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *EmbeddingResponse) GetSuccess() bool {
@@ -516,6 +726,417 @@ func (x *EmbeddingResponse) GetDimension() int32 {
 	return 0
 }
 
+// 批量 Embedding 请求
+type BatchEmbeddingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"` // 任务 ID（用于日志追踪）
+	Texts         []string               `protobuf:"bytes,2,rep,name=texts,proto3" json:"texts,omitempty"`                 // 要向量化的文本，顺序与响应一致
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchEmbeddingRequest) Reset() {
+	*x = BatchEmbeddingRequest{}
+	mi := &file_cognicore_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchEmbeddingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchEmbeddingRequest) ProtoMessage() {}
+
+func (x *BatchEmbeddingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchEmbeddingRequest.ProtoReflect.Descriptor instead.
+func (*BatchEmbeddingRequest) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *BatchEmbeddingRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *BatchEmbeddingRequest) GetTexts() []string {
+	if x != nil {
+		return x.Texts
+	}
+	return nil
+}
+
+// 单个文本的向量
+type EmbeddingVector struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []float32              `protobuf:"fixed32,1,rep,packed,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbeddingVector) Reset() {
+	*x = EmbeddingVector{}
+	mi := &file_cognicore_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbeddingVector) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbeddingVector) ProtoMessage() {}
+
+func (x *EmbeddingVector) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbeddingVector.ProtoReflect.Descriptor instead.
+func (*EmbeddingVector) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *EmbeddingVector) GetValues() []float32 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+// 批量 Embedding 响应
+type BatchEmbeddingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Embeddings    []*EmbeddingVector     `protobuf:"bytes,3,rep,name=embeddings,proto3" json:"embeddings,omitempty"` // 与请求中的 texts 一一对应
+	Dimension     int32                  `protobuf:"varint,4,opt,name=dimension,proto3" json:"dimension,omitempty"`  // 向量维度
+	Model         string                 `protobuf:"bytes,5,opt,name=model,proto3" json:"model,omitempty"`           // 生成向量的模型名称
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchEmbeddingResponse) Reset() {
+	*x = BatchEmbeddingResponse{}
+	mi := &file_cognicore_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchEmbeddingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchEmbeddingResponse) ProtoMessage() {}
+
+func (x *BatchEmbeddingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchEmbeddingResponse.ProtoReflect.Descriptor instead.
+func (*BatchEmbeddingResponse) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BatchEmbeddingResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *BatchEmbeddingResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *BatchEmbeddingResponse) GetEmbeddings() []*EmbeddingVector {
+	if x != nil {
+		return x.Embeddings
+	}
+	return nil
+}
+
+func (x *BatchEmbeddingResponse) GetDimension() int32 {
+	if x != nil {
+		return x.Dimension
+	}
+	return 0
+}
+
+func (x *BatchEmbeddingResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+// Rerank 候选文本
+type RerankCandidate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`     // 候选 ID（chunk_id）
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"` // 候选文本
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RerankCandidate) Reset() {
+	*x = RerankCandidate{}
+	mi := &file_cognicore_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RerankCandidate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RerankCandidate) ProtoMessage() {}
+
+func (x *RerankCandidate) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RerankCandidate.ProtoReflect.Descriptor instead.
+func (*RerankCandidate) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RerankCandidate) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RerankCandidate) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+// Rerank 请求（Go 调用 Python 时使用）
+type RerankRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"` // 任务 ID（用于日志追踪）
+	Query         string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`                 // 查询文本
+	Candidates    []*RerankCandidate     `protobuf:"bytes,3,rep,name=candidates,proto3" json:"candidates,omitempty"`       // 待排序的候选
+	TopN          int32                  `protobuf:"varint,4,opt,name=top_n,json=topN,proto3" json:"top_n,omitempty"`      // 返回前 N 个结果
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RerankRequest) Reset() {
+	*x = RerankRequest{}
+	mi := &file_cognicore_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RerankRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RerankRequest) ProtoMessage() {}
+
+func (x *RerankRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RerankRequest.ProtoReflect.Descriptor instead.
+func (*RerankRequest) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RerankRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *RerankRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *RerankRequest) GetCandidates() []*RerankCandidate {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+func (x *RerankRequest) GetTopN() int32 {
+	if x != nil {
+		return x.TopN
+	}
+	return 0
+}
+
+// 单个候选的打分
+type RerankResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`         // 候选 ID
+	Score         float32                `protobuf:"fixed32,2,opt,name=score,proto3" json:"score,omitempty"` // 相关性分数（越大越相关）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RerankResult) Reset() {
+	*x = RerankResult{}
+	mi := &file_cognicore_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RerankResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RerankResult) ProtoMessage() {}
+
+func (x *RerankResult) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RerankResult.ProtoReflect.Descriptor instead.
+func (*RerankResult) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RerankResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RerankResult) GetScore() float32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+// Rerank 响应
+type RerankResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Results       []*RerankResult        `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"` // 按分数从高到低排序
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RerankResponse) Reset() {
+	*x = RerankResponse{}
+	mi := &file_cognicore_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RerankResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RerankResponse) ProtoMessage() {}
+
+func (x *RerankResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cognicore_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RerankResponse.ProtoReflect.Descriptor instead.
+func (*RerankResponse) Descriptor() ([]byte, []int) {
+	return file_cognicore_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RerankResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RerankResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RerankResponse) GetResults() []*RerankResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
 var File_cognicore_proto protoreflect.FileDescriptor
 
 const file_cognicore_proto_rawDesc = "" +
@@ -554,7 +1175,20 @@ const file_cognicore_proto_rawDesc = "" +
 	"\rchunks_stored\x18\x04 \x01(\x05R\fchunksStored\x12#\n" +
 	"\rchunks_failed\x18\x05 \x01(\x05R\fchunksFailed\x12,\n" +
 	"\x12processing_time_ms\x18\a \x01(\x03R\x10processingTimeMs\x12\x17\n" +
-	"\afile_id\x18\b \x01(\tR\x06fileId\"?\n" +
+	"\afile_id\x18\b \x01(\tR\x06fileId\".\n" +
+	"\x13StoredChunksRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\"l\n" +
+	"\x14StoredChunksResponse\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12#\n" +
+	"\rchunk_indexes\x18\x02 \x03(\x05R\fchunkIndexes\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\")\n" +
+	"\x0eTaskURLRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\"[\n" +
+	"\x0fTaskURLResponse\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\x03R\texpiresAt\"?\n" +
 	"\x10EmbeddingRequest\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x12\n" +
 	"\x04text\x18\x02 \x01(\tR\x04text\"\x85\x01\n" +
@@ -564,12 +1198,48 @@ const file_cognicore_proto_rawDesc = "" +
 	"\n" +
 	"embeddings\x18\x03 \x03(\x02R\n" +
 	"embeddings\x12\x1c\n" +
-	"\tdimension\x18\x04 \x01(\x05R\tdimension2\x9e\x01\n" +
+	"\tdimension\x18\x04 \x01(\x05R\tdimension\"F\n" +
+	"\x15BatchEmbeddingRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x14\n" +
+	"\x05texts\x18\x02 \x03(\tR\x05texts\")\n" +
+	"\x0fEmbeddingVector\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\x02R\x06values\"\xbc\x01\n" +
+	"\x16BatchEmbeddingResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12:\n" +
+	"\n" +
+	"embeddings\x18\x03 \x03(\v2\x1a.cognicore.EmbeddingVectorR\n" +
+	"embeddings\x12\x1c\n" +
+	"\tdimension\x18\x04 \x01(\x05R\tdimension\x12\x14\n" +
+	"\x05model\x18\x05 \x01(\tR\x05model\"5\n" +
+	"\x0fRerankCandidate\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\"\x8f\x01\n" +
+	"\rRerankRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12:\n" +
+	"\n" +
+	"candidates\x18\x03 \x03(\v2\x1a.cognicore.RerankCandidateR\n" +
+	"candidates\x12\x13\n" +
+	"\x05top_n\x18\x04 \x01(\x05R\x04topN\"4\n" +
+	"\fRerankResult\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x02R\x05score\"w\n" +
+	"\x0eRerankResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x121\n" +
+	"\aresults\x18\x03 \x03(\v2\x17.cognicore.RerankResultR\aresults2\xb7\x02\n" +
 	"\rIngestService\x12G\n" +
 	"\x0eIngestDocument\x12\x18.cognicore.IngestRequest\x1a\x19.cognicore.IngestResponse(\x01\x12D\n" +
-	"\x11IngestSingleChunk\x12\x14.cognicore.TextChunk\x1a\x19.cognicore.IngestResponse2]\n" +
+	"\x11IngestSingleChunk\x12\x14.cognicore.TextChunk\x1a\x19.cognicore.IngestResponse\x12R\n" +
+	"\x0fGetStoredChunks\x12\x1e.cognicore.StoredChunksRequest\x1a\x1f.cognicore.StoredChunksResponse\x12C\n" +
+	"\n" +
+	"GetTaskURL\x12\x19.cognicore.TaskURLRequest\x1a\x1a.cognicore.TaskURLResponse2\xb3\x01\n" +
 	"\x10EmbeddingService\x12I\n" +
-	"\fGetEmbedding\x12\x1b.cognicore.EmbeddingRequest\x1a\x1c.cognicore.EmbeddingResponseB>Z<github.com/ZZZiggy_/go_chat_backend/platform/proto/cognicoreb\x06proto3"
+	"\fGetEmbedding\x12\x1b.cognicore.EmbeddingRequest\x1a\x1c.cognicore.EmbeddingResponse\x12T\n" +
+	"\rGetEmbeddings\x12 .cognicore.BatchEmbeddingRequest\x1a!.cognicore.BatchEmbeddingResponse2N\n" +
+	"\rRerankService\x12=\n" +
+	"\x06Rerank\x12\x18.cognicore.RerankRequest\x1a\x19.cognicore.RerankResponseB>Z<github.com/ZZZiggy_/go_chat_backend/platform/proto/cognicoreb\x06proto3"
 
 var (
 	file_cognicore_proto_rawDescOnce sync.Once
@@ -583,29 +1253,51 @@ func file_cognicore_proto_rawDescGZIP() []byte {
 	return file_cognicore_proto_rawDescData
 }
 
-var file_cognicore_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_cognicore_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_cognicore_proto_goTypes = []any{
-	(*TextChunk)(nil),         // 0: cognicore.TextChunk
-	(*DocumentMetadata)(nil),  // 1: cognicore.DocumentMetadata
-	(*IngestRequest)(nil),     // 2: cognicore.IngestRequest
-	(*IngestResponse)(nil),    // 3: cognicore.IngestResponse
-	(*EmbeddingRequest)(nil),  // 4: cognicore.EmbeddingRequest
-	(*EmbeddingResponse)(nil), // 5: cognicore.EmbeddingResponse
+	(*TextChunk)(nil),              // 0: cognicore.TextChunk
+	(*DocumentMetadata)(nil),       // 1: cognicore.DocumentMetadata
+	(*IngestRequest)(nil),          // 2: cognicore.IngestRequest
+	(*IngestResponse)(nil),         // 3: cognicore.IngestResponse
+	(*StoredChunksRequest)(nil),    // 4: cognicore.StoredChunksRequest
+	(*StoredChunksResponse)(nil),   // 5: cognicore.StoredChunksResponse
+	(*TaskURLRequest)(nil),         // 6: cognicore.TaskURLRequest
+	(*TaskURLResponse)(nil),        // 7: cognicore.TaskURLResponse
+	(*EmbeddingRequest)(nil),       // 8: cognicore.EmbeddingRequest
+	(*EmbeddingResponse)(nil),      // 9: cognicore.EmbeddingResponse
+	(*BatchEmbeddingRequest)(nil),  // 10: cognicore.BatchEmbeddingRequest
+	(*EmbeddingVector)(nil),        // 11: cognicore.EmbeddingVector
+	(*BatchEmbeddingResponse)(nil), // 12: cognicore.BatchEmbeddingResponse
+	(*RerankCandidate)(nil),        // 13: cognicore.RerankCandidate
+	(*RerankRequest)(nil),          // 14: cognicore.RerankRequest
+	(*RerankResult)(nil),           // 15: cognicore.RerankResult
+	(*RerankResponse)(nil),         // 16: cognicore.RerankResponse
 }
 var file_cognicore_proto_depIdxs = []int32{
-	1, // 0: cognicore.IngestRequest.metadata:type_name -> cognicore.DocumentMetadata
-	0, // 1: cognicore.IngestRequest.chunk:type_name -> cognicore.TextChunk
-	2, // 2: cognicore.IngestService.IngestDocument:input_type -> cognicore.IngestRequest
-	0, // 3: cognicore.IngestService.IngestSingleChunk:input_type -> cognicore.TextChunk
-	4, // 4: cognicore.EmbeddingService.GetEmbedding:input_type -> cognicore.EmbeddingRequest
-	3, // 5: cognicore.IngestService.IngestDocument:output_type -> cognicore.IngestResponse
-	3, // 6: cognicore.IngestService.IngestSingleChunk:output_type -> cognicore.IngestResponse
-	5, // 7: cognicore.EmbeddingService.GetEmbedding:output_type -> cognicore.EmbeddingResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	1,  // 0: cognicore.IngestRequest.metadata:type_name -> cognicore.DocumentMetadata
+	0,  // 1: cognicore.IngestRequest.chunk:type_name -> cognicore.TextChunk
+	11, // 2: cognicore.BatchEmbeddingResponse.embeddings:type_name -> cognicore.EmbeddingVector
+	13, // 3: cognicore.RerankRequest.candidates:type_name -> cognicore.RerankCandidate
+	15, // 4: cognicore.RerankResponse.results:type_name -> cognicore.RerankResult
+	2,  // 5: cognicore.IngestService.IngestDocument:input_type -> cognicore.IngestRequest
+	0,  // 6: cognicore.IngestService.IngestSingleChunk:input_type -> cognicore.TextChunk
+	4,  // 7: cognicore.IngestService.GetStoredChunks:input_type -> cognicore.StoredChunksRequest
+	6,  // 8: cognicore.IngestService.GetTaskURL:input_type -> cognicore.TaskURLRequest
+	8,  // 9: cognicore.EmbeddingService.GetEmbedding:input_type -> cognicore.EmbeddingRequest
+	10, // 10: cognicore.EmbeddingService.GetEmbeddings:input_type -> cognicore.BatchEmbeddingRequest
+	14, // 11: cognicore.RerankService.Rerank:input_type -> cognicore.RerankRequest
+	3,  // 12: cognicore.IngestService.IngestDocument:output_type -> cognicore.IngestResponse
+	3,  // 13: cognicore.IngestService.IngestSingleChunk:output_type -> cognicore.IngestResponse
+	5,  // 14: cognicore.IngestService.GetStoredChunks:output_type -> cognicore.StoredChunksResponse
+	7,  // 15: cognicore.IngestService.GetTaskURL:output_type -> cognicore.TaskURLResponse
+	9,  // 16: cognicore.EmbeddingService.GetEmbedding:output_type -> cognicore.EmbeddingResponse
+	12, // 17: cognicore.EmbeddingService.GetEmbeddings:output_type -> cognicore.BatchEmbeddingResponse
+	16, // 18: cognicore.RerankService.Rerank:output_type -> cognicore.RerankResponse
+	12, // [12:19] is the sub-list for method output_type
+	5,  // [5:12] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_cognicore_proto_init() }
@@ -623,9 +1315,9 @@ func file_cognicore_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_cognicore_proto_rawDesc), len(file_cognicore_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   17,
 			NumExtensions: 0,
-			NumServices:   2,
+			NumServices:   3,
 		},
 		GoTypes:           file_cognicore_proto_goTypes,
 		DependencyIndexes: file_cognicore_proto_depIdxs,
@@ -21,6 +21,8 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	IngestService_IngestDocument_FullMethodName    = "/cognicore.IngestService/IngestDocument"
 	IngestService_IngestSingleChunk_FullMethodName = "/cognicore.IngestService/IngestSingleChunk"
+	IngestService_GetStoredChunks_FullMethodName   = "/cognicore.IngestService/GetStoredChunks"
+	IngestService_GetTaskURL_FullMethodName        = "/cognicore.IngestService/GetTaskURL"
 )
 
 // IngestServiceClient is the client API for IngestService service.
@@ -40,6 +42,12 @@ type IngestServiceClient interface {
 	// Unary 模式：单个 chunk 上传
 	// 使用场景：重试失败的 chunk，或者测试单个 chunk
 	IngestSingleChunk(ctx context.Context, in *TextChunk, opts ...grpc.CallOption) (*IngestResponse, error)
+	// Unary 模式：查询已存储的 chunk_index
+	// 使用场景：流中断后重试，先查询再只发送缺失的 chunks（metadata 仍需发送）
+	GetStoredChunks(ctx context.Context, in *StoredChunksRequest, opts ...grpc.CallOption) (*StoredChunksResponse, error)
+	// Unary 模式：获取任务文件的下载链接
+	// 使用场景：ETL worker 从队列取出任务后再获取，链接不会因任务排队过久而过期
+	GetTaskURL(ctx context.Context, in *TaskURLRequest, opts ...grpc.CallOption) (*TaskURLResponse, error)
 }
 
 type ingestServiceClient struct {
@@ -73,6 +81,26 @@ func (c *ingestServiceClient) IngestSingleChunk(ctx context.Context, in *TextChu
 	return out, nil
 }
 
+func (c *ingestServiceClient) GetStoredChunks(ctx context.Context, in *StoredChunksRequest, opts ...grpc.CallOption) (*StoredChunksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StoredChunksResponse)
+	err := c.cc.Invoke(ctx, IngestService_GetStoredChunks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestServiceClient) GetTaskURL(ctx context.Context, in *TaskURLRequest, opts ...grpc.CallOption) (*TaskURLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TaskURLResponse)
+	err := c.cc.Invoke(ctx, IngestService_GetTaskURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // IngestServiceServer is the server API for IngestService service.
 // All implementations must embed UnimplementedIngestServiceServer
 // for forward compatibility.
@@ -90,6 +118,12 @@ type IngestServiceServer interface {
 	// Unary 模式：单个 chunk 上传
 	// 使用场景：重试失败的 chunk，或者测试单个 chunk
 	IngestSingleChunk(context.Context, *TextChunk) (*IngestResponse, error)
+	// Unary 模式：查询已存储的 chunk_index
+	// 使用场景：流中断后重试，先查询再只发送缺失的 chunks（metadata 仍需发送）
+	GetStoredChunks(context.Context, *StoredChunksRequest) (*StoredChunksResponse, error)
+	// Unary 模式：获取任务文件的下载链接
+	// 使用场景：ETL worker 从队列取出任务后再获取，链接不会因任务排队过久而过期
+	GetTaskURL(context.Context, *TaskURLRequest) (*TaskURLResponse, error)
 	mustEmbedUnimplementedIngestServiceServer()
 }
 
@@ -106,6 +140,12 @@ func (UnimplementedIngestServiceServer) IngestDocument(grpc.ClientStreamingServe
 func (UnimplementedIngestServiceServer) IngestSingleChunk(context.Context, *TextChunk) (*IngestResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method IngestSingleChunk not implemented")
 }
+func (UnimplementedIngestServiceServer) GetStoredChunks(context.Context, *StoredChunksRequest) (*StoredChunksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStoredChunks not implemented")
+}
+func (UnimplementedIngestServiceServer) GetTaskURL(context.Context, *TaskURLRequest) (*TaskURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTaskURL not implemented")
+}
 func (UnimplementedIngestServiceServer) mustEmbedUnimplementedIngestServiceServer() {}
 func (UnimplementedIngestServiceServer) testEmbeddedByValue()                       {}
 
@@ -152,6 +192,42 @@ func _IngestService_IngestSingleChunk_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IngestService_GetStoredChunks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoredChunksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestServiceServer).GetStoredChunks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestService_GetStoredChunks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestServiceServer).GetStoredChunks(ctx, req.(*StoredChunksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IngestService_GetTaskURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestServiceServer).GetTaskURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestService_GetTaskURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestServiceServer).GetTaskURL(ctx, req.(*TaskURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // IngestService_ServiceDesc is the grpc.ServiceDesc for IngestService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -163,6 +239,14 @@ var IngestService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "IngestSingleChunk",
 			Handler:    _IngestService_IngestSingleChunk_Handler,
 		},
+		{
+			MethodName: "GetStoredChunks",
+			Handler:    _IngestService_GetStoredChunks_Handler,
+		},
+		{
+			MethodName: "GetTaskURL",
+			Handler:    _IngestService_GetTaskURL_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -175,7 +259,8 @@ var IngestService_ServiceDesc = grpc.ServiceDesc{
 }
 
 const (
-	EmbeddingService_GetEmbedding_FullMethodName = "/cognicore.EmbeddingService/GetEmbedding"
+	EmbeddingService_GetEmbedding_FullMethodName  = "/cognicore.EmbeddingService/GetEmbedding"
+	EmbeddingService_GetEmbeddings_FullMethodName = "/cognicore.EmbeddingService/GetEmbeddings"
 )
 
 // EmbeddingServiceClient is the client API for EmbeddingService service.
@@ -191,6 +276,8 @@ const (
 type EmbeddingServiceClient interface {
 	// Go 调用 Python：发送文本，获取向量
 	GetEmbedding(ctx context.Context, in *EmbeddingRequest, opts ...grpc.CallOption) (*EmbeddingResponse, error)
+	// Go 调用 Python：一次发送多条文本，获取对应的向量
+	GetEmbeddings(ctx context.Context, in *BatchEmbeddingRequest, opts ...grpc.CallOption) (*BatchEmbeddingResponse, error)
 }
 
 type embeddingServiceClient struct {
@@ -211,6 +298,16 @@ func (c *embeddingServiceClient) GetEmbedding(ctx context.Context, in *Embedding
 	return out, nil
 }
 
+func (c *embeddingServiceClient) GetEmbeddings(ctx context.Context, in *BatchEmbeddingRequest, opts ...grpc.CallOption) (*BatchEmbeddingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchEmbeddingResponse)
+	err := c.cc.Invoke(ctx, EmbeddingService_GetEmbeddings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // EmbeddingServiceServer is the server API for EmbeddingService service.
 // All implementations must embed UnimplementedEmbeddingServiceServer
 // for forward compatibility.
@@ -224,6 +321,8 @@ func (c *embeddingServiceClient) GetEmbedding(ctx context.Context, in *Embedding
 type EmbeddingServiceServer interface {
 	// Go 调用 Python：发送文本，获取向量
 	GetEmbedding(context.Context, *EmbeddingRequest) (*EmbeddingResponse, error)
+	// Go 调用 Python：一次发送多条文本，获取对应的向量
+	GetEmbeddings(context.Context, *BatchEmbeddingRequest) (*BatchEmbeddingResponse, error)
 	mustEmbedUnimplementedEmbeddingServiceServer()
 }
 
@@ -237,6 +336,9 @@ type UnimplementedEmbeddingServiceServer struct{}
 func (UnimplementedEmbeddingServiceServer) GetEmbedding(context.Context, *EmbeddingRequest) (*EmbeddingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetEmbedding not implemented")
 }
+func (UnimplementedEmbeddingServiceServer) GetEmbeddings(context.Context, *BatchEmbeddingRequest) (*BatchEmbeddingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEmbeddings not implemented")
+}
 func (UnimplementedEmbeddingServiceServer) mustEmbedUnimplementedEmbeddingServiceServer() {}
 func (UnimplementedEmbeddingServiceServer) testEmbeddedByValue()                          {}
 
@@ -276,6 +378,24 @@ func _EmbeddingService_GetEmbedding_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _EmbeddingService_GetEmbeddings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchEmbeddingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmbeddingServiceServer).GetEmbeddings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmbeddingService_GetEmbeddings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmbeddingServiceServer).GetEmbeddings(ctx, req.(*BatchEmbeddingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // EmbeddingService_ServiceDesc is the grpc.ServiceDesc for EmbeddingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -287,6 +407,128 @@ var EmbeddingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetEmbedding",
 			Handler:    _EmbeddingService_GetEmbedding_Handler,
 		},
+		{
+			MethodName: "GetEmbeddings",
+			Handler:    _EmbeddingService_GetEmbeddings_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cognicore.proto",
+}
+
+const (
+	RerankService_Rerank_FullMethodName = "/cognicore.RerankService/Rerank"
+)
+
+// RerankServiceClient is the client API for RerankService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Rerank 服务
+// 角色分配：
+//   - Python：服务端（Server），使用 cross-encoder 对候选打分
+//   - Go：客户端（Client），发送查询和候选 chunks
+//
+// 数据流向：Go → Python → Go
+type RerankServiceClient interface {
+	// Go 调用 Python：发送查询和候选，获取排序后的分数
+	Rerank(ctx context.Context, in *RerankRequest, opts ...grpc.CallOption) (*RerankResponse, error)
+}
+
+type rerankServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRerankServiceClient(cc grpc.ClientConnInterface) RerankServiceClient {
+	return &rerankServiceClient{cc}
+}
+
+func (c *rerankServiceClient) Rerank(ctx context.Context, in *RerankRequest, opts ...grpc.CallOption) (*RerankResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RerankResponse)
+	err := c.cc.Invoke(ctx, RerankService_Rerank_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RerankServiceServer is the server API for RerankService service.
+// All implementations must embed UnimplementedRerankServiceServer
+// for forward compatibility.
+//
+// Rerank 服务
+// 角色分配：
+//   - Python：服务端（Server），使用 cross-encoder 对候选打分
+//   - Go：客户端（Client），发送查询和候选 chunks
+//
+// 数据流向：Go → Python → Go
+type RerankServiceServer interface {
+	// Go 调用 Python：发送查询和候选，获取排序后的分数
+	Rerank(context.Context, *RerankRequest) (*RerankResponse, error)
+	mustEmbedUnimplementedRerankServiceServer()
+}
+
+// UnimplementedRerankServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRerankServiceServer struct{}
+
+func (UnimplementedRerankServiceServer) Rerank(context.Context, *RerankRequest) (*RerankResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rerank not implemented")
+}
+func (UnimplementedRerankServiceServer) mustEmbedUnimplementedRerankServiceServer() {}
+func (UnimplementedRerankServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeRerankServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RerankServiceServer will
+// result in compilation errors.
+type UnsafeRerankServiceServer interface {
+	mustEmbedUnimplementedRerankServiceServer()
+}
+
+func RegisterRerankServiceServer(s grpc.ServiceRegistrar, srv RerankServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRerankServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RerankService_ServiceDesc, srv)
+}
+
+func _RerankService_Rerank_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RerankRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RerankServiceServer).Rerank(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RerankService_Rerank_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RerankServiceServer).Rerank(ctx, req.(*RerankRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RerankService_ServiceDesc is the grpc.ServiceDesc for RerankService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RerankService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cognicore.RerankService",
+	HandlerType: (*RerankServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Rerank",
+			Handler:    _RerankService_Rerank_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "cognicore.proto",
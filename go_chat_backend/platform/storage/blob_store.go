@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrObjectNotFound 对象不存在
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectInfo 对象元信息
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// UploadPolicy 浏览器直传的限制
+type UploadPolicy struct {
+	ContentType string
+	MaxSize     int64
+	TTL         time.Duration
+}
+
+// PresignedUpload 浏览器直传使用的 POST 表单：把 Fields 和文件（字段名 file）一起提交到 URL
+type PresignedUpload struct {
+	URL     string
+	Fields  map[string]string
+	Expires time.Time
+}
+
+// BlobStore 对象存储后端
+type BlobStore interface {
+	PresignUpload(ctx context.Context, key string, policy UploadPolicy) (*PresignedUpload, error)
+	PresignDownload(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// Stat 对象不存在时返回 ErrObjectNotFound
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Open 流式读取对象，调用方负责关闭
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Put 流式写入对象，size 未知时传 -1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Delete 对象不存在时视为成功
+	Delete(ctx context.Context, key string) error
+	// List 遍历 prefix 下的所有对象，fn 返回错误时停止
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go_chat_backend/pkg/logging"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本地存储签名链接对应的路由（见 routes.RegisterBlobRoutes）
+const (
+	LocalUploadPath   = "/api/blob/upload"
+	LocalDownloadPath = "/api/blob/download"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrURLExpired       = errors.New("signed URL expired")
+)
+
+// LocalStore 本地磁盘实现，用于开发和 CI，无需 MinIO
+// 上传/下载链接指向本服务的路由，以 HMAC 签名约束 key、有效期、大小和类型
+type LocalStore struct {
+	root    string
+	baseURL string
+	secret  []byte
+}
+
+// NewLocalStore secret 为空时随机生成，重启后之前签发的链接失效
+func NewLocalStore(root, baseURL, secret string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		logging.Logger.Warn("STORAGE_SIGNING_KEY not set, signed URLs will not survive a restart")
+	}
+	return &LocalStore{root: root, baseURL: strings.TrimRight(baseURL, "/"), secret: key}, nil
+}
+
+// path key 对应的文件路径，拒绝跳出根目录的 key
+func (s *LocalStore) path(key string) (string, error) {
+	clean := path.Clean("/" + key)[1:]
+	if clean == "" || clean != key {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+func (s *LocalStore) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalStore) verify(signature, expires string, parts ...string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(append(parts, expires)...))) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > exp {
+		return ErrURLExpired
+	}
+	return nil
+}
+
+func (s *LocalStore) PresignUpload(ctx context.Context, key string, policy UploadPolicy) (*PresignedUpload, error) {
+	if _, err := s.path(key); err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(policy.TTL)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	maxSize := strconv.FormatInt(policy.MaxSize, 10)
+	return &PresignedUpload{
+		URL: s.baseURL + LocalUploadPath,
+		Fields: map[string]string{
+			"key":          key,
+			"Content-Type": policy.ContentType,
+			"max_size":     maxSize,
+			"expires":      expires,
+			"signature":    s.sign("POST", key, policy.ContentType, maxSize, expires),
+		},
+		Expires: expiresAt,
+	}, nil
+}
+
+// VerifyUpload 校验上传表单的签名，返回允许的最大大小
+func (s *LocalStore) VerifyUpload(fields map[string]string) (int64, error) {
+	err := s.verify(fields["signature"], fields["expires"], "POST", fields["key"], fields["Content-Type"], fields["max_size"])
+	if err != nil {
+		return 0, err
+	}
+	maxSize, err := strconv.ParseInt(fields["max_size"], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidSignature
+	}
+	return maxSize, nil
+}
+
+func (s *LocalStore) PresignDownload(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("key", key)
+	q.Set("expires", expires)
+	q.Set("signature", s.sign("GET", key, expires))
+	return s.baseURL + LocalDownloadPath + "?" + q.Encode(), nil
+}
+
+// VerifyDownload 校验下载链接的签名
+func (s *LocalStore) VerifyDownload(key, expires, signature string) error {
+	return s.verify(signature, expires, "GET", key)
+}
+
+func (s *LocalStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{Key: key, Size: fi.Size(), ContentType: mime.TypeByExtension(filepath.Ext(p)), LastModified: fi.ModTime()}, nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return f, err
+}
+
+// Put 先写临时文件再改名，读者不会看到写了一半的对象
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && n != size {
+		return fmt.Errorf("short write: %d of %d bytes", n, size)
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	return filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(ObjectInfo{Key: key, Size: fi.Size(), ContentType: mime.TypeByExtension(filepath.Ext(p)), LastModified: fi.ModTime()})
+	})
+}
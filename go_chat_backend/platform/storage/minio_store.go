@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"go_chat_backend/pkg/logging"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// minioStore MinIO / S3 实现
+type minioStore struct {
+	client      *minio.Client
+	bucket      string
+	region      string
+	storageType string
+}
+
+func NewMinioStore(client *minio.Client, bucket, region, storageType string) (BlobStore, error) {
+	s := &minioStore{client: client, bucket: bucket, region: region, storageType: storageType}
+	if err := s.ensureBucketExists(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *minioStore) ensureBucketExists() error {
+	ctx := context.Background()
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		logging.Logger.Error("fail ensureBucketExists", "error", err)
+		return err
+	}
+	if exists {
+		logging.Logger.Info("Bucket already exists")
+		return nil
+	}
+	err = s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{
+		Region: s.region,
+	})
+	if err != nil {
+		if s.storageType == "s3" {
+			logging.Logger.Warn("Could not create S3 bucket (might exist or no permission)",
+				"bucket", s.bucket, "error", err)
+			return nil
+		}
+		logging.Logger.Error("fail ensureBucketExists", "error", err)
+		return err
+	}
+	logging.Logger.Info("Bucket created successfully")
+	return nil
+}
+
+func (s *minioStore) PresignUpload(ctx context.Context, key string, policy UploadPolicy) (*PresignedUpload, error) {
+	expires := time.Now().Add(policy.TTL)
+	p := minio.NewPostPolicy()
+	if err := p.SetBucket(s.bucket); err != nil {
+		return nil, err
+	}
+	if err := p.SetKey(key); err != nil {
+		return nil, err
+	}
+	if err := p.SetExpires(expires); err != nil {
+		return nil, err
+	}
+	if policy.MaxSize > 0 {
+		if err := p.SetContentLengthRange(1, policy.MaxSize); err != nil {
+			return nil, err
+		}
+	}
+	if policy.ContentType != "" {
+		if err := p.SetContentType(policy.ContentType); err != nil {
+			return nil, err
+		}
+	}
+	postURL, formData, err := s.client.PresignedPostPolicy(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate presigned POST: %w", err)
+	}
+	return &PresignedUpload{URL: postURL.String(), Fields: formData, Expires: expires}, nil
+}
+
+func (s *minioStore) PresignDownload(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, ttl, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (s *minioStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	return &ObjectInfo{Key: info.Key, Size: info.Size, ContentType: info.ContentType, LastModified: info.LastModified}, nil
+}
+
+func (s *minioStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject 是惰性的，Stat 一次以便对象不存在时立即返回错误
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *minioStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *minioStore) Delete(ctx context.Context, key string) error {
+	err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return err
+	}
+	return nil
+}
+
+func (s *minioStore) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := fn(ObjectInfo{Key: obj.Key, Size: obj.Size, ContentType: obj.ContentType, LastModified: obj.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
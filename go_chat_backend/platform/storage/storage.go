@@ -2,54 +2,52 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/minio/minio-go/v7"
 	"go_chat_backend/config"
 	"go_chat_backend/models"
 	"go_chat_backend/pkg/logging"
 	"go_chat_backend/utils"
+	"io"
 	"time"
 )
 
+// uploadURLTTL 浏览器直传链接有效期
+const uploadURLTTL = 15 * time.Minute
+
 type Service struct {
-	Client           *minio.Client
-	Config           *minio.Options
-	Bucket           string
+	Store            BlobStore
 	StorageType      string
 	FileKeyGenerator *utils.FileKeyGenerator
+	DownloadURLTTL   time.Duration
+	TaskURLTTL       time.Duration
 }
 
 func InitStorageService(cfg *config.Config) (*Service, error) {
-	// bucket
-	var minioClient *minio.Client
+	var store BlobStore
 	var err error
 
-	// local vs s3
+	// minio / s3 / local
 	switch cfg.StorageType {
-	case "minio":
-		minioClient, err = utils.CreateMinIOClient(cfg)
-	case "s3":
-		minioClient, err = utils.CreateS3Client(cfg)
+	case "minio", "s3":
+		store, err = newBucketStore(cfg)
+	case "local":
+		store, err = NewLocalStore(cfg.LocalStorageDir, cfg.PublicBaseURL, cfg.StorageSigningKey)
 	default:
-		logging.Logger.Error("fail InitStorageService, type error", err)
-		return nil, err
+		err = fmt.Errorf("unknown storage type %q", cfg.StorageType)
 	}
 	if err != nil {
-		logging.Logger.Error("fail InitStorageService", err)
+		logging.Logger.Error("fail InitStorageService", "error", err)
 		return nil, err
 	}
 	// generate callback message
 	keyGenerator := utils.NewFileKeyGenerator(utils.StrategyDateBased, "pdfs")
 	ss := &Service{
-		Client:           minioClient,
-		Config:           &minio.Options{Region: cfg.BucketRegion},
-		Bucket:           cfg.BucketName,
+		Store:            store,
 		StorageType:      cfg.StorageType,
 		FileKeyGenerator: keyGenerator,
-	}
-	if err := ss.EnsureBucketExists(); err != nil {
-		logging.Logger.Error("fail InitStorageService", err)
-		return nil, err
+		DownloadURLTTL:   cfg.DownloadURLTTL,
+		TaskURLTTL:       cfg.TaskURLTTL,
 	}
 	logging.Logger.Info("Storage service initialized",
 		"type", cfg.StorageType,
@@ -59,102 +57,107 @@ func InitStorageService(cfg *config.Config) (*Service, error) {
 
 	return ss, nil
 }
-func (ss *Service) EnsureBucketExists() error {
-	ctx := context.Background()
-	exists, err := ss.Client.BucketExists(ctx, ss.Bucket)
-	if err != nil {
-		logging.Logger.Error("fail ensureBucketExists", err)
-		return err
-	}
-	if exists {
-		logging.Logger.Info("Bucket already exists")
-		return nil
+
+func newBucketStore(cfg *config.Config) (BlobStore, error) {
+	create := utils.CreateMinIOClient
+	if cfg.StorageType == "s3" {
+		create = utils.CreateS3Client
 	}
-	err = ss.Client.MakeBucket(ctx, ss.Bucket, minio.MakeBucketOptions{
-		Region: ss.Config.Region,
-	})
+	client, err := create(cfg)
 	if err != nil {
-		if ss.StorageType == "s3" {
-			logging.Logger.Warn("Could not create S3 bucket (might exist or no permission)",
-				"bucket", ss.Bucket, "error", err)
-			return nil
-		}
-		logging.Logger.Error("fail ensureBucketExists", err)
-		return err
+		return nil, err
 	}
-	logging.Logger.Info("Bucket created successfully")
-	return nil
+	return NewMinioStore(client, cfg.BucketName, cfg.BucketRegion, cfg.StorageType)
+}
+
+// LocalStore 本地存储时返回底层实现，供签名链接路由使用
+func (ss *Service) LocalStore() (*LocalStore, bool) {
+	ls, ok := ss.Store.(*LocalStore)
+	return ls, ok
 }
 
 func (ss *Service) GeneratePresignedPostUpload(filename string, maxFileSize int64, docID string) (*models.UploadResp, error) {
 	fileKey := ss.FileKeyGenerator.GenerateFileKey(filename, "")
 
-	policy := minio.NewPostPolicy()
-	err := policy.SetBucket(ss.Bucket)
-	if err != nil {
-		return nil, err
-	}
-	err = policy.SetKey(fileKey)
-	if err != nil {
-		return nil, err
-	}
-	err = policy.SetExpires(time.Now().Add(15 * time.Minute))
-	if err != nil {
-		return nil, err
-	}
-
-	if maxFileSize > 0 {
-		err := policy.SetContentLengthRange(1, maxFileSize)
-		if err != nil {
-			return nil, err
-		}
-	}
-	err = policy.SetContentType("application/pdf")
+	upload, err := ss.Store.PresignUpload(context.Background(), fileKey, UploadPolicy{
+		ContentType: "application/pdf",
+		MaxSize:     maxFileSize,
+		TTL:         uploadURLTTL,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	postURL, formData, err := ss.Client.PresignedPostPolicy(context.Background(), policy)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate presigned POST: %w", err)
-	}
-
 	return &models.UploadResp{
 		DocId:     docID,
-		UploadURL: postURL.String(),
+		UploadURL: upload.URL,
 		FileKey:   fileKey,
-		Fields:    formData,
-		Expires:   time.Now().Add(15 * time.Minute),
+		Fields:    upload.Fields,
+		Expires:   upload.Expires,
 		Provider:  ss.StorageType,
 	}, nil
 }
 func (ss *Service) GeneratePresignedGetDownload(fileKey string, expiration time.Time) (string, error) {
 	duration := time.Until(expiration)
 	if duration <= 0 {
-		logging.Logger.Error("fail GeneratePresignedGetDownload, expiration error", expiration)
+		logging.Logger.Error("fail GeneratePresignedGetDownload, expiration error", "expiration", expiration)
 		return "", fmt.Errorf("expiration error")
 	}
-	presignedURL, err := ss.Client.PresignedGetObject(
-		context.Background(),
-		ss.Bucket,
-		fileKey,
-		duration,
-		nil,
-	)
+	presignedURL, err := ss.Store.PresignDownload(context.Background(), fileKey, duration)
 	if err != nil {
-		logging.Logger.Error("fail GeneratePresignedGetDownload", err)
+		logging.Logger.Error("fail GeneratePresignedGetDownload", "error", err)
 		return "", err
 	}
-	return presignedURL.String(), nil
+	return presignedURL, nil
+}
+
+// DownloadURL 为用户生成下载链接，返回链接和过期时间
+func (ss *Service) DownloadURL(fileKey string) (string, time.Time, error) {
+	expires := time.Now().Add(ss.DownloadURLTTL)
+	url, err := ss.GeneratePresignedGetDownload(fileKey, expires)
+	return url, expires, err
+}
+
+// TaskURL 为 ETL 任务生成下载链接，返回链接和过期时间；worker 取出任务时通过 GetTaskURL 获取，
+// TaskURLTTL 只需覆盖下载时间，不需要覆盖排队时间
+func (ss *Service) TaskURL(fileKey string) (string, time.Time, error) {
+	expires := time.Now().Add(ss.TaskURLTTL)
+	url, err := ss.GeneratePresignedGetDownload(fileKey, expires)
+	return url, expires, err
 }
 
 func (ss *Service) FileExists(fileKey string) (bool, error) {
-	_, err := ss.Client.StatObject(context.Background(), ss.Bucket, fileKey, minio.StatObjectOptions{})
+	_, err := ss.Store.Stat(context.Background(), fileKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
 	if err != nil {
-		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
-			return false, nil
-		}
 		return false, err
 	}
 	return true, nil
 }
+
+// Stat 对象不存在时返回 ErrObjectNotFound
+func (ss *Service) Stat(ctx context.Context, fileKey string) (*ObjectInfo, error) {
+	return ss.Store.Stat(ctx, fileKey)
+}
+
+// OpenFile 流式读取对象，调用方负责关闭
+func (ss *Service) OpenFile(ctx context.Context, fileKey string) (io.ReadCloser, error) {
+	return ss.Store.Open(ctx, fileKey)
+}
+
+// PutFile 流式写入对象，size 未知时传 -1
+func (ss *Service) PutFile(ctx context.Context, fileKey string, r io.Reader, size int64, contentType string) error {
+	return ss.Store.Put(ctx, fileKey, r, size, contentType)
+}
+
+// ListFiles 遍历 FileKeyGenerator 前缀下的所有对象
+func (ss *Service) ListFiles(ctx context.Context, fn func(ObjectInfo) error) error {
+	return ss.Store.List(ctx, ss.FileKeyGenerator.Prefix()+"/", fn)
+}
+
+// DeleteFile 删除对象，对象不存在时视为成功
+func (ss *Service) DeleteFile(fileKey string) error {
+	return ss.Store.Delete(context.Background(), fileKey)
+}
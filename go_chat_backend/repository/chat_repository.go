@@ -49,3 +49,18 @@ func (r *chatRepository) GetNodeByID(ctx context.Context, nodeID string, fileID
 	}
 	return &res, nil
 }
+func (r *chatRepository) ListNodeIDs(ctx context.Context, fileID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&models.ChatNode{}).Where("file_id = ?", fileID).Pluck("id", &ids).Error
+	return ids, err
+}
+func (r *chatRepository) DeleteByFileID(ctx context.Context, fileID string) (int64, error) {
+	res := r.db.WithContext(ctx).Where("file_id = ?", fileID).Delete(&models.ChatNode{})
+	return res.RowsAffected, res.Error
+}
+func (r *chatRepository) UpdateNode(ctx context.Context, nodeID string, fileID string, question, answer string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.ChatNode{}).
+		Where("id = ? AND file_id = ?", nodeID, fileID).
+		Updates(map[string]interface{}{"question": question, "answer": answer}).Error
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"go_chat_backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type chunkGenerationRepository struct {
+	DB *gorm.DB
+}
+
+func NewChunkGenerationRepository(db *gorm.DB) ChunkGenerationRepository {
+	return &chunkGenerationRepository{DB: db}
+}
+
+func (r *chunkGenerationRepository) Create(ctx context.Context, gen *models.ChunkGeneration) error {
+	return r.DB.WithContext(ctx).Create(gen).Error
+}
+
+func (r *chunkGenerationRepository) GetByID(ctx context.Context, id string) (*models.ChunkGeneration, error) {
+	var gen models.ChunkGeneration
+	err := r.DB.WithContext(ctx).Where("id = ?", id).First(&gen).Error
+	return &gen, err
+}
+
+func (r *chunkGenerationRepository) ListByDoc(ctx context.Context, docID string) ([]*models.ChunkGeneration, error) {
+	var gens []*models.ChunkGeneration
+	err := r.DB.WithContext(ctx).Where("doc_id = ?", docID).Order("created_at ASC").Find(&gens).Error
+	return gens, err
+}
+
+func (r *chunkGenerationRepository) Update(ctx context.Context, gen *models.ChunkGeneration) error {
+	return r.DB.WithContext(ctx).Save(gen).Error
+}
+
+func (r *chunkGenerationRepository) RetireActive(ctx context.Context, docID string, exceptID string) error {
+	return r.DB.WithContext(ctx).
+		Model(&models.ChunkGeneration{}).
+		Where("doc_id = ? AND status = ? AND id <> ?", docID, models.GenerationActive, exceptID).
+		Update("status", models.GenerationRetired).Error
+}
+
+func (r *chunkGenerationRepository) ListReplacedDue(ctx context.Context, now time.Time, limit int) ([]*models.ChunkGeneration, error) {
+	var gens []*models.ChunkGeneration
+	err := r.DB.WithContext(ctx).
+		Where("replaced_chunk_set <> '' AND replaced_purge_after <= ?", now).
+		Order("replaced_purge_after ASC").
+		Limit(limit).
+		Find(&gens).Error
+	return gens, err
+}
+
+func (r *chunkGenerationRepository) ClearReplaced(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).
+		Model(&models.ChunkGeneration{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"replaced_chunk_set": "", "replaced_purge_after": nil}).Error
+}
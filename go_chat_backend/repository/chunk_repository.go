@@ -4,23 +4,109 @@ import (
 	"context"
 	"go_chat_backend/models"
 
+	"github.com/pgvector/pgvector-go"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type chunkRepository struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	store VectorStore
 }
 
-func NewChunkRepository(db *gorm.DB) ChunkRepository {
-	return &chunkRepository{DB: db}
+// NewChunkRepository store 为 nil 时使用 pgvector
+func NewChunkRepository(db *gorm.DB, store VectorStore) ChunkRepository {
+	if store == nil {
+		store = NewPgVectorStore(db, PgVectorSearch{})
+	}
+	return &chunkRepository{DB: db, store: store}
 }
 
-func (r *chunkRepository) BatchCreate(ctx context.Context, chunks []*models.Chunk) error {
-	return r.DB.WithContext(ctx).Create(chunks).Error
+// BatchCreate 在一个事务中按 (file_id, chunk_index) 批量写入；整批失败时在 savepoint 中逐个重试，
+// 定位出错的 chunk，其余照常提交。errs 与 chunks 一一对应，全部成功时为 nil
+func (r *chunkRepository) BatchCreate(ctx context.Context, chunks []*models.Chunk) ([]error, error) {
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	var errs []error
+	stored := chunks
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// PostgreSQL 中语句出错后整个事务不可用，需要回滚到 savepoint 才能继续
+		if err := tx.SavePoint("chunk_batch").Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(upsertChunkClauses()...).Create(chunks).Error; err == nil {
+			return nil
+		}
+		if err := tx.RollbackTo("chunk_batch").Error; err != nil {
+			return err
+		}
+
+		errs = make([]error, len(chunks))
+		stored = nil
+		for i, chunk := range chunks {
+			if err := tx.SavePoint("chunk_single").Error; err != nil {
+				return err
+			}
+			if err := tx.Clauses(upsertChunkClauses()...).Create(chunk).Error; err != nil {
+				errs[i] = err
+				if err := tx.RollbackTo("chunk_single").Error; err != nil {
+					return err
+				}
+				continue
+			}
+			stored = append(stored, chunk)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return errs, r.mirror(ctx, stored)
 }
 
 func (r *chunkRepository) Create(ctx context.Context, chunk *models.Chunk) error {
-	return r.DB.WithContext(ctx).Create(chunk).Error
+	if err := r.DB.WithContext(ctx).Create(chunk).Error; err != nil {
+		return err
+	}
+	return r.mirror(ctx, []*models.Chunk{chunk})
+}
+
+// Upsert 按 (file_id, chunk_index) 插入或覆盖，重复写入同一个 chunk 是幂等的；
+// 已存在时保留原来的 chunk_id，外部 VectorStore 中的向量仍然对应同一个 ID
+func (r *chunkRepository) Upsert(ctx context.Context, chunk *models.Chunk) error {
+	err := r.DB.WithContext(ctx).
+		Clauses(upsertChunkClauses()...).
+		Create(chunk).Error
+	if err != nil {
+		return err
+	}
+	return r.mirror(ctx, []*models.Chunk{chunk})
+}
+
+// upsertChunkClauses 按 (file_id, chunk_index) 覆盖写入，并取回已存在的 chunk_id
+func upsertChunkClauses() []clause.Expression {
+	return []clause.Expression{
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "file_id"}, {Name: "chunk_index"}},
+			DoUpdates: clause.AssignmentColumns([]string{"chapter", "chunk_text", "embedding_vector", "embedding_model"}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "chunk_id"}}},
+	}
+}
+
+// external 向量是否保存在 chunks 表之外
+func (r *chunkRepository) external() bool {
+	_, ok := r.store.(*pgVectorStore)
+	return !ok
+}
+
+// mirror chunks 表始终保存向量；使用外部 VectorStore 时同步写入
+func (r *chunkRepository) mirror(ctx context.Context, chunks []*models.Chunk) error {
+	if !r.external() {
+		return nil
+	}
+	return r.store.Upsert(ctx, chunks)
 }
 
 func (r *chunkRepository) GetByFileID(ctx context.Context, fileID string) ([]*models.Chunk, error) {
@@ -35,36 +121,67 @@ func (r *chunkRepository) GetByFileID(ctx context.Context, fileID string) ([]*mo
 	return chunks, nil
 }
 
+func (r *chunkRepository) GetTextsByFileID(ctx context.Context, fileID string) ([]*models.Chunk, error) {
+	var chunks []*models.Chunk
+	err := r.DB.WithContext(ctx).
+		Select("chunk_id", "file_id", "chunk_index", "chapter", "chunk_text").
+		Where("file_id = ?", fileID).
+		Order("chunk_index ASC").
+		Find(&chunks).Error
+	if err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
 func (r *chunkRepository) GetNodeBySection(ctx context.Context, section string, fileID string) (*models.Chunk, error) {
 	var chunk models.Chunk
 	err := r.DB.WithContext(ctx).Where("chapter = ? AND file_id = ?", section, fileID).First(&chunk).Error
 	return &chunk, err
 }
 
-func (r *chunkRepository) SearchSimilar(ctx context.Context, embedding []float32, limit int) ([]*models.Chunk, error) {
+// SearchSimilar 在 VectorStore 中搜索后从数据库加载命中的 chunks，保持相似度顺序
+func (r *chunkRepository) SearchSimilar(ctx context.Context, fileID string, model string, embedding []float32, limit int) ([]*models.ScoredChunk, error) {
+	hits, err := r.store.Search(ctx, embedding, VectorFilter{FileID: fileID, Model: model}, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return []*models.ScoredChunk{}, nil
+	}
+
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ChunkID
+	}
 	var chunks []*models.Chunk
+	if err := r.DB.WithContext(ctx).Where("chunk_id IN ?", ids).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Chunk, len(chunks))
+	for _, c := range chunks {
+		byID[c.ChunkID] = c
+	}
 
-	// 将 []float32 转换为 pgvector.Vector
-	queryVector := make([]float64, len(embedding))
-	for i, v := range embedding {
-		queryVector[i] = float64(v)
+	res := make([]*models.ScoredChunk, 0, len(hits))
+	for _, h := range hits {
+		if c, ok := byID[h.ChunkID]; ok {
+			res = append(res, &models.ScoredChunk{Chunk: c, Score: h.Score})
+		}
 	}
+	return res, nil
+}
 
-	// 使用余弦相似度进行向量搜索
-	// <=> 是 pgvector 的余弦距离操作符（值越小越相似）
-	// 也可以使用：
-	// <-> L2 距离（欧几里得距离）
-	// <#> 负内积（最大内积搜索）
+// GetByIndexRange 获取同一文件同一章节内 chunk_index 在 [from, to] 区间的 chunks
+func (r *chunkRepository) GetByIndexRange(ctx context.Context, fileID string, chapter string, from, to int32) ([]*models.Chunk, error) {
+	var chunks []*models.Chunk
 	err := r.DB.WithContext(ctx).
-		Select("chunk_id, file_id, chunk_index, chapter, chunk_text, embedding_vector, created_at").
-		Order(gorm.Expr("embedding_vector <=> ?", queryVector)).
-		Limit(limit).
+		Where("file_id = ? AND chapter = ? AND chunk_index BETWEEN ? AND ?", fileID, chapter, from, to).
+		Order("chunk_index ASC").
 		Find(&chunks).Error
-
 	if err != nil {
 		return nil, err
 	}
-
 	return chunks, nil
 }
 
@@ -85,3 +202,93 @@ func (r *chunkRepository) CountByFileID(ctx context.Context, fileID string) (int
 		Count(&count).Error
 	return count, err
 }
+
+func (r *chunkRepository) ListChunkIndexes(ctx context.Context, fileID string) ([]int32, error) {
+	var indexes []int32
+	err := r.DB.WithContext(ctx).
+		Model(&models.Chunk{}).
+		Where("file_id = ?", fileID).
+		Order("chunk_index ASC").
+		Pluck("chunk_index", &indexes).Error
+	return indexes, err
+}
+
+func (r *chunkRepository) GetStaleEmbeddings(ctx context.Context, fileID string, model string, limit int) ([]*models.Chunk, error) {
+	var chunks []*models.Chunk
+	err := r.DB.WithContext(ctx).
+		Where("file_id = ? AND (embedding_model IS NULL OR embedding_model <> ?)", fileID, model).
+		Order("chunk_index ASC").
+		Limit(limit).
+		Find(&chunks).Error
+	if err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (r *chunkRepository) UpdateEmbedding(ctx context.Context, chunkID string, model string, embedding []float32) error {
+	err := r.DB.WithContext(ctx).
+		Model(&models.Chunk{}).
+		Where("chunk_id = ?", chunkID).
+		Updates(map[string]interface{}{
+			"embedding_vector": pgvector.NewVector(embedding),
+			"embedding_model":  model,
+		}).Error
+	if err != nil || !r.external() {
+		return err
+	}
+	var chunk models.Chunk
+	if err := r.DB.WithContext(ctx).Select("chunk_id, file_id, chapter").Where("chunk_id = ?", chunkID).First(&chunk).Error; err != nil {
+		return err
+	}
+	chunk.EmbeddingVector = pgvector.NewVector(embedding)
+	chunk.EmbeddingModel = model
+	return r.store.Upsert(ctx, []*models.Chunk{&chunk})
+}
+
+func (r *chunkRepository) AssignLegacyModel(ctx context.Context, model string, dimension int) (int64, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.Chunk{}).
+		Where("(embedding_model IS NULL OR embedding_model = '') AND vector_dims(embedding_vector) = ?", dimension).
+		Update("embedding_model", model)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.RowsAffected, res.Error
+	}
+	// 已缓存的向量仍然记录着空模型，会被模型过滤掉
+	return res.RowsAffected, r.store.Invalidate(ctx, "")
+}
+
+func (r *chunkRepository) InvalidateVectors(ctx context.Context, fileID string) error {
+	return r.store.Invalidate(ctx, fileID)
+}
+
+func (r *chunkRepository) DeleteByFileID(ctx context.Context, fileID string) (int64, error) {
+	res := r.DB.WithContext(ctx).Where("file_id = ?", fileID).Delete(&models.Chunk{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return res.RowsAffected, r.store.DeleteByFile(ctx, fileID)
+}
+
+// missingEmbedding chat-only 模式下 ETL 写入全零向量
+const missingEmbedding = "(embedding_vector IS NULL OR vector_norm(embedding_vector) = 0)"
+
+func (r *chunkRepository) GetMissingEmbeddings(ctx context.Context, fileID string, limit int) ([]*models.Chunk, error) {
+	var chunks []*models.Chunk
+	err := r.DB.WithContext(ctx).
+		Where("file_id = ?", fileID).
+		Where(missingEmbedding).
+		Order("chunk_index ASC").
+		Limit(limit).
+		Find(&chunks).Error
+	if err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (r *chunkRepository) CountMissingEmbeddings(ctx context.Context, fileID string) (int64, error) {
+	var n int64
+	err := r.DB.WithContext(ctx).Model(&models.Chunk{}).Where("file_id = ?", fileID).Where(missingEmbedding).Count(&n).Error
+	return n, err
+}
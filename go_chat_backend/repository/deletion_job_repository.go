@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"go_chat_backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type deletionJobRepository struct {
+	DB *gorm.DB
+}
+
+func NewDeletionJobRepository(db *gorm.DB) DeletionJobRepository {
+	return &deletionJobRepository{DB: db}
+}
+
+func (r *deletionJobRepository) Create(ctx context.Context, job *models.DeletionJob) error {
+	return r.DB.WithContext(ctx).Create(job).Error
+}
+
+func (r *deletionJobRepository) GetByDocID(ctx context.Context, docID string) (*models.DeletionJob, error) {
+	var job models.DeletionJob
+	err := r.DB.WithContext(ctx).Where("doc_id = ?", docID).First(&job).Error
+	return &job, err
+}
+
+func (r *deletionJobRepository) ListByUser(ctx context.Context, userID string, status string) ([]*models.DeletionJob, error) {
+	var jobs []*models.DeletionJob
+	err := r.DB.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, status).
+		Order("created_at DESC").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *deletionJobRepository) ListDue(ctx context.Context, now time.Time, staleAfter time.Duration, limit int) ([]*models.DeletionJob, error) {
+	var jobs []*models.DeletionJob
+	err := r.DB.WithContext(ctx).
+		Scopes(dueJobs(now, staleAfter)).
+		Order("purge_after ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *deletionJobRepository) Claim(ctx context.Context, docID string, now time.Time, staleAfter time.Duration) (bool, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.DeletionJob{}).
+		Scopes(dueJobs(now, staleAfter)).
+		Where("doc_id = ?", docID).
+		Updates(map[string]interface{}{
+			"status":     models.DeletionRunning,
+			"updated_at": now,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// dueJobs 到期的 pending/failed 任务，以及超过 staleAfter 未更新的 running 任务
+func dueJobs(now time.Time, staleAfter time.Duration) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"purge_after <= ? AND (status IN ? OR (status = ? AND updated_at < ?))",
+			now, []string{models.DeletionPending, models.DeletionFailed}, models.DeletionRunning, now.Add(-staleAfter),
+		)
+	}
+}
+
+func (r *deletionJobRepository) Update(ctx context.Context, job *models.DeletionJob) error {
+	return r.DB.WithContext(ctx).Save(job).Error
+}
+
+func (r *deletionJobRepository) DeletePending(ctx context.Context, docID string) (bool, error) {
+	res := r.DB.WithContext(ctx).
+		Where("doc_id = ? AND status = ? AND step = ?", docID, models.DeletionPending, models.DeletionSteps[0]).
+		Delete(&models.DeletionJob{})
+	return res.RowsAffected == 1, res.Error
+}
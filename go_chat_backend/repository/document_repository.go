@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"go_chat_backend/models"
+	"strings"
+	"time"
 
 	"github.com/lib/pq"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type documentRepository struct {
@@ -33,38 +35,204 @@ func (r *documentRepository) GetByHash(ctx context.Context, fileHash string) (*m
 	return &doc, err
 }
 
+func (r *documentRepository) List(ctx context.Context, filter models.DocumentFilter) ([]*models.DocumentMeta, int64, error) {
+	query := r.DB.WithContext(ctx).Model(&models.DocumentMeta{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Filename != "" {
+		query = query.Where("filename ILIKE ?", "%"+escapeLike(filter.Filename)+"%")
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var docs []*models.DocumentMeta
+	err := query.
+		Order("created_at DESC").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Find(&docs).Error
+	return docs, total, err
+}
+
+// usesChunkSet 文档的 ChunkFileID 等于给定值（参数传两次）
+const usesChunkSet = "(chunk_source_id = ? OR (file_id = ? AND (chunk_source_id IS NULL OR chunk_source_id = '')))"
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func (r *documentRepository) UpdateStatus(ctx context.Context, fileID string, status string) error {
 	return r.DB.WithContext(ctx).Model(&models.DocumentMeta{}).Where("file_id = ?", fileID).Update("status", status).Error
 }
 
 func (r *documentRepository) UpdateProcessingStats(ctx context.Context, fileID string, received, stored, failed int32) error {
-	return r.DB.WithContext(ctx).Model(&models.DocumentMeta{}).Where("file_id = ?", fileID).Update("received", received).Update("stored", stored).Update("failed", failed).Error
+	return r.DB.WithContext(ctx).
+		Model(&models.DocumentMeta{}).
+		Where("file_id = ?", fileID).
+		Updates(map[string]interface{}{
+			"chunks_received": received,
+			"chunks_stored":   stored,
+			"chunks_failed":   failed,
+		}).Error
+}
+
+func (r *documentRepository) UpdateChunksTotal(ctx context.Context, fileID string, total int32) error {
+	return r.DB.WithContext(ctx).
+		Model(&models.DocumentMeta{}).
+		Where("file_id = ?", fileID).
+		Update("chunks_total", total).Error
+}
+
+func (r *documentRepository) TransitionStatus(ctx context.Context, fileID string, from string, fields map[string]interface{}) (bool, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.DocumentMeta{}).
+		Where("file_id = ? AND status = ?", fileID, from).
+		Updates(fields)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *documentRepository) FindProcessedByHash(ctx context.Context, fileHash string, ragMode bool, excludeFileID string) (*models.DocumentMeta, error) {
+	var doc models.DocumentMeta
+	err := r.DB.WithContext(ctx).
+		Where("file_hash = ? AND status IN ? AND rag_mode = ? AND file_id <> ?", fileHash, models.CompletedStatuses, ragMode, excludeFileID).
+		Order("completed_at ASC").
+		First(&doc).Error
+	return &doc, err
+}
+
+func (r *documentRepository) UpdateFileHash(ctx context.Context, fileID string, fileHash string) error {
+	return r.DB.WithContext(ctx).Model(&models.DocumentMeta{}).Where("file_id = ?", fileID).Update("file_hash", fileHash).Error
+}
+
+func (r *documentRepository) UpdateSections(ctx context.Context, fileID string, sections []string) error {
+	return r.DB.WithContext(ctx).Model(&models.DocumentMeta{}).Where("file_id = ?", fileID).Update("sections", pq.StringArray(sections)).Error
+}
+
+func (r *documentRepository) UpdateRagMode(ctx context.Context, fileID string, ragMode bool) error {
+	return r.DB.WithContext(ctx).Model(&models.DocumentMeta{}).Where("file_id = ?", fileID).Update("rag_mode", ragMode).Error
+}
+
+func (r *documentRepository) ClaimRagBackfill(ctx context.Context, fileID string, now, staleBefore time.Time) (bool, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.DocumentMeta{}).
+		Where("file_id = ? AND (rag_backfill_status IS DISTINCT FROM ? OR rag_backfill_at IS NULL OR rag_backfill_at < ?)", fileID, models.RagBackfillRunning, staleBefore).
+		Updates(map[string]interface{}{"rag_backfill_status": models.RagBackfillRunning, "rag_backfill_at": now})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *documentRepository) TouchRagBackfill(ctx context.Context, fileID string, now time.Time) (bool, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.DocumentMeta{}).
+		Where("file_id = ? AND rag_backfill_status = ?", fileID, models.RagBackfillRunning).
+		Update("rag_backfill_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *documentRepository) FinishRagBackfill(ctx context.Context, fileID string) (bool, error) {
+	res := r.DB.WithContext(ctx).
+		Model(&models.DocumentMeta{}).
+		Where("file_id = ? AND rag_backfill_status = ?", fileID, models.RagBackfillRunning).
+		Updates(map[string]interface{}{"rag_mode": true, "rag_backfill_status": "", "rag_backfill_at": nil})
+	return res.RowsAffected > 0, res.Error
 }
 
-func (r *documentRepository) UpsertByHash(ctx context.Context, doc *models.DocumentMeta) error {
+func (r *documentRepository) SetRagBackfillStatus(ctx context.Context, fileID string, status string) error {
 	return r.DB.WithContext(ctx).
-		Clauses(clause.OnConflict{
-			Columns: []clause.Column{{Name: "file_hash"}},
-			Where: clause.Where{
-				Exprs: []clause.Expression{
-					clause.Or(
-						clause.Eq{Column: "document_meta.status", Value: models.StatusFailed},
-						clause.Eq{Column: "document_meta.status", Value: models.StatusProcessing},
-					),
-				},
-			},
-			DoUpdates: clause.AssignmentColumns([]string{
-				"total_pages",
-				"estimated_chunks",
-				"started_at",
-			}),
-		}).
-		Create(doc).Error
+		Model(&models.DocumentMeta{}).
+		Where("file_id = ?", fileID).
+		Updates(map[string]interface{}{"rag_backfill_status": status, "rag_backfill_at": nil}).Error
+}
+
+func (r *documentRepository) ListStaleRagBackfills(ctx context.Context, staleBefore time.Time, limit int) ([]*models.DocumentMeta, error) {
+	var docs []*models.DocumentMeta
+	err := r.DB.WithContext(ctx).
+		Where("rag_backfill_status = ? AND (rag_backfill_at IS NULL OR rag_backfill_at < ?)", models.RagBackfillRunning, staleBefore).
+		Limit(limit).
+		Find(&docs).Error
+	return docs, err
+}
+
+func (r *documentRepository) SwitchChunkSet(ctx context.Context, fileID string, gen *models.ChunkGeneration, sections []string, embeddingModel string) error {
+	res := r.DB.WithContext(ctx).
+		Model(&models.DocumentMeta{}).
+		Where("file_id = ? AND status IN ?", fileID, models.CompletedStatuses).
+		Updates(map[string]interface{}{
+			"chunk_source_id":  gen.ID,
+			"total_pages":      gen.TotalPages,
+			"estimated_chunks": gen.EstimatedChunks,
+			"chunks_received":  gen.ChunksReceived,
+			"chunks_stored":    gen.ChunksStored,
+			"chunks_failed":    0,
+			"embedding_model":  embeddingModel,
+			"sections":         pq.Array(sections),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("document %s is not completed", fileID)
+	}
+	return nil
+}
+
+func (r *documentRepository) LinkChunkSet(ctx context.Context, fileID string, source *models.DocumentMeta) error {
+	return r.DB.WithContext(ctx).
+		Model(&models.DocumentMeta{}).
+		Where("file_id = ?", fileID).
+		Updates(map[string]interface{}{
+			"chunk_source_id":  source.ChunkFileID(),
+			"total_pages":      source.TotalPages,
+			"estimated_chunks": source.EstimatedChunks,
+			"chunks_received":  source.ChunksReceived,
+			"chunks_stored":    source.ChunksStored,
+			"embedding_model":  source.EmbeddingModel,
+			"rag_mode":         source.RagMode,
+			"sections":         pq.Array(source.Sections),
+		}).Error
+}
+
+func (r *documentRepository) CountChunkSetRefs(ctx context.Context, chunkSetID string, excludeFileID string) (int64, error) {
+	var n int64
+	err := r.DB.WithContext(ctx).
+		Unscoped().
+		Model(&models.DocumentMeta{}).
+		Where(usesChunkSet, chunkSetID, chunkSetID).
+		Where("file_id <> ?", excludeFileID).
+		Count(&n).Error
+	return n, err
 }
 
 func (r *documentRepository) UpdateRoot(ctx context.Context, fileID string, rootID string) error {
 	return r.DB.WithContext(ctx).Model(&models.DocumentMeta{}).Where("file_id = ?", fileID).Update("root", rootID).Error
 }
+func (r *documentRepository) UpdateNeighborWindow(ctx context.Context, fileID string, window int32) error {
+	return r.DB.WithContext(ctx).Model(&models.DocumentMeta{}).Where("file_id = ?", fileID).Update("neighbor_window", window).Error
+}
+func (r *documentRepository) UpdateEmbeddingModel(ctx context.Context, chunkSetID string, model string) error {
+	return r.DB.WithContext(ctx).Model(&models.DocumentMeta{}).Where(usesChunkSet, chunkSetID, chunkSetID).Update("embedding_model", model).Error
+}
+func (r *documentRepository) ListStaleEmbeddings(ctx context.Context, model string, limit int) ([]*models.DocumentMeta, error) {
+	var docs []*models.DocumentMeta
+	err := r.DB.WithContext(ctx).
+		Where("status IN ? AND (embedding_model IS NULL OR embedding_model <> ?)", models.CompletedStatuses, model).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&docs).Error
+	return docs, err
+}
 func (r *documentRepository) UpdateMetadata(ctx context.Context, fileID string, doc *models.DocumentMeta) error {
 	return r.DB.WithContext(ctx).
 		Model(&models.DocumentMeta{}).
@@ -75,6 +243,46 @@ func (r *documentRepository) UpdateMetadata(ctx context.Context, fileID string,
 			"total_pages":      doc.TotalPages,
 			"estimated_chunks": doc.EstimatedChunks,
 			"started_at":       doc.StartedAt,
+			"embedding_model":  doc.EmbeddingModel,
 			"sections":         pq.Array(doc.Sections),  // ← 使用 pq.Array 包装
 		}).Error
 }
+func (r *documentRepository) SoftDelete(ctx context.Context, fileID string) error {
+	return r.DB.WithContext(ctx).Where("file_id = ?", fileID).Delete(&models.DocumentMeta{}).Error
+}
+func (r *documentRepository) Restore(ctx context.Context, fileID string) error {
+	return r.DB.WithContext(ctx).Unscoped().Model(&models.DocumentMeta{}).Where("file_id = ?", fileID).Update("deleted_at", nil).Error
+}
+func (r *documentRepository) Purge(ctx context.Context, fileID string) error {
+	return r.DB.WithContext(ctx).Unscoped().Where("file_id = ?", fileID).Delete(&models.DocumentMeta{}).Error
+}
+func (r *documentRepository) ListStaleProcessing(ctx context.Context, createdBefore time.Time, limit int) ([]*models.DocumentMeta, error) {
+	var docs []*models.DocumentMeta
+	err := r.DB.WithContext(ctx).
+		Where("status IN ? AND created_at < ?", models.ActiveStatuses, createdBefore).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&docs).Error
+	return docs, err
+}
+func (r *documentRepository) ListCompletedAfter(ctx context.Context, afterFileID string, limit int) ([]*models.DocumentMeta, error) {
+	var docs []*models.DocumentMeta
+	err := r.DB.WithContext(ctx).
+		Where("status IN ? AND file_id > ?", models.CompletedStatuses, afterFileID).
+		Order("file_id ASC").
+		Limit(limit).
+		Find(&docs).Error
+	return docs, err
+}
+func (r *documentRepository) ExistingFileKeys(ctx context.Context, keys []string) ([]string, error) {
+	var existing []string
+	if len(keys) == 0 {
+		return existing, nil
+	}
+	err := r.DB.WithContext(ctx).
+		Unscoped().
+		Model(&models.DocumentMeta{}).
+		Where("file_key IN ?", keys).
+		Pluck("file_key", &existing).Error
+	return existing, err
+}
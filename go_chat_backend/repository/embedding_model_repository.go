@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"go_chat_backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type embeddingModelRepository struct {
+	DB *gorm.DB
+}
+
+func NewEmbeddingModelRepository(db *gorm.DB) EmbeddingModelRepository {
+	return &embeddingModelRepository{DB: db}
+}
+
+func (r *embeddingModelRepository) Upsert(ctx context.Context, model *models.EmbeddingModel) error {
+	return r.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"dimension"}),
+		}).
+		Create(model).Error
+}
+
+func (r *embeddingModelRepository) GetByID(ctx context.Context, id string) (*models.EmbeddingModel, error) {
+	var model models.EmbeddingModel
+	err := r.DB.WithContext(ctx).Where("id = ?", id).First(&model).Error
+	return &model, err
+}
+
+func (r *embeddingModelRepository) List(ctx context.Context) ([]*models.EmbeddingModel, error) {
+	var res []*models.EmbeddingModel
+	err := r.DB.WithContext(ctx).Order("created_at ASC").Find(&res).Error
+	return res, err
+}
+
+func (r *embeddingModelRepository) SetActive(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.EmbeddingModel{}).Where("id <> ?", id).Update("active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.EmbeddingModel{}).Where("id = ?", id).Update("active", true).Error
+	})
+}
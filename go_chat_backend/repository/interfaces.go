@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"go_chat_backend/models"
+	"time"
 )
 
 type DocumentRepository interface {
@@ -11,33 +12,103 @@ type DocumentRepository interface {
 
 	GetByID(ctx context.Context, fileID string) (*models.DocumentMeta, error)
 	GetByHash(ctx context.Context, fileHash string) (*models.DocumentMeta, error)
+	// FindProcessedByHash 查找内容相同、以相同 RAG 模式处理完成的其他文档
+	FindProcessedByHash(ctx context.Context, fileHash string, ragMode bool, excludeFileID string) (*models.DocumentMeta, error)
+	// List 按条件分页查询，返回当前页和总数（按创建时间倒序）
+	List(ctx context.Context, filter models.DocumentFilter) ([]*models.DocumentMeta, int64, error)
 
 	UpdateStatus(ctx context.Context, fileID string, status string) error
+	// TransitionStatus 仅当当前状态为 from 时更新 fields（包括 status），返回是否更新；合法性由 models.CheckStatusTransition 检查
+	TransitionStatus(ctx context.Context, fileID string, from string, fields map[string]interface{}) (bool, error)
 	UpdateProcessingStats(ctx context.Context, fileID string, received, stored, failed int32) error
-	UpsertByHash(ctx context.Context, doc *models.DocumentMeta) error
+	// UpdateChunksTotal 记录 ingest 流正常结束时的 chunk 总数
+	UpdateChunksTotal(ctx context.Context, fileID string, total int32) error
+	UpdateFileHash(ctx context.Context, fileID string, fileHash string) error
+	UpdateSections(ctx context.Context, fileID string, sections []string) error
+	UpdateRagMode(ctx context.Context, fileID string, ragMode bool) error
+	// ClaimRagBackfill 没有进行中的回填，或进行中的回填心跳早于 staleBefore 时开始回填，返回是否领取
+	ClaimRagBackfill(ctx context.Context, fileID string, now, staleBefore time.Time) (bool, error)
+	// TouchRagBackfill 更新回填心跳；回填已被取消时返回 false
+	TouchRagBackfill(ctx context.Context, fileID string, now time.Time) (bool, error)
+	// FinishRagBackfill 回填未被取消时结束回填并开启 RAG（单条 UPDATE），返回是否开启
+	FinishRagBackfill(ctx context.Context, fileID string) (bool, error)
+	// SetRagBackfillStatus 取消（空）或标记失败
+	SetRagBackfillStatus(ctx context.Context, fileID string, status string) error
+	// ListStaleRagBackfills 返回心跳早于 staleBefore 的进行中回填
+	ListStaleRagBackfills(ctx context.Context, staleBefore time.Time, limit int) ([]*models.DocumentMeta, error)
+	// SwitchChunkSet 把文档切换到重新处理得到的 generation（单条 UPDATE，原子切换）；文档状态不变，
+	// 文档已不是完成状态时不切换并返回错误
+	SwitchChunkSet(ctx context.Context, fileID string, gen *models.ChunkGeneration, sections []string, embeddingModel string) error
+	// LinkChunkSet 让文档共用 source 的 chunks，复制处理结果（状态由调用方转换）
+	LinkChunkSet(ctx context.Context, fileID string, source *models.DocumentMeta) error
+	// CountChunkSetRefs 统计除 excludeFileID 外 ChunkFileID 为 chunkSetID 的文档数（包括回收站中的）
+	CountChunkSetRefs(ctx context.Context, chunkSetID string, excludeFileID string) (int64, error)
 	UpdateRoot(ctx context.Context, fileID string, rootID string) error
 	UpdateMetadata(ctx context.Context, fileID string, doc *models.DocumentMeta) error // ✅ 新增
+	UpdateNeighborWindow(ctx context.Context, fileID string, window int32) error
+	// UpdateEmbeddingModel 更新所有使用该 chunks（ChunkFileID 为 chunkSetID）的文档
+	UpdateEmbeddingModel(ctx context.Context, chunkSetID string, model string) error
+	// ListStaleEmbeddings 返回 chunks 不是由 model 生成的已完成文档
+	ListStaleEmbeddings(ctx context.Context, model string, limit int) ([]*models.DocumentMeta, error)
+	// SoftDelete 移入回收站；Restore 撤销软删除；Purge 永久删除记录（包括已软删除的）
+	SoftDelete(ctx context.Context, fileID string) error
+	Restore(ctx context.Context, fileID string) error
+	Purge(ctx context.Context, fileID string) error
+	// ListStaleProcessing 返回创建时间早于 createdBefore 且仍在 processing / finalizing 的文档（最早的在前）
+	ListStaleProcessing(ctx context.Context, createdBefore time.Time, limit int) ([]*models.DocumentMeta, error)
+	// ListCompletedAfter 按 file_id 顺序返回 file_id 大于 afterFileID 的已完成文档，用于分批遍历
+	ListCompletedAfter(ctx context.Context, afterFileID string, limit int) ([]*models.DocumentMeta, error)
+	// ExistingFileKeys 返回 keys 中有文档记录（包括回收站中的）引用的部分
+	ExistingFileKeys(ctx context.Context, keys []string) ([]string, error)
 	//MarkAsCompleted(ctx context.Context, fileID string) error
 	//MarkAsFailed(ctx context.Context, fileID string) error
 	//
-	//Delete(ctx context.Context, fileID string) error
-	//
 	//CheckDuplicate(ctx context.Context, fileHash, fileID string) (bool, error)
 
 }
 
 type ChunkRepository interface {
-	BatchCreate(ctx context.Context, chunks []*models.Chunk) error
+	// BatchCreate 在一个事务中按 (file_id, chunk_index) 批量插入或覆盖；errs 与 chunks 一一对应，
+	// 全部成功时为 nil；err 不为 nil 时整批视为失败
+	BatchCreate(ctx context.Context, chunks []*models.Chunk) (errs []error, err error)
 
 	Create(ctx context.Context, chunk *models.Chunk) error
+	// Upsert 按 (file_id, chunk_index) 插入或覆盖
+	Upsert(ctx context.Context, chunk *models.Chunk) error
 
 	GetByFileID(ctx context.Context, fileID string) ([]*models.Chunk, error)
+	// GetTextsByFileID 按 chunk_index 顺序返回文件的 chunks，只加载章节和文本（不加载向量）
+	GetTextsByFileID(ctx context.Context, fileID string) ([]*models.Chunk, error)
 	GetByID(ctx context.Context, chunkID string) (*models.Chunk, error)
 
-	SearchSimilar(ctx context.Context, embedding []float32, limit int) ([]*models.Chunk, error)
+	// SearchSimilar 只在 model 生成的向量中搜索；model 为空时不过滤
+	SearchSimilar(ctx context.Context, fileID string, model string, embedding []float32, limit int) ([]*models.ScoredChunk, error)
+	GetByIndexRange(ctx context.Context, fileID string, chapter string, from, to int32) ([]*models.Chunk, error)
 
 	CountByFileID(ctx context.Context, fileID string) (int64, error)
+	// ListChunkIndexes 返回文件已存储的 chunk_index（升序）
+	ListChunkIndexes(ctx context.Context, fileID string) ([]int32, error)
+	// GetStaleEmbeddings 返回文件中不是由 model 生成向量的 chunks
+	GetStaleEmbeddings(ctx context.Context, fileID string, model string, limit int) ([]*models.Chunk, error)
+	UpdateEmbedding(ctx context.Context, chunkID string, model string, embedding []float32) error
+	// GetMissingEmbeddings 返回没有向量（NULL 或全零，chat-only 模式写入）的 chunks
+	GetMissingEmbeddings(ctx context.Context, fileID string, limit int) ([]*models.Chunk, error)
+	CountMissingEmbeddings(ctx context.Context, fileID string) (int64, error)
+	// AssignLegacyModel 为未记录模型且维度相符的旧 chunk 补上模型
+	AssignLegacyModel(ctx context.Context, model string, dimension int) (int64, error)
 	GetNodeBySection(ctx context.Context, section string, fileID string) (*models.Chunk, error)
+	// DeleteByFileID 删除文件的所有 chunks 及其向量
+	DeleteByFileID(ctx context.Context, fileID string) (int64, error)
+	// InvalidateVectors 丢弃 VectorStore 中缓存的文件向量，下次搜索时按 chunks 表重新加载
+	InvalidateVectors(ctx context.Context, fileID string) error
+}
+
+type EmbeddingModelRepository interface {
+	Upsert(ctx context.Context, model *models.EmbeddingModel) error
+	GetByID(ctx context.Context, id string) (*models.EmbeddingModel, error)
+	List(ctx context.Context) ([]*models.EmbeddingModel, error)
+	// SetActive 激活指定模型并停用其他模型
+	SetActive(ctx context.Context, id string) error
 }
 
 type ChatRepository interface {
@@ -45,4 +116,33 @@ type ChatRepository interface {
 	GetChatHistory(ctx context.Context, fileID string, nodeID string) ([]*models.ChatNode, error)
 	GetChatChildren(ctx context.Context, fileID string, nodeID string) ([]*models.ChatNode, error)
 	GetNodeByID(ctx context.Context, nodeID string, fileID string) (*models.ChatNode, error)
+	UpdateNode(ctx context.Context, nodeID string, fileID string, question, answer string) error
+	ListNodeIDs(ctx context.Context, fileID string) ([]string, error)
+	DeleteByFileID(ctx context.Context, fileID string) (int64, error)
+}
+
+type DeletionJobRepository interface {
+	Create(ctx context.Context, job *models.DeletionJob) error
+	GetByDocID(ctx context.Context, docID string) (*models.DeletionJob, error)
+	ListByUser(ctx context.Context, userID string, status string) ([]*models.DeletionJob, error)
+	// ListDue 返回到期且未完成的任务；running 超过 staleAfter 未更新的视为中断，一并返回
+	ListDue(ctx context.Context, now time.Time, staleAfter time.Duration, limit int) ([]*models.DeletionJob, error)
+	// Claim 把到期任务标记为 running，返回是否抢到（避免多个实例重复执行）
+	Claim(ctx context.Context, docID string, now time.Time, staleAfter time.Duration) (bool, error)
+	Update(ctx context.Context, job *models.DeletionJob) error
+	// DeletePending 删除仍在回收站中（尚未开始清理）的任务，返回是否删除
+	DeletePending(ctx context.Context, docID string) (bool, error)
+}
+
+type ChunkGenerationRepository interface {
+	Create(ctx context.Context, gen *models.ChunkGeneration) error
+	GetByID(ctx context.Context, id string) (*models.ChunkGeneration, error)
+	ListByDoc(ctx context.Context, docID string) ([]*models.ChunkGeneration, error)
+	Update(ctx context.Context, gen *models.ChunkGeneration) error
+	// RetireActive 把文档其他 active 的 generation 标记为 retired
+	RetireActive(ctx context.Context, docID string, exceptID string) error
+	// ListReplacedDue 返回被替换的 chunks 已过宽限期、尚未删除的 generation
+	ListReplacedDue(ctx context.Context, now time.Time, limit int) ([]*models.ChunkGeneration, error)
+	// ClearReplaced 被替换的 chunks 已删除
+	ClearReplaced(ctx context.Context, id string) error
 }
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"go_chat_backend/models"
+	"math"
+	"sort"
+	"sync"
+)
+
+// memoryVectorStore 进程内向量存储，暴力计算余弦相似度
+// 适合测试和单机小规模部署；向量在写入时归一化，搜索只需点积
+type memoryVectorStore struct {
+	mu      sync.RWMutex
+	vectors map[string]*memoryVector       // chunk_id -> vector
+	byFile  map[string]map[string]struct{} // file_id -> chunk_ids
+
+	// loader 首次搜索某个文件时加载其向量，为 nil 时只使用 Upsert 写入的数据
+	loader func(ctx context.Context, fileID string) ([]*models.Chunk, error)
+	loaded map[string]bool
+
+	// maxVectors 有 loader 时缓存的向量数上限，超过后按最近使用淘汰整个文件（之后搜索时重新加载）；
+	// 没有 loader 时淘汰的数据无法恢复，不限制。0 表示不限制
+	maxVectors int
+	tick       uint64
+	lastUsed   map[string]uint64 // file_id -> 最近一次写入或搜索
+}
+
+type memoryVector struct {
+	fileID  string
+	model   string
+	chapter string
+	values  []float32 // 已归一化
+}
+
+func NewMemoryVectorStore(loader func(ctx context.Context, fileID string) ([]*models.Chunk, error), maxVectors int) VectorStore {
+	return &memoryVectorStore{
+		vectors:    make(map[string]*memoryVector),
+		byFile:     make(map[string]map[string]struct{}),
+		loader:     loader,
+		loaded:     make(map[string]bool),
+		maxVectors: maxVectors,
+		lastUsed:   make(map[string]uint64),
+	}
+}
+
+func (s *memoryVectorStore) Upsert(ctx context.Context, chunks []*models.Chunk) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range chunks {
+		s.put(c)
+		s.touch(c.FileID)
+	}
+	if len(chunks) > 0 {
+		s.evict(chunks[len(chunks)-1].FileID)
+	}
+	return nil
+}
+
+func (s *memoryVectorStore) touch(fileID string) {
+	s.tick++
+	s.lastUsed[fileID] = s.tick
+}
+
+// evict 超过 maxVectors 时淘汰最久未使用的文件，keep 为正在使用的文件，不淘汰
+func (s *memoryVectorStore) evict(keep string) {
+	if s.loader == nil || s.maxVectors <= 0 {
+		return
+	}
+	for len(s.vectors) > s.maxVectors {
+		oldest, oldestTick := "", uint64(0)
+		for fileID, t := range s.lastUsed {
+			if fileID != keep && (oldest == "" || t < oldestTick) {
+				oldest, oldestTick = fileID, t
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		s.drop(oldest)
+	}
+}
+
+func (s *memoryVectorStore) put(c *models.Chunk) {
+	values := normalize(c.EmbeddingVector.Slice())
+	if values == nil {
+		return
+	}
+	if prev, ok := s.vectors[c.ChunkID]; ok && prev.fileID != c.FileID {
+		delete(s.byFile[prev.fileID], c.ChunkID)
+	}
+	s.vectors[c.ChunkID] = &memoryVector{fileID: c.FileID, model: c.EmbeddingModel, chapter: c.Chapter, values: values}
+	if s.byFile[c.FileID] == nil {
+		s.byFile[c.FileID] = make(map[string]struct{})
+	}
+	s.byFile[c.FileID][c.ChunkID] = struct{}{}
+}
+
+func (s *memoryVectorStore) DeleteByFile(ctx context.Context, fileID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(fileID)
+	return nil
+}
+
+// Invalidate 丢弃文件已加载的向量，下次搜索时通过 loader 重新加载；没有 loader 时数据只来自 Upsert，保持不变
+func (s *memoryVectorStore) Invalidate(ctx context.Context, fileID string) error {
+	if s.loader == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fileID != "" {
+		s.drop(fileID)
+		return nil
+	}
+	s.vectors = make(map[string]*memoryVector)
+	s.byFile = make(map[string]map[string]struct{})
+	s.loaded = make(map[string]bool)
+	s.lastUsed = make(map[string]uint64)
+	return nil
+}
+
+func (s *memoryVectorStore) drop(fileID string) {
+	for id := range s.byFile[fileID] {
+		delete(s.vectors, id)
+	}
+	delete(s.byFile, fileID)
+	delete(s.loaded, fileID)
+	delete(s.lastUsed, fileID)
+}
+
+func (s *memoryVectorStore) Search(ctx context.Context, embedding []float32, filter VectorFilter, limit int) ([]VectorHit, error) {
+	if err := s.ensureLoaded(ctx, filter.FileID); err != nil {
+		return nil, err
+	}
+	query := normalize(embedding)
+	if query == nil {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var hits []VectorHit
+	match := func(id string, v *memoryVector) {
+		if filter.Model != "" && v.model != filter.Model {
+			return
+		}
+		if filter.Chapter != "" && v.chapter != filter.Chapter {
+			return
+		}
+		if len(v.values) != len(query) {
+			return
+		}
+		var dot float64
+		for i := range query {
+			dot += float64(query[i]) * float64(v.values[i])
+		}
+		hits = append(hits, VectorHit{ChunkID: id, Score: dot})
+	}
+	if filter.FileID != "" {
+		for id := range s.byFile[filter.FileID] {
+			match(id, s.vectors[id])
+		}
+	} else {
+		for id, v := range s.vectors {
+			match(id, v)
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ChunkID < hits[j].ChunkID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// ensureLoaded 文件的向量尚未加载时通过 loader 加载
+func (s *memoryVectorStore) ensureLoaded(ctx context.Context, fileID string) error {
+	if s.loader == nil || fileID == "" {
+		return nil
+	}
+	s.mu.Lock()
+	loaded := s.loaded[fileID]
+	if loaded {
+		s.touch(fileID)
+	}
+	s.mu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	chunks, err := s.loader(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range chunks {
+		// 加载期间 Upsert 写入的新向量优先
+		if _, exists := s.vectors[c.ChunkID]; !exists {
+			s.put(c)
+		}
+	}
+	s.loaded[fileID] = true
+	s.touch(fileID)
+	s.evict(fileID)
+	return nil
+}
+
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	res := make([]float32, len(v))
+	for i, x := range v {
+		res[i] = float32(float64(x) / norm)
+	}
+	return res
+}
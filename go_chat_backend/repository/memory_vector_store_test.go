@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"go_chat_backend/models"
+	"testing"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+func testChunk(id, fileID string, values ...float32) *models.Chunk {
+	return &models.Chunk{
+		ChunkID:         id,
+		FileID:          fileID,
+		EmbeddingModel:  "test@1",
+		EmbeddingVector: pgvector.NewVector(values),
+	}
+}
+
+func hitIDs(hits []VectorHit) []string {
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ChunkID
+	}
+	return ids
+}
+
+func TestMemoryVectorStoreWithoutLoader(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryVectorStore(nil, 0)
+	err := store.Upsert(ctx, []*models.Chunk{
+		testChunk("a1", "a", 1, 0),
+		testChunk("a2", "a", 0.6, 0.8),
+		testChunk("a3", "a", 0, 1),
+		testChunk("b1", "b", 1, 0),
+	})
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	hits, err := store.Search(ctx, []float32{1, 0}, VectorFilter{FileID: "a", Model: "test@1"}, 2)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := hitIDs(hits); len(got) != 2 || got[0] != "a1" || got[1] != "a2" {
+		t.Fatalf("Search file a = %v, want [a1 a2]", got)
+	}
+	if hits[0].Score < 0.999 || hits[1].Score < 0.599 || hits[1].Score > 0.601 {
+		t.Fatalf("scores = %v, want cosine similarities 1 and 0.6", hits)
+	}
+
+	hits, err = store.Search(ctx, []float32{1, 0}, VectorFilter{Model: "other@1"}, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Fatalf("Search with another model = %v, want no hits", hitIDs(hits))
+	}
+
+	if err := store.DeleteByFile(ctx, "a"); err != nil {
+		t.Fatalf("DeleteByFile: %v", err)
+	}
+	hits, err = store.Search(ctx, []float32{1, 0}, VectorFilter{}, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := hitIDs(hits); len(got) != 1 || got[0] != "b1" {
+		t.Fatalf("Search after DeleteByFile = %v, want [b1]", got)
+	}
+
+	// 没有 loader 时 Invalidate 不丢弃数据
+	if err := store.Invalidate(ctx, ""); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	hits, _ = store.Search(ctx, []float32{1, 0}, VectorFilter{FileID: "b"}, 10)
+	if len(hits) != 1 {
+		t.Fatalf("Search after Invalidate = %v, want [b1]", hitIDs(hits))
+	}
+}
+
+func TestMemoryVectorStoreEvictsLeastRecentlyUsedFile(t *testing.T) {
+	ctx := context.Background()
+	loads := map[string]int{}
+	loader := func(ctx context.Context, fileID string) ([]*models.Chunk, error) {
+		loads[fileID]++
+		return []*models.Chunk{
+			testChunk(fileID+"1", fileID, 1, 0),
+			testChunk(fileID+"2", fileID, 0, 1),
+		}, nil
+	}
+	store := NewMemoryVectorStore(loader, 4).(*memoryVectorStore)
+
+	for _, fileID := range []string{"a", "b", "a", "c"} {
+		if _, err := store.Search(ctx, []float32{1, 0}, VectorFilter{FileID: fileID}, 1); err != nil {
+			t.Fatalf("Search %s: %v", fileID, err)
+		}
+	}
+	if len(store.vectors) > 4 {
+		t.Fatalf("cached %d vectors, want at most 4", len(store.vectors))
+	}
+	if store.loaded["b"] || !store.loaded["a"] || !store.loaded["c"] {
+		t.Fatalf("loaded = %v, want b evicted", store.loaded)
+	}
+
+	hits, err := store.Search(ctx, []float32{1, 0}, VectorFilter{FileID: "b"}, 1)
+	if err != nil {
+		t.Fatalf("Search b: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ChunkID != "b1" || loads["b"] != 2 {
+		t.Fatalf("Search evicted file = %v (loads %d), want it reloaded", hitIDs(hits), loads["b"])
+	}
+}
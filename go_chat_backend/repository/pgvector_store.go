@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go_chat_backend/models"
+	"sort"
+
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/gorm"
+)
+
+// pgVectorStore 向量直接存在 chunks.embedding_vector 列中
+type pgVectorStore struct {
+	DB     *gorm.DB
+	search PgVectorSearch
+}
+
+func NewPgVectorStore(db *gorm.DB, search PgVectorSearch) VectorStore {
+	return &pgVectorStore{DB: db, search: search}
+}
+
+// Upsert 更新已存在 chunk 行的向量列
+func (s *pgVectorStore) Upsert(ctx context.Context, chunks []*models.Chunk) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, c := range chunks {
+			err := tx.Model(&models.Chunk{}).
+				Where("chunk_id = ?", c.ChunkID).
+				Updates(map[string]interface{}{
+					"embedding_vector": c.EmbeddingVector,
+					"embedding_model":  c.EmbeddingModel,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteByFile 向量就在 chunk 行中，随 chunk 一起删除，这里无需额外操作
+func (s *pgVectorStore) DeleteByFile(ctx context.Context, fileID string) error {
+	return nil
+}
+
+// Invalidate 直接查询 chunks 表，没有缓存
+func (s *pgVectorStore) Invalidate(ctx context.Context, fileID string) error {
+	return nil
+}
+
+type vectorHitRow struct {
+	ChunkID  string  `gorm:"column:chunk_id"`
+	Distance float64 `gorm:"column:distance"`
+}
+
+func (s *pgVectorStore) Search(ctx context.Context, embedding []float32, filter VectorFilter, limit int) ([]VectorHit, error) {
+	var rows []*vectorHitRow
+
+	// 将 []float32 转换为 pgvector.Vector
+	queryVector := pgvector.NewVector(embedding)
+
+	// 使用余弦相似度进行向量搜索
+	// <=> 是 pgvector 的余弦距离操作符（值越小越相似）
+	// 也可以使用：
+	// <-> L2 距离（欧几里得距离）
+	// <#> 负内积（最大内积搜索）
+	// 指定模型时使用与该模型部分索引相同的表达式（按维度转换）才能走 ANN 索引，由查询计划按代价选择
+	// ANN 索引或 file_id 索引加精确排序。按文件检索只在索引支持迭代扫描时使用该表达式：否则索引只返回
+	// 全局最近的 ef_search 行，再按 file_id 过滤后往往所剩无几，此时通过 file_id 索引取出后精确排序
+	column := "embedding_vector"
+	ann := filter.Model != "" && (filter.FileID == "" || s.search.Filtered)
+	if ann {
+		column = fmt.Sprintf("embedding_vector::vector(%d)", len(embedding))
+	}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, setting := range s.search.Settings {
+			if !ann {
+				break
+			}
+			if err := tx.Exec(setting).Error; err != nil {
+				return err
+			}
+		}
+		query := tx.
+			Model(&models.Chunk{}).
+			Select("chunk_id, "+column+" <=> ? AS distance", queryVector).
+			Where("embedding_vector IS NOT NULL")
+		if filter.FileID != "" {
+			query = query.Where("file_id = ?", filter.FileID)
+		}
+		// 不同模型的向量不可比较（维度也可能不同）
+		if filter.Model != "" {
+			query = query.Where("embedding_model = ?", filter.Model)
+		}
+		if filter.Chapter != "" {
+			query = query.Where("chapter = ?", filter.Chapter)
+		}
+		return query.
+			Order(gorm.Expr(column+" <=> ?", queryVector)).
+			Limit(limit).
+			Find(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 迭代扫描（relaxed_order）返回的结果可能略微乱序
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Distance < rows[j].Distance })
+	res := make([]VectorHit, len(rows))
+	for i, row := range rows {
+		// 余弦相似度 = 1 - 余弦距离
+		res[i] = VectorHit{ChunkID: row.ChunkID, Score: 1 - row.Distance}
+	}
+	return res, nil
+}
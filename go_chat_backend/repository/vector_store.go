@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"go_chat_backend/models"
+
+	"gorm.io/gorm"
+)
+
+// VectorFilter 向量搜索过滤条件，空字段表示不过滤
+type VectorFilter struct {
+	FileID  string
+	Model   string // embedding 模型（name@version），不同模型的向量不可比较
+	Chapter string
+}
+
+// VectorHit 向量搜索命中，Score 为余弦相似度
+type VectorHit struct {
+	ChunkID string
+	Score   float64
+}
+
+// VectorStore 存储 chunk 向量并做相似度搜索
+// chunk 的文本和元数据始终保存在 Postgres，VectorStore 只负责向量
+type VectorStore interface {
+	// Upsert 写入或替换 chunks 的向量（按 ChunkID）
+	Upsert(ctx context.Context, chunks []*models.Chunk) error
+	DeleteByFile(ctx context.Context, fileID string) error
+	// Invalidate 丢弃缓存的文件向量，下次搜索时从 chunks 表重新加载；fileID 为空时丢弃全部
+	Invalidate(ctx context.Context, fileID string) error
+	Search(ctx context.Context, embedding []float32, filter VectorFilter, limit int) ([]VectorHit, error)
+}
+
+// PgVectorSearch pgvector 的查询参数
+type PgVectorSearch struct {
+	// Settings 每次 ANN 查询前在事务内执行的 SET LOCAL 语句（如 hnsw.ef_search）
+	Settings []string
+	// Filtered 索引支持迭代扫描，按文件的检索也可以走 ANN 索引；否则按文件的检索精确排序
+	Filtered bool
+}
+
+// NewVectorStore 根据配置创建 VectorStore："memory" 为进程内暴力搜索，其他值为 pgvector。
+// memory 只替代相似度搜索，chunks 和向量仍然保存在 Postgres；缓存只在本进程写入时失效，只适合单实例部署，
+// 最多缓存 memoryMaxVectors 个向量（按文件最近使用淘汰）
+func NewVectorStore(kind string, db *gorm.DB, search PgVectorSearch, memoryMaxVectors int) VectorStore {
+	switch kind {
+	case "memory":
+		// 向量仍然写入 chunks 表；首次搜索某个文件时从数据库加载，文档完成处理或切换 generation 时重新加载
+		return NewMemoryVectorStore(func(ctx context.Context, fileID string) ([]*models.Chunk, error) {
+			var chunks []*models.Chunk
+			err := db.WithContext(ctx).
+				Select("chunk_id, file_id, chapter, embedding_model, embedding_vector").
+				Where("file_id = ? AND embedding_vector IS NOT NULL", fileID).
+				Find(&chunks).Error
+			return chunks, err
+		}, memoryMaxVectors)
+	default:
+		return NewPgVectorStore(db, search)
+	}
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go_chat_backend/handlers"
+	"go_chat_backend/platform/storage"
+)
+
+// RegisterBlobRoutes 本地存储的签名上传/下载链接
+func RegisterBlobRoutes(app *fiber.App, handler *handlers.BlobHandler) {
+	app.Post(storage.LocalUploadPath, handler.Upload)
+	app.Get(storage.LocalDownloadPath, handler.Download)
+}
@@ -8,4 +8,5 @@ import (
 func RegisterChatRoutes(app *fiber.App, chatHandler *handlers.ChatHandler) {
 	chats := app.Group("api/chat")
 	chats.Post("/:doc_id/questions", chatHandler.AskQuestions)
+	chats.Post("/:doc_id/retrieve", chatHandler.DryRunRetrieval)
 }
@@ -7,7 +7,18 @@ import (
 
 func RegisterDocumentRoutes(app *fiber.App, handler *handlers.DocHandler) {
 	document := app.Group("api/pdf")
+	document.Get("/", handler.ListDocuments)
+	document.Get("/trash", handler.ListTrash)
+	document.Get("/:doc_id", handler.GetDocument)
+	document.Delete("/:doc_id", handler.DeleteDocument)
+	document.Post("/:doc_id/restore", handler.RestoreDocument)
+	document.Post("/:doc_id/reprocess", handler.ReprocessDocument)
+	document.Post("/:doc_id/summary", handler.RetrySummary)
 	document.Post("/upload", handler.RequestUpload)
+	document.Post("/direct", handler.DirectUpload)
 	document.Post("/:doc_id/confirm", handler.ConfirmUpload)
 	document.Get("/:doc_id/toc", handler.GetToc)
+	document.Get("/:doc_id/download", handler.DownloadDocument)
+	document.Patch("/:doc_id/retrieval", handler.UpdateRetrievalSettings)
+	document.Patch("/:doc_id/rag-mode", handler.UpdateRagMode)
 }
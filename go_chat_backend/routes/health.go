@@ -1,9 +1,18 @@
 package routes
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/expvar"
+)
 
 func RegisterHealthRoutes(app *fiber.App) {
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "ok"})
 	})
 }
+
+// RegisterMetricsRoutes 运行时指标（expvar，/debug/vars），例如 ingest_live_contexts。
+// 输出包含 memstats 和进程命令行，只挂在 METRICS_ADDR 的内部监听上，不挂在公开的 API 上
+func RegisterMetricsRoutes(app *fiber.App) {
+	app.Use(expvar.New())
+}
@@ -85,6 +85,7 @@ func (s *ChatService) GetChatTree(ctx context.Context, fileID string) (*models.C
 }
 
 func (s *ChatService) AskQuestion(ctx context.Context, fileID string, req models.ChatReq) (*models.ChatRes, error) {
+	start := time.Now()
 	var ChatHistory []*models.ChatNode
 	var err error
 
@@ -114,12 +115,14 @@ func (s *ChatService) AskQuestion(ctx context.Context, fileID string, req models
 		ragMode = false
 	}
 
-	prompt := s.llmService.BuildPrompt(ChatHistory, req.Question, req.Section, req.FileID, llmConfig.Provider, llmConfig.Model, ragMode)
+	prompt, debug := s.llmService.BuildPrompt(ctx, ChatHistory, req.Question, req.Section, fileID, llmConfig, ragMode)
+	llmStart := time.Now()
 	answer, err := s.llmService.CallLLM(prompt, llmConfig.Provider, llmConfig.Model, llmConfig.APIKey)
 	if err != nil {
 		logging.Logger.Error("fail AskQuestion", "error", err)
 		return nil, err
 	}
+	debug.LLMMs = time.Since(llmStart).Milliseconds()
 	ID := uuid.New().String()
 	newNode := &models.ChatNode{
 		ID:        ID,
@@ -141,16 +144,47 @@ func (s *ChatService) AskQuestion(ctx context.Context, fileID string, req models
 	}()
 
 	tree, err := s.GetChatTree(ctx, fileID)
-	return &models.ChatRes{
+	res := &models.ChatRes{
 		ID:       ID,
 		Answer:   answer,
 		Question: req.Question,
 		Tree:     tree,
-	}, err
+	}
+	if req.Debug {
+		debug.TotalMs = time.Since(start).Milliseconds()
+		res.Debug = debug
+	}
+	return res, err
+}
+
+// DryRunRetrieval 执行与 AskQuestion 相同的检索和 prompt 组装，但不调用 LLM、不保存节点
+func (s *ChatService) DryRunRetrieval(ctx context.Context, fileID string, req models.ChatReq) (*models.RetrievalDebug, error) {
+	start := time.Now()
+	history, err := s.GetHistoryByID(ctx, req.ParentID, fileID)
+	if err != nil {
+		logging.Logger.Error("fail DryRunRetrieval", "error", err)
+		return nil, err
+	}
+
+	// 查询改写和 LLM 重排序需要 LLM 配置，没有时这些步骤会退回默认行为
+	llmConfig, err := s.llmConfigService.GetOrUseDefault(ctx, req.UserID, req.APIKey, req.Model, req.Provider)
+	if err != nil {
+		logging.Logger.Warn("no LLM config for dry run", "error", err, "userID", req.UserID)
+		llmConfig = nil
+	}
+	ragMode, err := s.ragService.GetRagMode(ctx, fileID)
+	if err != nil {
+		logging.Logger.Error("fail to get RAG mode", "error", err, "fileID", fileID)
+		ragMode = false
+	}
+
+	_, debug := s.llmService.BuildPrompt(ctx, history, req.Question, req.Section, fileID, llmConfig, ragMode)
+	debug.TotalMs = time.Since(start).Milliseconds()
+	return debug, nil
 }
 
 func (s *ChatService) GetHistoryByID(ctx context.Context, ParentID string, fileID string) ([]*models.ChatNode, error) {
-	cacheKey := fmt.Sprintf("chat_node:%s:%s", fileID, ParentID)
+	cacheKey := historyCacheKey(fileID, ParentID)
 	var ChatHistory []*models.ChatNode
 	var err error
 	if ParentID == "" {
@@ -180,3 +214,8 @@ func (s *ChatService) GetHistoryByID(ctx context.Context, ParentID string, fileI
 
 	return ChatHistory, nil
 }
+
+// historyCacheKey 从根节点到 nodeID 的对话历史缓存 key
+func historyCacheKey(fileID, nodeID string) string {
+	return fmt.Sprintf("chat_node:%s:%s", fileID, nodeID)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/proto/cognicore"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ChunkBatchWriter 缓冲一个 ingest 流的 chunks，攒够 batchSize 个或第一个缓冲的 chunk 等待超过
+// flushInterval 时批量写入（一个事务）；成功和失败的计数由 writer 记录，流结束前需要调用 Flush
+type ChunkBatchWriter struct {
+	cs            *ChunkService
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	pending []*models.Chunk
+	timer   *time.Timer
+	stored  int32
+	failed  []int32 // 写入失败的 chunk_index
+}
+
+// NewBatchWriter 为一个 ingest 流创建 writer
+func (cs *ChunkService) NewBatchWriter() *ChunkBatchWriter {
+	return &ChunkBatchWriter{
+		cs:            cs,
+		batchSize:     max(cs.batchSize, 1),
+		flushInterval: cs.flushInterval,
+	}
+}
+
+// Add 校验 chunk、更新处理上下文并放入缓冲区，缓冲区满时立即写入；
+// 返回的错误只表示 chunk 本身不合法（已计为失败），写入错误由 writer 记录
+func (w *ChunkBatchWriter) Add(chunk *cognicore.TextChunk) error {
+	chunkRes, err := w.cs.buildChunk(chunk)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.failed = append(w.failed, chunk.ChunkIndex)
+		return err
+	}
+	w.cs.appendToContext(w.cs.getOrCreateContext(chunk.FileId), chunkRes)
+	w.pending = append(w.pending, chunkRes)
+
+	if len(w.pending) >= w.batchSize {
+		w.flushLocked()
+	} else if w.timer == nil && w.flushInterval > 0 {
+		// ETL 发送变慢时已收到的 chunks 也不会一直停留在内存中
+		w.timer = time.AfterFunc(w.flushInterval, w.Flush)
+	}
+	return nil
+}
+
+// Flush 写入缓冲区中剩余的 chunks
+func (w *ChunkBatchWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLocked()
+}
+
+// Stats 已写入和写入失败的 chunk 数（不包括还在缓冲区中的）
+func (w *ChunkBatchWriter) Stats() (stored, failed int32) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stored, int32(len(w.failed))
+}
+
+// FailedIndexes 写入失败的 chunk_index（升序）
+func (w *ChunkBatchWriter) FailedIndexes() []int32 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	indexes := slices.Clone(w.failed)
+	slices.Sort(indexes)
+	return indexes
+}
+
+func (w *ChunkBatchWriter) flushLocked() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if len(w.pending) == 0 {
+		return
+	}
+	batch := w.pending
+	w.pending = nil
+
+	// 与流的上下文无关：流断开前收到的 chunks 仍然写入，续传时不需要重新发送
+	errs, err := w.cs.chunkRepo.BatchCreate(context.Background(), batch)
+	if err != nil {
+		logging.Logger.Error("fail BatchCreate", "error", err, "file_id", batch[0].FileID, "chunks", len(batch))
+		for _, c := range batch {
+			w.failed = append(w.failed, c.ChunkIndex)
+		}
+		return
+	}
+	for i, c := range batch {
+		if errs != nil && errs[i] != nil {
+			logging.Logger.Error("fail to store chunk", "error", errs[i], "file_id", c.FileID, "chunk_index", c.ChunkIndex)
+			w.failed = append(w.failed, c.ChunkIndex)
+			continue
+		}
+		w.stored++
+	}
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/cache"
+	"go_chat_backend/platform/storage"
+	"go_chat_backend/repository"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// deletionStaleAfter running 状态超过该时间未更新的任务视为实例中断，可被重新领取
+const deletionStaleAfter = 10 * time.Minute
+
+// ErrRestoreExpired 文档已开始清理或超过保留期，无法恢复
+var ErrRestoreExpired = errors.New("document can no longer be restored")
+
+// DeletionService 文档删除：先移入回收站，保留期过后由后台任务级联清理
+// chunks、ChatNode、对象存储文件、缓存和文档记录；每一步完成后持久化进度，失败后从该步骤继续
+type DeletionService struct {
+	docRepo        repository.DocumentRepository
+	chunkRepo      repository.ChunkRepository
+	chatRepo       repository.ChatRepository
+	jobRepo        repository.DeletionJobRepository
+	genRepo        repository.ChunkGenerationRepository
+	storageService *storage.Service
+	cacheService   cache.CacheService
+	ragService     *RagModeService
+	retention      time.Duration
+	interval       time.Duration
+}
+
+func NewDeletionService(
+	docRepo repository.DocumentRepository,
+	chunkRepo repository.ChunkRepository,
+	chatRepo repository.ChatRepository,
+	jobRepo repository.DeletionJobRepository,
+	genRepo repository.ChunkGenerationRepository,
+	storageService *storage.Service,
+	cacheService cache.CacheService,
+	ragService *RagModeService,
+	retention time.Duration,
+	interval time.Duration,
+) *DeletionService {
+	return &DeletionService{
+		docRepo:        docRepo,
+		chunkRepo:      chunkRepo,
+		chatRepo:       chatRepo,
+		jobRepo:        jobRepo,
+		genRepo:        genRepo,
+		storageService: storageService,
+		cacheService:   cacheService,
+		ragService:     ragService,
+		retention:      retention,
+		interval:       interval,
+	}
+}
+
+// DeleteDocument 把文档移入回收站并登记删除任务
+// permanent 或保留期为 0 时立即开始清理；只能删除 userID 自己的文档
+func (s *DeletionService) DeleteDocument(ctx context.Context, docID, userID string, permanent bool) (*models.DeletionJob, error) {
+	doc, err := loadOwnedDocument(ctx, s.docRepo, docID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &models.DeletionJob{
+		DocID:      doc.FileID,
+		UserID:     doc.UserID,
+		Filename:   doc.Filename,
+		FileKey:    doc.FileKey,
+		ChunkSetID: doc.ChunkFileID(),
+		Step:       models.DeletionSteps[0],
+		Status:     models.DeletionPending,
+		PurgeAfter: now.Add(s.retention),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if permanent || s.retention <= 0 {
+		job.PurgeAfter = now
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		logging.Logger.Error("fail to create deletion job", "error", err, "docID", docID)
+		return nil, err
+	}
+	if err := s.docRepo.SoftDelete(ctx, docID); err != nil {
+		logging.Logger.Error("fail SoftDelete", "error", err, "docID", docID)
+		return nil, err
+	}
+	if err := s.ragService.InvalidateCache(docID); err != nil {
+		logging.Logger.Error("fail to invalidate rag mode cache", "error", err, "docID", docID)
+	}
+
+	if !job.PurgeAfter.After(now) {
+		go func() {
+			if err := s.Purge(context.Background(), job.DocID); err != nil {
+				logging.Logger.Error("fail Purge", "error", err, "docID", job.DocID)
+			}
+		}()
+	}
+	return job, nil
+}
+
+// RestoreDocument 从回收站恢复 userID 自己的文档，只能在保留期内且清理尚未开始时恢复
+func (s *DeletionService) RestoreDocument(ctx context.Context, docID, userID string) error {
+	if userID == "" {
+		return ErrUserRequired
+	}
+	job, err := s.jobRepo.GetByDocID(ctx, docID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrDocumentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if job.UserID != userID {
+		return ErrDocumentNotFound
+	}
+	if !time.Now().Before(job.PurgeAfter) {
+		return ErrRestoreExpired
+	}
+	// 条件删除与 Claim 互斥：任务一旦被领取就无法再删除
+	ok, err := s.jobRepo.DeletePending(ctx, docID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRestoreExpired
+	}
+	if err := s.docRepo.Restore(ctx, docID); err != nil {
+		logging.Logger.Error("fail Restore", "error", err, "docID", docID)
+		return err
+	}
+	return nil
+}
+
+// ListTrash 用户回收站中可恢复的文档
+func (s *DeletionService) ListTrash(ctx context.Context, userID string) ([]*models.DeletionJob, error) {
+	return s.jobRepo.ListByUser(ctx, userID, models.DeletionPending)
+}
+
+// Run 每个 interval 处理一次到期任务，直到 ctx 结束
+func (s *DeletionService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			logging.Logger.Error("fail deletion jobs", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 处理一批到期任务和到期的被替换 chunks，单个任务失败不影响其他任务
+func (s *DeletionService) RunOnce(ctx context.Context) error {
+	jobs, err := s.jobRepo.ListDue(ctx, time.Now(), deletionStaleAfter, 20)
+	if err != nil {
+		return fmt.Errorf("failed to list deletion jobs: %w", err)
+	}
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.Purge(ctx, job.DocID); err != nil {
+			logging.Logger.Error("fail Purge", "error", err, "docID", job.DocID)
+		}
+	}
+	return s.purgeReplacedChunks(ctx)
+}
+
+// purgeReplacedChunks 删除重新处理时被替换、已过宽限期的旧 chunks；仍被其他文档共用时只清除记录
+func (s *DeletionService) purgeReplacedChunks(ctx context.Context) error {
+	gens, err := s.genRepo.ListReplacedDue(ctx, time.Now(), 20)
+	if err != nil {
+		return fmt.Errorf("failed to list replaced chunks: %w", err)
+	}
+	for _, gen := range gens {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		refs, err := s.docRepo.CountChunkSetRefs(ctx, gen.ReplacedChunkSet, "")
+		if err != nil {
+			logging.Logger.Error("fail CountChunkSetRefs", "error", err, "chunkSet", gen.ReplacedChunkSet)
+			continue
+		}
+		if refs == 0 {
+			if _, err := s.chunkRepo.DeleteByFileID(ctx, gen.ReplacedChunkSet); err != nil {
+				logging.Logger.Error("fail to delete replaced chunks", "error", err, "chunkSet", gen.ReplacedChunkSet)
+				continue
+			}
+		}
+		if err := s.genRepo.ClearReplaced(ctx, gen.ID); err != nil {
+			logging.Logger.Error("fail ClearReplaced", "error", err, "generation", gen.ID)
+			continue
+		}
+		logging.Logger.Info("replaced chunks purged", "docID", gen.DocID, "chunkSet", gen.ReplacedChunkSet, "refs", refs)
+	}
+	return nil
+}
+
+// Purge 领取任务并从记录的步骤继续执行剩余步骤
+func (s *DeletionService) Purge(ctx context.Context, docID string) error {
+	ok, err := s.jobRepo.Claim(ctx, docID, time.Now(), deletionStaleAfter)
+	if err != nil || !ok {
+		return err
+	}
+	job, err := s.jobRepo.GetByDocID(ctx, docID)
+	if err != nil {
+		return err
+	}
+
+	start := slices.Index(models.DeletionSteps, job.Step)
+	if start < 0 {
+		start = 0
+	}
+	for _, step := range models.DeletionSteps[start:] {
+		job.Step = step
+		if err := s.runStep(ctx, job, step); err != nil {
+			job.Status = models.DeletionFailed
+			job.Attempts++
+			job.LastError = fmt.Sprintf("%s: %v", step, err)
+			job.UpdatedAt = time.Now()
+			if uerr := s.jobRepo.Update(ctx, job); uerr != nil {
+				logging.Logger.Error("fail to update deletion job", "error", uerr, "docID", docID)
+			}
+			return fmt.Errorf("deletion step %s failed: %w", step, err)
+		}
+		job.UpdatedAt = time.Now()
+		if next := slices.Index(models.DeletionSteps, step) + 1; next < len(models.DeletionSteps) {
+			job.Step = models.DeletionSteps[next]
+			if err := s.jobRepo.Update(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+
+	job.Status = models.DeletionDone
+	job.LastError = ""
+	job.UpdatedAt = time.Now()
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		return err
+	}
+	logging.Logger.Info("document purged", "docID", docID, "attempts", job.Attempts+1)
+	return nil
+}
+
+// runStep 每一步都是幂等的，重复执行不会出错
+func (s *DeletionService) runStep(ctx context.Context, job *models.DeletionJob, step string) error {
+	switch step {
+	case models.DeleteStepCache:
+		return s.purgeCache(ctx, job.DocID)
+	case models.DeleteStepChatNodes:
+		_, err := s.chatRepo.DeleteByFileID(ctx, job.DocID)
+		return err
+	case models.DeleteStepChunks:
+		return s.purgeChunks(ctx, job)
+	case models.DeleteStepObject:
+		if job.FileKey == "" {
+			return nil
+		}
+		return s.storageService.DeleteFile(job.FileKey)
+	case models.DeleteStepMeta:
+		return s.docRepo.Purge(ctx, job.DocID)
+	default:
+		return fmt.Errorf("unknown deletion step %q", step)
+	}
+}
+
+// purgeCache 清除 RAG 模式、目录（key 为文档 ID）和各节点的对话历史缓存
+func (s *DeletionService) purgeCache(ctx context.Context, docID string) error {
+	if err := s.ragService.InvalidateCache(docID); err != nil {
+		return err
+	}
+	if err := s.cacheService.DelCache(docID); err != nil {
+		return err
+	}
+	nodeIDs, err := s.chatRepo.ListNodeIDs(ctx, docID)
+	if err != nil {
+		return err
+	}
+	for _, id := range nodeIDs {
+		if err := s.cacheService.DelCache(historyCacheKey(docID, id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeChunks chunks 可能被内容相同的其他文档共用，最后一个引用者被清理时才删除；
+// 未完成的重新处理写入的 chunks 直接删除
+func (s *DeletionService) purgeChunks(ctx context.Context, job *models.DeletionJob) error {
+	gens, err := s.genRepo.ListByDoc(ctx, job.DocID)
+	if err != nil {
+		return err
+	}
+	for _, gen := range gens {
+		if gen.Status == models.GenerationProcessing || gen.Status == models.GenerationFailed {
+			if _, err := s.chunkRepo.DeleteByFileID(ctx, gen.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	chunkSet := job.ChunkSetID
+	if chunkSet == "" {
+		chunkSet = job.DocID
+	}
+	refs, err := s.docRepo.CountChunkSetRefs(ctx, chunkSet, job.DocID)
+	if err != nil {
+		return err
+	}
+	if refs > 0 {
+		logging.Logger.Info("chunks still shared, keeping them", "docID", job.DocID, "chunkSet", chunkSet, "refs", refs)
+		return nil
+	}
+	_, err = s.chunkRepo.DeleteByFileID(ctx, chunkSet)
+	return err
+}
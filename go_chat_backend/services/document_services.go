@@ -1,18 +1,43 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"go_chat_backend/models"
 	"go_chat_backend/pkg/logging"
 	"go_chat_backend/platform/cache"
+	"go_chat_backend/platform/events"
 	"go_chat_backend/platform/storage"
 	"go_chat_backend/repository"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ErrDocumentNotFound 文档不存在或不属于当前用户
+var ErrDocumentNotFound = errors.New("document not found")
+
+// ErrSummaryNotMissing 只有 completed_without_summary 的文档需要补上摘要
+var ErrSummaryNotMissing = errors.New("document is not missing a summary")
+
+// ErrUserRequired 按文档操作的接口必须提供 user_id，不允许跳过归属校验
+var ErrUserRequired = errors.New("user_id is required")
+
+// ErrInvalidRetrievalSettings 检索配置超出允许范围
+var ErrInvalidRetrievalSettings = errors.New("invalid retrieval settings")
+
+// ErrInvalidListQuery 文档列表的查询参数不合法
+var ErrInvalidListQuery = errors.New("invalid list query")
+
 type DocumentService struct {
 	chatRepo            repository.ChatRepository
 	docRepo             repository.DocumentRepository
@@ -22,6 +47,8 @@ type DocumentService struct {
 	llmService          LLMService
 	llmConfigService    *LLMConfigService
 	ragService          *RagModeService
+	eventPublisher      *events.EventPublisher
+	maxFileSize         int64
 }
 
 func NewDocumentService(
@@ -31,7 +58,9 @@ func NewDocumentService(
 	storageService *storage.Service,
 	cacheService cache.CacheService,
 	llmConfigService *LLMConfigService,
-	ragService *RagModeService) *DocumentService {
+	ragService *RagModeService,
+	eventPublisher *events.EventPublisher,
+	maxFileSize int64) *DocumentService {
 	return &DocumentService{
 		docRepo:             docRepo,
 		chatRepo:            chatRepo,
@@ -40,34 +69,31 @@ func NewDocumentService(
 		cacheService:        cacheService,
 		llmConfigService:    llmConfigService,
 		ragService:          ragService,
+		eventPublisher:      eventPublisher,
+		maxFileSize:         maxFileSize,
 	}
 }
 
 func (s *DocumentService) RequestUpload(ctx context.Context, req models.UploadReq) (*models.UploadResp, error) {
 	docID := uuid.New().String()
-	if req.FileSize > 50*1024*1024 {
-		logging.Logger.Error("file too large: max 50MB")
-		return nil, fmt.Errorf("file too large: max 50MB")
+	if req.FileSize > s.maxFileSize {
+		logging.Logger.Error("file too large", "size", req.FileSize, "max", s.maxFileSize)
+		return nil, fmt.Errorf("%w: max %d bytes", ErrFileTooLarge, s.maxFileSize)
 	}
 	if req.ContentType != "application/pdf" {
 		logging.Logger.Error("unsupported file type: only pdf")
 		return nil, fmt.Errorf("unsupported file type: only pdf")
 	}
-	// ✅ 修复：使用固定的最大值（50MB），而不是实际文件大小
+	// ✅ 修复：使用固定的最大值（MaxFileSize），而不是实际文件大小
 	// 这样可以避免因为 HTTP 头等额外数据导致超过限制
 	res, err := s.storageService.GeneratePresignedPostUpload(
-		req.FileName, 50*1024*1024, docID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate presigned URL: %v", err)
-	}
-	presignedUrl, err := s.storageService.GeneratePresignedGetDownload(res.FileKey, res.Expires)
+		req.FileName, s.maxFileSize, docID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate presigned URL: %v", err)
 	}
 	docBaseInfo := models.DocumentMeta{
 		FileID:          docID,
 		UserID:          req.UserID,
-		URL:             presignedUrl,
 		Filename:        req.FileName,
 		TotalPages:      0,
 		EstimatedChunks: 0,
@@ -99,33 +125,41 @@ func (s *DocumentService) ConfirmUpload(ctx context.Context, req models.ConfirmU
 			logging.Logger.Error("fail to set RAG mode", "error", err, "docID", req.DocId)
 		}
 	}()
+	if req.NeighborWindow != 0 {
+		if err := s.setNeighborWindow(ctx, req.DocId, req.NeighborWindow); err != nil {
+			logging.Logger.Error("fail UpdateRetrievalSettings", "error", err, "docID", req.DocId)
+			return nil, err
+		}
+	}
 	info, err := s.docRepo.GetByID(ctx, req.DocId)
 	if err != nil {
 		logging.Logger.Error("fail GetBaseInfo", err)
 		return nil, err
 	}
-	ok, err := s.storageService.FileExists(info.FileKey)
-	if err != nil {
-		logging.Logger.Error("fail FileExists", "error", err)
-		return nil, err
-	} else if !ok {
-		logging.Logger.Error("file does not exist in storage")
-		return nil, fmt.Errorf("file does not exist in storage")
-	}
 	if info.Status != models.StatusProcessing {
 		logging.Logger.Error("fail ConfirmUpload, docID already exists")
 		return nil, fmt.Errorf("fail ConfirmUpload, docID already exists")
 	}
-	etlTask := models.EtlTask{
-		DocID:     info.FileID,
-		FileName:  info.Filename,
-		URL:       info.URL,
-		UserID:    info.UserID,
-		CreatedAt: time.Now(),
-		RagMode:   req.RagMode,
+	hash, err := s.validateUpload(ctx, info)
+	if err != nil {
+		// 对象还没上传时允许客户端重试确认，其余校验失败直接拒绝
+		if code := UploadErrorCode(err); code != "" && !errors.Is(err, ErrUploadMissing) {
+			s.rejectUpload(ctx, info, err)
+		}
+		logging.Logger.Error("fail validateUpload", "error", err, "docID", info.FileID)
+		return nil, err
+	}
+	if err := s.docRepo.UpdateFileHash(ctx, info.FileID, hash); err != nil {
+		logging.Logger.Error("fail UpdateFileHash", "error", err, "docID", info.FileID)
+		return nil, err
 	}
-	if err = s.messageQueueService.PushToQueue("upload_tasks", etlTask); err != nil {
-		logging.Logger.Error("fail PushToQueue", err)
+	info.FileHash = hash
+	if res, ok, err := s.reuseProcessed(ctx, info, reqMode); err != nil {
+		return nil, err
+	} else if ok {
+		return res, nil
+	}
+	if err := s.queueEtl(info, req.RagMode); err != nil {
 		return nil, err
 	}
 
@@ -135,26 +169,218 @@ func (s *DocumentService) ConfirmUpload(ctx context.Context, req models.ConfirmU
 		Status:  "queued",
 	}, nil
 }
+
+// DirectUpload 一次请求完成上传：边写入存储边计算 SHA-256，创建文档记录并排队 ETL 任务
+// size 为 multipart 中文件的大小
+func (s *DocumentService) DirectUpload(ctx context.Context, req models.DirectUploadReq, filename string, size int64, file io.Reader) (*models.ConfirmUploadResp, error) {
+	if size <= 0 {
+		return nil, ErrEmptyFile
+	}
+	if size > s.maxFileSize {
+		return nil, fmt.Errorf("%w: max %d bytes", ErrFileTooLarge, s.maxFileSize)
+	}
+	head := make([]byte, len(pdfMagic))
+	if _, err := io.ReadFull(file, head); err != nil || !bytes.Equal(head, pdfMagic) {
+		return nil, ErrNotPDF
+	}
+
+	docID := uuid.New().String()
+	fileKey := s.storageService.FileKeyGenerator.GenerateFileKey(filename, "")
+	inspector := newPDFInspector()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), file), inspector)
+	if err := s.storageService.PutFile(ctx, fileKey, body, size, "application/pdf"); err != nil {
+		logging.Logger.Error("fail PutFile", "error", err, "docID", docID)
+		return nil, err
+	}
+	if err := inspector.check(); err != nil {
+		if derr := s.storageService.DeleteFile(fileKey); derr != nil {
+			logging.Logger.Error("fail DeleteFile", "error", derr, "fileKey", fileKey)
+		}
+		return nil, err
+	}
+
+	ragMode := req.RagMode == "true"
+	info := &models.DocumentMeta{
+		FileID:         docID,
+		UserID:         req.UserID,
+		Filename:       filename,
+		FileHash:       inspector.sum(),
+		FileSize:       size,
+		CreatedAt:      time.Now(),
+		FileKey:        fileKey,
+		RagMode:        ragMode,
+		NeighborWindow: clampNeighborWindow(req.NeighborWindow),
+		Status:         models.StatusProcessing,
+	}
+	if err := s.docRepo.Create(ctx, info); err != nil {
+		logging.Logger.Error("failed to create document", "error", err, "docID", docID)
+		if derr := s.storageService.DeleteFile(fileKey); derr != nil {
+			logging.Logger.Error("fail DeleteFile", "error", derr, "fileKey", fileKey)
+		}
+		return nil, fmt.Errorf("failed to create document: %v", err)
+	}
+
+	if res, ok, err := s.reuseProcessed(ctx, info, ragMode); err != nil {
+		return nil, err
+	} else if ok {
+		return res, nil
+	}
+	if err := s.queueEtl(info, req.RagMode); err != nil {
+		return nil, err
+	}
+	return &models.ConfirmUploadResp{
+		Message: "Upload accepted",
+		DocId:   docID,
+		Status:  "queued",
+	}, nil
+}
+
+// queueEtl 推送 ETL 任务；下载链接由 worker 取出任务时通过 GetTaskURL 获取
+func (s *DocumentService) queueEtl(info *models.DocumentMeta, ragMode string) error {
+	etlTask := models.EtlTask{
+		DocID:     info.FileID,
+		FileName:  info.Filename,
+		UserID:    info.UserID,
+		CreatedAt: time.Now(),
+		RagMode:   ragMode,
+	}
+	if err := s.messageQueueService.PushToQueue("upload_tasks", etlTask); err != nil {
+		logging.Logger.Error("fail PushToQueue", "error", err)
+		return err
+	}
+	return nil
+}
 func (s *DocumentService) GenerateDocumentSummary(docID, userID string, chunkService *ChunkService) (string, error) {
-	// 从 ChunkService 的上下文获取 FullText
-	chunkService.mu.RLock()
-	docCtx, exists := chunkService.docContexts[docID]
-	chunkService.mu.RUnlock()
+	return s.generateRoot(docID, docID, userID, chunkService)
+}
 
-	if !exists {
-		logging.Logger.Error("fail GenerateDocumentSummary, document context not found")
-		return "", fmt.Errorf("fail GenerateDocumentSummary, document context not found")
+// generateRoot 生成摘要并作为文档的根节点
+func (s *DocumentService) generateRoot(docID, contextID, userID string, chunkService *ChunkService) (string, error) {
+	msg, summary, err := s.summarize(docID, contextID, userID, chunkService)
+	if err != nil {
+		return "", err
 	}
 
-	docCtx.mu.Lock()
-	fullText := docCtx.FullText.String()
-	docCtx.mu.Unlock()
+	rootID := uuid.New().String()
+	node := &models.ChatNode{
+		ID:        rootID,
+		ParentID:  "",
+		FileID:    docID,
+		Question:  msg,
+		Answer:    summary,
+		CreatedAt: time.Now(),
+	}
+	err = s.chatRepo.Create(context.Background(), node)
+	if err != nil {
+		logging.Logger.Error("fail GenerateDocumentSummary", err)
+		return "", err
+	}
+	if err = s.docRepo.UpdateRoot(context.Background(), docID, rootID); err != nil {
+		logging.Logger.Error("fail GenerateDocumentSummary", err)
+		return "", err
+	}
+	logging.Logger.Info(
+		"GenerateDocumentSummary",
+		"docID", docID,
+		"summary", summary,
+		"rootID", rootID,
+	)
+	return summary, nil
+}
+
+// RetrySummary 为摘要生成失败（completed_without_summary）的文档重新生成摘要，成功后转换到 completed 并发布完成事件
+func (s *DocumentService) RetrySummary(ctx context.Context, docID, userID string, chunkService *ChunkService) (string, error) {
+	doc, err := loadOwnedDocument(ctx, s.docRepo, docID, userID)
+	if err != nil {
+		return "", err
+	}
+	if doc.Status != models.StatusCompletedWithoutSummary {
+		return "", ErrSummaryNotMissing
+	}
+	summary, err := s.RegenerateDocumentSummary(docID, doc.ChunkFileID(), doc.UserID, chunkService)
+	if err != nil {
+		return "", err
+	}
+	if err := s.summaryRestored(ctx, doc, summary); err != nil {
+		return "", err
+	}
+	return summary, nil
+}
+
+// SummaryRestored 重新处理时补上了摘要：completed_without_summary 的文档转换到 completed，其他状态不变
+func (s *DocumentService) SummaryRestored(ctx context.Context, docID string) error {
+	doc, err := s.docRepo.GetByID(ctx, docID)
+	if err != nil {
+		return err
+	}
+	if doc.Status != models.StatusCompletedWithoutSummary {
+		return nil
+	}
+	return transitionStatus(ctx, s.docRepo, doc, models.StatusCompleted, "")
+}
+
+func (s *DocumentService) summaryRestored(ctx context.Context, doc *models.DocumentMeta, summary string) error {
+	if err := transitionStatus(ctx, s.docRepo, doc, models.StatusCompleted, ""); err != nil {
+		logging.Logger.Error("fail TransitionStatus", "error", err, "docID", doc.FileID)
+		return err
+	}
+	err := s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
+		Type:     models.EventDocumentCompleted,
+		DocID:    doc.FileID,
+		UserID:   doc.UserID,
+		Status:   models.StatusCompleted,
+		Message:  "Document summary generated",
+		Summary:  summary,
+		Sections: doc.Sections,
+	})
+	if err != nil {
+		logging.Logger.Error("fail PublishDocumentEvent", "error", err, "docID", doc.FileID)
+	}
+	return nil
+}
+
+// RegenerateDocumentSummary 用重新处理得到的全文（contextID 的 chunks）重新生成摘要
+// 已有根节点时原地更新，保留挂在根节点下的对话
+func (s *DocumentService) RegenerateDocumentSummary(docID, contextID, userID string, chunkService *ChunkService) (string, error) {
+	ctx := context.Background()
+	doc, err := s.docRepo.GetByID(ctx, docID)
+	if err != nil {
+		return "", err
+	}
+	if doc.Root == "" {
+		return s.generateRoot(docID, contextID, userID, chunkService)
+	}
+	msg, summary, err := s.summarize(docID, contextID, userID, chunkService)
+	if err != nil {
+		return "", err
+	}
+	if err := s.chatRepo.UpdateNode(ctx, doc.Root, docID, msg, summary); err != nil {
+		logging.Logger.Error("fail RegenerateDocumentSummary", "error", err, "docID", docID)
+		return "", err
+	}
+	if err := s.cacheService.DelCache(historyCacheKey(docID, doc.Root)); err != nil {
+		logging.Logger.Error("fail to invalidate history cache", "error", err, "docID", docID)
+	}
+	return summary, nil
+}
+
+// summarize 用 contextID（文档或 generation）已存储的 chunks 拼成的全文调用用户配置的 LLM 生成摘要，返回 prompt 和摘要
+func (s *DocumentService) summarize(docID, contextID, userID string, chunkService *ChunkService) (string, string, error) {
+	doc, err := s.docRepo.GetByID(context.Background(), docID)
+	if err != nil {
+		return "", "", err
+	}
+	fullText, err := chunkService.SummaryInput(context.Background(), contextID, doc.Filename)
+	if err != nil {
+		logging.Logger.Error("fail GenerateDocumentSummary, cannot load stored chunks", "error", err, "docID", docID)
+		return "", "", fmt.Errorf("fail GenerateDocumentSummary, cannot load stored chunks: %w", err)
+	}
 
 	// 获取用户的 LLM 配置
 	llmConfig, err := s.llmConfigService.GetUserLLMConfig(context.Background(), userID)
 	if err != nil {
 		logging.Logger.Error("fail to get LLM config for summary", "error", err, "userID", userID)
-		return "", fmt.Errorf("LLM configuration required for generating summary: %w", err)
+		return "", "", fmt.Errorf("LLM configuration required for generating summary: %w", err)
 	}
 
 	logging.Logger.Info("GenerateDocumentSummary with LLM config",
@@ -181,37 +407,12 @@ func (s *DocumentService) GenerateDocumentSummary(docID, userID string, chunkSer
 	summary, err := s.llmService.CallLLM(prompt, llmConfig.Provider, llmConfig.Model, llmConfig.APIKey)
 	if err != nil {
 		logging.Logger.Error("fail GenerateDocumentSummary", "error", err)
-		return "", err
+		return "", "", err
 	}
 	if len(summary) > 3000 {
 		summary = summary[:3000]
 	}
-
-	rootID := uuid.New().String()
-	node := &models.ChatNode{
-		ID:        rootID,
-		ParentID:  "",
-		FileID:    docID,
-		Question:  msg,
-		Answer:    summary,
-		CreatedAt: time.Now(),
-	}
-	err = s.chatRepo.Create(context.Background(), node)
-	if err != nil {
-		logging.Logger.Error("fail GenerateDocumentSummary", err)
-		return "", err
-	}
-	if err = s.docRepo.UpdateRoot(context.Background(), docID, rootID); err != nil {
-		logging.Logger.Error("fail GenerateDocumentSummary", err)
-		return "", err
-	}
-	logging.Logger.Info(
-		"GenerateDocumentSummary",
-		"docID", docID,
-		"summary", summary,
-		"rootID", rootID,
-	)
-	return summary, nil
+	return msg, summary, nil
 }
 
 func (s *DocumentService) GetSections(ctx context.Context, docID string) ([]string, error) {
@@ -234,11 +435,8 @@ func (s *DocumentService) GetDocumentByID(ctx context.Context, docID string) (*m
 }
 
 func (s *DocumentService) UpdateSections(ctx context.Context, docID string, sections []string) error {
-	// 更新数据库
-	doc := &models.DocumentMeta{
-		Sections: sections,
-	}
-	if err := s.docRepo.UpdateMetadata(ctx, docID, doc); err != nil {
+	// 更新数据库（只更新 sections，不能覆盖其他元数据）
+	if err := s.docRepo.UpdateSections(ctx, docID, sections); err != nil {
 		logging.Logger.Error("fail UpdateSections", "error", err)
 		return err
 	}
@@ -250,3 +448,257 @@ func (s *DocumentService) UpdateSections(ctx context.Context, docID string, sect
 
 	return nil
 }
+
+// UpdateRetrievalSettings 更新 userID 的文档的检索配置
+func (s *DocumentService) UpdateRetrievalSettings(ctx context.Context, docID, userID string, req models.RetrievalSettingsReq) error {
+	if _, err := loadOwnedDocument(ctx, s.docRepo, docID, userID); err != nil {
+		return err
+	}
+	return s.setNeighborWindow(ctx, docID, req.NeighborWindow)
+}
+
+func (s *DocumentService) setNeighborWindow(ctx context.Context, docID string, window int32) error {
+	if window < 0 || window > maxNeighborWindow {
+		return fmt.Errorf("%w: neighbor_window must be between 0 and %d", ErrInvalidRetrievalSettings, maxNeighborWindow)
+	}
+	return s.docRepo.UpdateNeighborWindow(ctx, docID, window)
+}
+
+// loadOwnedDocument 加载属于 userID 的文档；userID 为空时返回 ErrUserRequired，
+// 文档不存在和属于其他用户都返回 ErrDocumentNotFound，不暴露文档是否存在
+func loadOwnedDocument(ctx context.Context, repo repository.DocumentRepository, docID, userID string) (*models.DocumentMeta, error) {
+	if userID == "" {
+		return nil, ErrUserRequired
+	}
+	doc, err := repo.GetByID(ctx, docID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if doc.UserID != userID {
+		return nil, ErrDocumentNotFound
+	}
+	return doc, nil
+}
+
+// TransitionStatus 按状态机转换文档状态；状态机不允许或状态已被并发修改时返回 models.ErrInvalidStatusTransition
+func (s *DocumentService) TransitionStatus(ctx context.Context, docID, to, reason string) error {
+	doc, err := s.docRepo.GetByID(ctx, docID)
+	if err != nil {
+		return err
+	}
+	return transitionStatus(ctx, s.docRepo, doc, to, reason)
+}
+
+// RecordProgress 持久化 ingest 流的 chunk 计数
+func (s *DocumentService) RecordProgress(ctx context.Context, docID string, received, stored, failed int32) error {
+	return s.docRepo.UpdateProcessingStats(ctx, docID, received, stored, failed)
+}
+
+// RecordChunksTotal 记录 ingest 流正常结束时的 chunk 总数，之后的单个 chunk 重试按它判断是否已补齐
+func (s *DocumentService) RecordChunksTotal(ctx context.Context, docID string, total int32) error {
+	return s.docRepo.UpdateChunksTotal(ctx, docID, total)
+}
+
+// transitionStatus 把 doc 从当前状态转换到 to，成功后更新 doc.Status
+func transitionStatus(ctx context.Context, repo repository.DocumentRepository, doc *models.DocumentMeta, to, reason string) error {
+	if err := models.CheckStatusTransition(doc.Status, to); err != nil {
+		return err
+	}
+	ok, err := repo.TransitionStatus(ctx, doc.FileID, doc.Status, models.StatusTransitionFields(to, time.Now(), reason))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: status of %s is no longer %s", models.ErrInvalidStatusTransition, doc.FileID, doc.Status)
+	}
+	doc.Status = to
+	return nil
+}
+
+// ListDocuments 分页列出用户的文档
+func (s *DocumentService) ListDocuments(ctx context.Context, req models.DocumentListReq) (*models.DocumentListResp, error) {
+	if req.UserID == "" {
+		return nil, ErrUserRequired
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = defaultPageSize
+	}
+	if req.PageSize > maxPageSize {
+		req.PageSize = maxPageSize
+	}
+	filter := models.DocumentFilter{
+		UserID:   req.UserID,
+		Status:   req.Status,
+		Filename: req.Filename,
+		Offset:   (req.Page - 1) * req.PageSize,
+		Limit:    req.PageSize,
+	}
+	var err error
+	if filter.CreatedAfter, err = parseDateParam(req.CreatedAfter); err != nil {
+		return nil, fmt.Errorf("%w: invalid created_after: %v", ErrInvalidListQuery, err)
+	}
+	if filter.CreatedBefore, err = parseDateParam(req.CreatedBefore); err != nil {
+		return nil, fmt.Errorf("%w: invalid created_before: %v", ErrInvalidListQuery, err)
+	}
+
+	docs, total, err := s.docRepo.List(ctx, filter)
+	if err != nil {
+		logging.Logger.Error("fail ListDocuments", "error", err, "userID", req.UserID)
+		return nil, err
+	}
+	return &models.DocumentListResp{
+		Documents: docs,
+		Total:     total,
+		Page:      req.Page,
+		PageSize:  req.PageSize,
+	}, nil
+}
+
+// GetDocumentDetail 返回文档元数据、根节点摘要和新的下载链接，只能查看 userID 自己的文档
+func (s *DocumentService) GetDocumentDetail(ctx context.Context, docID, userID string) (*models.DocumentDetailResp, error) {
+	doc, err := loadOwnedDocument(ctx, s.docRepo, docID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &models.DocumentDetailResp{DocumentMeta: doc}
+	if doc.Root != "" {
+		root, err := s.chatRepo.GetNodeByID(ctx, doc.Root, docID)
+		if err != nil {
+			logging.Logger.Error("fail to get root summary", "error", err, "docID", docID)
+		} else {
+			res.Summary = root.Answer
+		}
+	}
+
+	res.DownloadURL, res.URLExpires, err = s.storageService.DownloadURL(doc.FileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate download URL: %w", err)
+	}
+	return res, nil
+}
+
+// parseDateParam 解析 RFC3339 或 2006-01-02 格式的日期，空字符串返回 nil
+func parseDateParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("expected RFC3339 or YYYY-MM-DD, got %q", value)
+}
+
+// reuseProcessed 已有内容相同且处理完成的文档时共用其 chunks，跳过 ETL
+// 对话树不共用：复制源文档的摘要作为新文档自己的根节点。
+// 没有可复用的文档时返回 false；已共用 chunks 但无法转换到完成状态（文档已被删除或状态已被修改）时返回错误
+func (s *DocumentService) reuseProcessed(ctx context.Context, info *models.DocumentMeta, ragMode bool) (*models.ConfirmUploadResp, bool, error) {
+	// FileHash 在上传校验时已经算好
+	source, err := s.docRepo.FindProcessedByHash(ctx, info.FileHash, ragMode, info.FileID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logging.Logger.Error("fail FindProcessedByHash", "error", err, "docID", info.FileID)
+		}
+		return nil, false, nil
+	}
+	if err := s.docRepo.LinkChunkSet(ctx, info.FileID, source); err != nil {
+		logging.Logger.Error("fail LinkChunkSet", "error", err, "docID", info.FileID)
+		return nil, false, nil
+	}
+
+	summary, status := "", models.StatusCompletedWithoutSummary
+	if source.Root != "" {
+		if summary, err = s.copyRoot(ctx, info.FileID, source); err != nil {
+			logging.Logger.Error("fail to copy root summary", "error", err, "docID", info.FileID, "sourceID", source.FileID)
+		} else {
+			status = models.StatusCompleted
+		}
+	}
+	if err := transitionStatus(ctx, s.docRepo, info, status, ""); err != nil {
+		logging.Logger.Error("fail TransitionStatus", "error", err, "docID", info.FileID)
+		return nil, true, fmt.Errorf("failed to complete reused document: %w", err)
+	}
+	if err := s.cacheService.SetCache(info.FileID, []string(source.Sections), 24*time.Hour); err != nil {
+		logging.Logger.Error("fail to set section cache", "error", err)
+	}
+	logging.Logger.Info("reusing processed document", "docID", info.FileID, "sourceID", source.FileID, "chunkSet", source.ChunkFileID())
+
+	err = s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
+		Type:     models.EventDocumentCompleted,
+		DocID:    info.FileID,
+		UserID:   info.UserID,
+		Status:   status,
+		Message:  "Document already processed, reusing existing chunks",
+		Summary:  summary,
+		Sections: source.Sections,
+		Progress: &models.ProgressInfo{
+			ChunksReceived: source.ChunksReceived,
+			ChunksStored:   source.ChunksStored,
+			TotalChunks:    source.EstimatedChunks,
+			Percentage:     100,
+		},
+	})
+	if err != nil {
+		logging.Logger.Error("fail PublishDocumentEvent", "error", err, "docID", info.FileID)
+	}
+	return &models.ConfirmUploadResp{
+		Message: "Document already processed, reusing existing chunks",
+		DocId:   info.FileID,
+		Status:  status,
+	}, true, nil
+}
+
+// copyRoot 为文档创建源文档根节点的副本，返回摘要
+func (s *DocumentService) copyRoot(ctx context.Context, docID string, source *models.DocumentMeta) (string, error) {
+	root, err := s.chatRepo.GetNodeByID(ctx, source.Root, source.FileID)
+	if err != nil {
+		return "", err
+	}
+	node := &models.ChatNode{
+		ID:        uuid.New().String(),
+		FileID:    docID,
+		Question:  root.Question,
+		Answer:    root.Answer,
+		CreatedAt: time.Now(),
+	}
+	if err := s.chatRepo.Create(ctx, node); err != nil {
+		return "", err
+	}
+	if err := s.docRepo.UpdateRoot(ctx, docID, node.ID); err != nil {
+		return "", err
+	}
+	return root.Answer, nil
+}
+
+// TaskURL 为 ETL 任务生成文档原文件的下载链接，文档不存在时返回 ErrDocumentNotFound
+func (s *DocumentService) TaskURL(ctx context.Context, docID string) (string, time.Time, error) {
+	doc, err := s.docRepo.GetByID(ctx, docID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", time.Time{}, ErrDocumentNotFound
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return s.storageService.TaskURL(doc.FileKey)
+}
+
+// GetDownloadURL 为 userID 自己的文档原文件生成新的下载链接
+func (s *DocumentService) GetDownloadURL(ctx context.Context, docID, userID string) (*models.DownloadResp, error) {
+	doc, err := loadOwnedDocument(ctx, s.docRepo, docID, userID)
+	if err != nil {
+		return nil, err
+	}
+	url, expires, err := s.storageService.DownloadURL(doc.FileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate download URL: %w", err)
+	}
+	return &models.DownloadResp{DocID: docID, URL: url, ExpiresAt: expires}, nil
+}
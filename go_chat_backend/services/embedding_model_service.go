@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/repository"
+)
+
+// EmbeddingModelRegistry 登记 embedding 模型并记录当前激活的模型
+// 写入的 chunk 必须与激活模型维度一致，查询只与同一模型的向量比较
+type EmbeddingModelRegistry struct {
+	repo      repository.EmbeddingModelRepository
+	chunkRepo repository.ChunkRepository
+	active    *models.EmbeddingModel
+}
+
+func NewEmbeddingModelRegistry(repo repository.EmbeddingModelRepository, chunkRepo repository.ChunkRepository) *EmbeddingModelRegistry {
+	return &EmbeddingModelRegistry{repo: repo, chunkRepo: chunkRepo}
+}
+
+// Activate 登记配置中的模型并设为激活模型
+// 未记录模型的旧 chunk 如果维度相符，视为由该模型生成；维度不符的留给重新向量化任务处理
+func (r *EmbeddingModelRegistry) Activate(ctx context.Context, name, version string, dimension int) error {
+	if name == "" || dimension <= 0 {
+		return fmt.Errorf("invalid embedding model %q with dimension %d", name, dimension)
+	}
+	model := &models.EmbeddingModel{
+		ID:        models.EmbeddingModelID(name, version),
+		Name:      name,
+		Version:   version,
+		Dimension: dimension,
+	}
+	if err := r.repo.Upsert(ctx, model); err != nil {
+		return fmt.Errorf("failed to register embedding model: %w", err)
+	}
+	if err := r.repo.SetActive(ctx, model.ID); err != nil {
+		return fmt.Errorf("failed to activate embedding model: %w", err)
+	}
+	model.Active = true
+	r.active = model
+
+	n, err := r.chunkRepo.AssignLegacyModel(ctx, model.ID, dimension)
+	if err != nil {
+		return fmt.Errorf("failed to assign legacy chunks: %w", err)
+	}
+	logging.Logger.Info("embedding model active", "model", model.ID, "dimension", dimension, "legacy_chunks", n)
+	return nil
+}
+
+// Active 当前激活的模型
+func (r *EmbeddingModelRegistry) Active() *models.EmbeddingModel {
+	return r.active
+}
+
+// ActiveID 当前激活模型的 ID，未激活时为空
+func (r *EmbeddingModelRegistry) ActiveID() string {
+	if r.active == nil {
+		return ""
+	}
+	return r.active.ID
+}
+
+// Validate 检查向量维度是否与激活模型一致
+func (r *EmbeddingModelRegistry) Validate(embedding []float32) error {
+	if r.active == nil {
+		return fmt.Errorf("no active embedding model")
+	}
+	if len(embedding) != r.active.Dimension {
+		return fmt.Errorf("embedding dimension %d does not match model %s (%d)", len(embedding), r.active.ID, r.active.Dimension)
+	}
+	return nil
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"expvar"
+	"fmt"
 	"go_chat_backend/models"
 	"go_chat_backend/pkg/logging"
-	"go_chat_backend/platform/database"
 	"go_chat_backend/platform/proto/cognicore"
 	"go_chat_backend/repository"
 	"strings"
@@ -15,28 +18,45 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+// 存活的处理上下文数量和因超时被清理的数量，通过 /debug/vars 查看
+var (
+	liveContexts    = expvar.NewInt("ingest_live_contexts")
+	expiredContexts = expvar.NewInt("ingest_expired_contexts")
+)
+
 type ChunkService struct {
 	chunkRepo    repository.ChunkRepository
 	metadataRepo repository.DocumentRepository
+	registry     *EmbeddingModelRegistry
 
-	// 为每个文档维护独立的处理上下文
+	// ingest 流批量写入：每批 chunk 数和缓冲的最长等待时间
+	batchSize     int
+	flushInterval time.Duration
+
+	// 为每个文档维护独立的处理上下文，超过 contextTTL 没有更新的由 RunContextExpiry 清理
 	docContexts map[string]*DocumentProcessContext
+	contextTTL  time.Duration
 	mu          sync.RWMutex
 }
 
-// DocumentProcessContext 每个文档的处理上下文
+// DocumentProcessContext 每个文档的处理上下文；只保存 sections，
+// 生成摘要用的全文从已存储的 chunks 读取，不在内存中累积
 type DocumentProcessContext struct {
 	FileID   string
-	FullText *strings.Builder
 	Sections []string
+	lastSeen time.Time
 	mu       sync.Mutex
 }
 
-func NewChunkService(db *database.DB) *ChunkService {
+func NewChunkService(chunkRepo repository.ChunkRepository, metadataRepo repository.DocumentRepository, registry *EmbeddingModelRegistry, batchSize int, flushInterval, contextTTL time.Duration) *ChunkService {
 	return &ChunkService{
-		chunkRepo:    repository.NewChunkRepository(db.GetDatabase()),
-		metadataRepo: repository.NewDocumentRepository(db.GetDatabase()),
-		docContexts:  make(map[string]*DocumentProcessContext),
+		chunkRepo:     chunkRepo,
+		metadataRepo:  metadataRepo,
+		registry:      registry,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		docContexts:   make(map[string]*DocumentProcessContext),
+		contextTTL:    contextTTL,
 	}
 }
 
@@ -48,11 +68,18 @@ func (cs *ChunkService) getOrCreateContext(fileID string) *DocumentProcessContex
 	if ctx, exists := cs.docContexts[fileID]; exists {
 		return ctx
 	}
+	return cs.putContextLocked(fileID)
+}
 
+// putContextLocked 创建新的空上下文，替换已有的；调用方持有 cs.mu
+func (cs *ChunkService) putContextLocked(fileID string) *DocumentProcessContext {
 	ctx := &DocumentProcessContext{
 		FileID:   fileID,
-		FullText: &strings.Builder{},
 		Sections: []string{},
+		lastSeen: time.Now(),
+	}
+	if _, exists := cs.docContexts[fileID]; !exists {
+		liveContexts.Add(1)
 	}
 	cs.docContexts[fileID] = ctx
 	return ctx
@@ -62,7 +89,54 @@ func (cs *ChunkService) getOrCreateContext(fileID string) *DocumentProcessContex
 func (cs *ChunkService) CleanupContext(fileID string) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
-	delete(cs.docContexts, fileID)
+	if _, exists := cs.docContexts[fileID]; exists {
+		delete(cs.docContexts, fileID)
+		liveContexts.Add(-1)
+	}
+}
+
+// HasContext 文档的处理上下文是否还在（可能已超时清理，或流在其他实例上）
+func (cs *ChunkService) HasContext(fileID string) bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	_, exists := cs.docContexts[fileID]
+	return exists
+}
+
+// RunContextExpiry 定期清理超过 contextTTL 没有更新的上下文，直到 ctx 结束；
+// 兜底流异常中断等没有走到清理的情况
+func (cs *ChunkService) RunContextExpiry(ctx context.Context) {
+	ticker := time.NewTicker(max(cs.contextTTL/2, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := cs.ExpireContexts(now); n > 0 {
+				logging.Logger.Warn("expired idle ingest contexts", "count", n)
+			}
+		}
+	}
+}
+
+// ExpireContexts 清理 lastSeen 早于 now - contextTTL 的上下文，返回清理的数量
+func (cs *ChunkService) ExpireContexts(now time.Time) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	expired := 0
+	for fileID, docCtx := range cs.docContexts {
+		docCtx.mu.Lock()
+		idle := now.Sub(docCtx.lastSeen) > cs.contextTTL
+		docCtx.mu.Unlock()
+		if idle {
+			delete(cs.docContexts, fileID)
+			expired++
+		}
+	}
+	liveContexts.Add(int64(-expired))
+	expiredContexts.Add(int64(expired))
+	return expired
 }
 
 // GetSections 获取文档的 sections
@@ -85,11 +159,7 @@ func (cs *ChunkService) ProcessDocumentMetadata(metadata *cognicore.DocumentMeta
 	now := time.Now()
 
 	// 为这个文档创建处理上下文
-	docCtx := cs.getOrCreateContext(metadata.FileId)
-	docCtx.mu.Lock()
-	docCtx.FullText.WriteString(metadata.Filename)
-	docCtx.FullText.WriteString("\n")
-	docCtx.mu.Unlock()
+	cs.beginContext(metadata)
 
 	doc := &models.DocumentMeta{
 		FileID:          metadata.FileId,
@@ -101,48 +171,134 @@ func (cs *ChunkService) ProcessDocumentMetadata(metadata *cognicore.DocumentMeta
 		EstimatedChunks: metadata.EstimatedChunks,
 		Status:          models.StatusProcessing,
 		StartedAt:       &now,
+		EmbeddingModel:  cs.registry.ActiveID(),
 		Sections:        pq.StringArray{}, // 初始化为空数组
 	}
 
 	return cs.metadataRepo.UpdateMetadata(ctx, metadata.FileId, doc)
 }
-func (cs *ChunkService) ProcessChunk(chunk *cognicore.TextChunk) error {
-	ctx := context.Background()
 
-	//  DEBUG: Check embedding vector length
-	if len(chunk.EmbeddingVector) == 0 {
-		logging.Logger.Error("chunk %d: embedding vector is EMPTY (length=0)", chunk.ChunkIndex)
+// ProcessGenerationMetadata 重新处理时 metadata.FileId 是 generation ID，只创建处理上下文，
+// 文档元数据在切换 generation 时更新
+func (cs *ChunkService) ProcessGenerationMetadata(metadata *cognicore.DocumentMetadata) {
+	cs.beginContext(metadata)
+}
+
+// beginContext 新的流从空上下文开始，丢弃之前中断的流留下的上下文
+func (cs *ChunkService) beginContext(metadata *cognicore.DocumentMetadata) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.putContextLocked(metadata.FileId)
+}
+
+// IngestSingleChunk 单个 chunk 重试：按 (file_id, chunk_index) 幂等写入，不更新处理上下文
+// （收尾时由 RebuildContext 从已存储的 chunks 重建）
+func (cs *ChunkService) IngestSingleChunk(ctx context.Context, chunk *cognicore.TextChunk) error {
+	chunkRes, err := cs.buildChunk(chunk)
+	if err != nil {
+		return err
+	}
+	return cs.chunkRepo.Upsert(ctx, chunkRes)
+}
+
+// InvalidateVectors 文档完成处理时丢弃处理期间缓存的向量（只有 memory VectorStore 有缓存），
+// 处理期间搜索加载的部分 chunks 不会一直留在缓存中
+func (cs *ChunkService) InvalidateVectors(ctx context.Context, fileID string) error {
+	return cs.chunkRepo.InvalidateVectors(ctx, fileID)
+}
+
+// CountStored 文件已存储的 chunk 数
+func (cs *ChunkService) CountStored(ctx context.Context, fileID string) (int32, error) {
+	n, err := cs.chunkRepo.CountByFileID(ctx, fileID)
+	return int32(n), err
+}
+
+// StoredChunkIndexes 文件已存储的 chunk_index（升序），断点续传时跳过这些 chunks
+func (cs *ChunkService) StoredChunkIndexes(ctx context.Context, fileID string) ([]int32, error) {
+	return cs.chunkRepo.ListChunkIndexes(ctx, fileID)
+}
+
+// RebuildContext 按 chunk_index 顺序从已存储的 chunks 重建处理上下文：流已经结束、上下文已清理，
+// 或续传的流只发送了缺失的 chunks 时使用
+func (cs *ChunkService) RebuildContext(ctx context.Context, fileID string) error {
+	chunks, err := cs.chunkRepo.GetTextsByFileID(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	cs.mu.Lock()
+	docCtx := cs.putContextLocked(fileID)
+	cs.mu.Unlock()
+	for _, c := range chunks {
+		cs.appendToContext(docCtx, c)
+	}
+	return nil
+}
+
+// SummaryInput 按 chunk_index 顺序拼接文件已存储的 chunks，作为生成摘要的全文（第一行为文件名）
+func (cs *ChunkService) SummaryInput(ctx context.Context, fileID, filename string) (string, error) {
+	chunks, err := cs.chunkRepo.GetTextsByFileID(ctx, fileID)
+	if err != nil {
+		return "", err
+	}
+	if len(chunks) == 0 {
+		return "", fmt.Errorf("no stored chunks for %s", fileID)
+	}
+	var fullText strings.Builder
+	fullText.WriteString(filename)
+	fullText.WriteString("\n")
+	for _, c := range chunks {
+		fullText.WriteString(c.Chapter)
+		fullText.WriteString("\n")
+		fullText.WriteString(c.ChunkText)
+		fullText.WriteString("\n")
+	}
+	return fullText.String(), nil
+}
+
+// ChunkID 与 pdf_processor 相同的 chunk ID：md5("{file_id}_{chunk_index}")
+func ChunkID(fileID string, chunkIndex int32) string {
+	sum := md5.Sum([]byte(fmt.Sprintf("%s_%d", fileID, chunkIndex)))
+	return hex.EncodeToString(sum[:])
+}
+
+// buildChunk 校验向量维度并清理文本，没有 chunk_id 时按规则生成
+func (cs *ChunkService) buildChunk(chunk *cognicore.TextChunk) (*models.Chunk, error) {
+	// 维度必须与激活模型一致，否则会和其他向量混在一起无法比较
+	if err := cs.registry.Validate(chunk.EmbeddingVector); err != nil {
+		logging.Logger.Error("invalid chunk embedding", "chunk_index", chunk.ChunkIndex, "error", err)
+		return nil, err
 	}
 
 	// Clean text to remove NULL bytes (PostgreSQL doesn't allow \x00 in UTF-8)
 	cleanedChapter := strings.ReplaceAll(chunk.Chapter, "\x00", "")
 	cleanedText := strings.ReplaceAll(chunk.ChunkText, "\x00", "")
-
-	// 获取文档上下文并更新
-	docCtx := cs.getOrCreateContext(chunk.FileId)
-	docCtx.mu.Lock()
-	docCtx.FullText.WriteString(cleanedChapter)
-	docCtx.FullText.WriteString("\n")
-	docCtx.FullText.WriteString(cleanedText)
-	docCtx.FullText.WriteString("\n")
-
-	// 去重并添加 section
-	if cleanedChapter != "" && !contains(docCtx.Sections, cleanedChapter) {
-		docCtx.Sections = append(docCtx.Sections, cleanedChapter)
+	chunkID := chunk.ChunkId
+	if chunkID == "" {
+		chunkID = ChunkID(chunk.FileId, chunk.ChunkIndex)
 	}
-	docCtx.mu.Unlock()
 
-	chunkRes := &models.Chunk{
-		ChunkID:         chunk.ChunkId,
+	return &models.Chunk{
+		ChunkID:         chunkID,
 		FileID:          chunk.FileId,
 		ChunkIndex:      chunk.ChunkIndex,
 		Chapter:         cleanedChapter,
 		ChunkText:       cleanedText,
 		EmbeddingVector: pgvector.NewVector(chunk.EmbeddingVector),
+		EmbeddingModel:  cs.registry.ActiveID(),
 		CreatedAt:       time.Now(),
-	}
+	}, nil
+}
 
-	return cs.chunkRepo.Create(ctx, chunkRes)
+// appendToContext 记录 chunk 中新出现的 section，并刷新上下文的活跃时间
+func (cs *ChunkService) appendToContext(docCtx *DocumentProcessContext, chunk *models.Chunk) {
+	docCtx.mu.Lock()
+	defer docCtx.mu.Unlock()
+	docCtx.lastSeen = time.Now()
+
+	// 去重并添加 section
+	if chunk.Chapter != "" && !contains(docCtx.Sections, chunk.Chapter) {
+		docCtx.Sections = append(docCtx.Sections, chunk.Chapter)
+	}
 }
 
 // contains 辅助函数：检查字符串是否在切片中
@@ -2,29 +2,216 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/cache"
 	"go_chat_backend/platform/grpc/clients"
 	pb "go_chat_backend/platform/proto/cognicore"
 	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const embeddingCachePrefix = "embedding:"
+
+// EmbeddingConfig embedding 调用参数
+type EmbeddingConfig struct {
+	Model        string        // 模型 ID（name@version），作为缓存 key 的一部分
+	Dimension    int           // 期望的向量维度，0 表示不检查
+	Timeout      time.Duration // 单次 RPC 超时
+	MaxRetries   int           // 临时错误的重试次数
+	RetryBackoff time.Duration // 首次重试等待时间，之后翻倍
+	BatchSize    int           // 单次 GetEmbeddings 最多发送的文本数
+	CacheTTL     time.Duration // 向量缓存时间，0 表示不缓存
+}
+
 type GRPCService struct {
 	clients *clients.GrpcClients
+	cfg     EmbeddingConfig
+	cache   *cache.TypedCache[[]float32]
+}
+
+func NewGRPCService(clients *clients.GrpcClients, cacheService cache.CacheService, cfg EmbeddingConfig) *GRPCService {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 32
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 200 * time.Millisecond
+	}
+	s := &GRPCService{clients: clients, cfg: cfg}
+	if cacheService != nil && cfg.CacheTTL > 0 {
+		s.cache = cache.NewTypedCache[[]float32](cacheService)
+	}
+	return s
+}
+
+// GetEmbedding 向量化单条文本，ctx 的超时和取消传递给 gRPC 调用
+func (s *GRPCService) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := s.GetEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
 }
 
-func NewGRPCService(clients *clients.GrpcClients) *GRPCService {
-	return &GRPCService{clients: clients}
+// GetEmbeddings 批量向量化，结果与 texts 一一对应
+// 先查缓存（内容哈希 + 模型名），未命中的文本去重后按 BatchSize 分批调用 Python
+func (s *GRPCService) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	res := make([][]float32, len(texts))
+	missing := make(map[string][]int) // text -> 在 texts 中的位置
+	var pending []string
+	for i, text := range texts {
+		if v, ok := s.getCached(text); ok {
+			res[i] = v
+			continue
+		}
+		if _, seen := missing[text]; !seen {
+			pending = append(pending, text)
+		}
+		missing[text] = append(missing[text], i)
+	}
+
+	for start := 0; start < len(pending); start += s.cfg.BatchSize {
+		end := min(start+s.cfg.BatchSize, len(pending))
+		batch := pending[start:end]
+		embeddings, err := s.embedBatch(ctx, batch)
+		if err != nil {
+			logging.Logger.Error("fail GetEmbeddings", "error", err, "texts", len(batch))
+			return nil, err
+		}
+		for j, text := range batch {
+			for _, i := range missing[text] {
+				res[i] = embeddings[j]
+			}
+			s.setCached(text, embeddings[j])
+		}
+	}
+	return res, nil
 }
 
-func (s *GRPCService) GetEmbedding(text string) ([]float32, error) {
-	req := &pb.EmbeddingRequest{
-		TaskId: "embedding-" + time.Now().Format("20060102150405"),
-		Text:   text,
+// embedBatch 调用批量 RPC；Python 端未实现 GetEmbeddings 时退回逐条调用
+func (s *GRPCService) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp *pb.BatchEmbeddingResponse
+	err := s.withRetry(ctx, func(callCtx context.Context) error {
+		var err error
+		resp, err = s.clients.EmbeddingClient.GetEmbeddings(callCtx, &pb.BatchEmbeddingRequest{
+			TaskId: "embedding-" + time.Now().Format("20060102150405"),
+			Texts:  texts,
+		})
+		return err
+	})
+	if status.Code(err) == codes.Unimplemented {
+		return s.embedEach(ctx, texts)
 	}
-	r, err := s.clients.EmbeddingClient.GetEmbedding(context.Background(), req)
 	if err != nil {
-		logging.Logger.Error("fail GetEmbedding", err)
+		return nil, err
 	}
-	logging.Logger.Info("embedding", "embedding", r)
-	return r.Embeddings, err
+	if !resp.Success {
+		return nil, fmt.Errorf("embedding failed: %s", resp.Message)
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Embeddings))
+	}
+	res := make([][]float32, len(texts))
+	for i, e := range resp.Embeddings {
+		res[i] = e.Values
+	}
+	return res, s.checkDimension(res)
+}
+
+func (s *GRPCService) embedEach(ctx context.Context, texts []string) ([][]float32, error) {
+	res := make([][]float32, len(texts))
+	for i, text := range texts {
+		var resp *pb.EmbeddingResponse
+		err := s.withRetry(ctx, func(callCtx context.Context) error {
+			var err error
+			resp, err = s.clients.EmbeddingClient.GetEmbedding(callCtx, &pb.EmbeddingRequest{
+				TaskId: "embedding-" + time.Now().Format("20060102150405"),
+				Text:   text,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Success {
+			return nil, fmt.Errorf("embedding failed: %s", resp.Message)
+		}
+		res[i] = resp.Embeddings
+	}
+	return res, s.checkDimension(res)
+}
+
+// checkDimension 防止 Python 端换了模型后把不同维度的向量混进来
+func (s *GRPCService) checkDimension(embeddings [][]float32) error {
+	if s.cfg.Dimension <= 0 {
+		return nil
+	}
+	for _, e := range embeddings {
+		if len(e) != s.cfg.Dimension {
+			return fmt.Errorf("embedding dimension %d does not match model %s (%d)", len(e), s.cfg.Model, s.cfg.Dimension)
+		}
+	}
+	return nil
+}
+
+// withRetry 每次调用带独立超时，对临时错误按指数退避重试
+func (s *GRPCService) withRetry(ctx context.Context, call func(ctx context.Context) error) error {
+	backoff := s.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		callCtx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+		err := call(callCtx)
+		cancel()
+		if err == nil || attempt >= s.cfg.MaxRetries || !retryable(err) {
+			return err
+		}
+		logging.Logger.Warn("embedding call failed, retrying", "error", err, "attempt", attempt+1)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *GRPCService) getCached(text string) ([]float32, bool) {
+	if s.cache == nil {
+		return nil, false
+	}
+	v, found, err := s.cache.Get(s.cacheKey(text))
+	if err != nil || !found || len(v) == 0 {
+		return nil, false
+	}
+	return v, true
+}
+
+func (s *GRPCService) setCached(text string, embedding []float32) {
+	if s.cache == nil || len(embedding) == 0 {
+		return
+	}
+	if err := s.cache.Set(s.cacheKey(text), embedding, s.cfg.CacheTTL); err != nil {
+		logging.Logger.Warn("fail to cache embedding", "error", err)
+	}
+}
+
+// cacheKey embedding:<model>:<sha256(text)>，换模型后旧向量自然失效
+func (s *GRPCService) cacheKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return embeddingCachePrefix + s.cfg.Model + ":" + hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/events"
+	"go_chat_backend/platform/storage"
+	"go_chat_backend/repository"
+	"slices"
+	"time"
+)
+
+// janitorBatchSize 每次检查的文档数和对象数
+const janitorBatchSize = 100
+
+// JanitorService 定期清理：
+//   - 创建后一直没有文件的上传（客户端放弃）：删除文档记录
+//   - 处理中途 ingest 流断开、一直停在 processing 的文档：标记为失败
+//   - 存储中没有文档记录引用的孤儿对象：删除
+//   - 已完成但存储中没有对象的文档：只记录告警。分块仍可检索，但下载和重新处理会失败，需要人工处理；
+//     completed 不能转换到 failed。failed 的文档不检查：校验未通过的上传会删除对象，缺少对象是正常的
+//
+// 修复的文档都会发布 EventDocumentFailed
+type JanitorService struct {
+	docRepo        repository.DocumentRepository
+	storageService *storage.Service
+	eventPublisher *events.EventPublisher
+	abandonedAfter time.Duration
+	staleAfter     time.Duration
+	interval       time.Duration
+	// completedCursor 上一轮检查到的已完成文档，每轮检查一批，遍历完后从头开始
+	completedCursor string
+}
+
+func NewJanitorService(
+	docRepo repository.DocumentRepository,
+	storageService *storage.Service,
+	eventPublisher *events.EventPublisher,
+	abandonedAfter time.Duration,
+	staleAfter time.Duration,
+	interval time.Duration,
+) *JanitorService {
+	return &JanitorService{
+		docRepo:        docRepo,
+		storageService: storageService,
+		eventPublisher: eventPublisher,
+		abandonedAfter: abandonedAfter,
+		staleAfter:     staleAfter,
+		interval:       interval,
+	}
+}
+
+// Run 按 interval 定期执行，直到 ctx 取消
+func (s *JanitorService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			logging.Logger.Error("fail janitor", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 执行一轮清理
+func (s *JanitorService) RunOnce(ctx context.Context) error {
+	if err := s.reapDocuments(ctx); err != nil {
+		return err
+	}
+	if err := s.checkCompletedDocuments(ctx); err != nil {
+		return err
+	}
+	return s.reapOrphanObjects(ctx)
+}
+
+// reapDocuments 处理创建超过 abandonedAfter 仍在 processing（或 finalizing）的文档
+func (s *JanitorService) reapDocuments(ctx context.Context) error {
+	now := time.Now()
+	docs, err := s.docRepo.ListStaleProcessing(ctx, now.Add(-s.abandonedAfter), janitorBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list processing documents: %w", err)
+	}
+	for _, doc := range docs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		exists, err := s.storageService.FileExists(doc.FileKey)
+		if err != nil {
+			logging.Logger.Error("fail FileExists", "error", err, "docID", doc.FileID)
+			continue
+		}
+		if !exists {
+			s.reapAbandonedUpload(ctx, doc)
+			continue
+		}
+		if lastActivity(doc).Before(now.Add(-s.staleAfter)) {
+			s.reapStuckDocument(ctx, doc)
+		}
+	}
+	return nil
+}
+
+// lastActivity ETL 开始处理时会更新 started_at，没有时用创建时间
+func lastActivity(doc *models.DocumentMeta) time.Time {
+	if doc.StartedAt != nil && doc.StartedAt.After(doc.CreatedAt) {
+		return *doc.StartedAt
+	}
+	return doc.CreatedAt
+}
+
+func (s *JanitorService) reapAbandonedUpload(ctx context.Context, doc *models.DocumentMeta) {
+	if err := s.docRepo.Purge(ctx, doc.FileID); err != nil {
+		logging.Logger.Error("fail Purge", "error", err, "docID", doc.FileID)
+		return
+	}
+	logging.Logger.Info("removed abandoned upload", "docID", doc.FileID, "createdAt", doc.CreatedAt)
+	s.publishFailed(doc, "upload_abandoned", "Upload was never completed, document removed")
+}
+
+func (s *JanitorService) reapStuckDocument(ctx context.Context, doc *models.DocumentMeta) {
+	// 条件更新：检查期间刚好处理完成的文档不受影响
+	message := fmt.Sprintf("Processing did not finish within %s", s.staleAfter)
+	err := transitionStatus(ctx, s.docRepo, doc, models.StatusFailed, message)
+	if errors.Is(err, models.ErrInvalidStatusTransition) {
+		return
+	}
+	if err != nil {
+		logging.Logger.Error("fail TransitionStatus", "error", err, "docID", doc.FileID)
+		return
+	}
+	logging.Logger.Warn("marked stuck document as failed", "docID", doc.FileID, "chunksReceived", doc.ChunksReceived, "chunksStored", doc.ChunksStored)
+	s.publishFailed(doc, "processing_timeout", message)
+}
+
+// checkCompletedDocuments 检查一批已完成文档的对象是否还在存储中
+func (s *JanitorService) checkCompletedDocuments(ctx context.Context) error {
+	docs, err := s.docRepo.ListCompletedAfter(ctx, s.completedCursor, janitorBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list completed documents: %w", err)
+	}
+	if len(docs) < janitorBatchSize {
+		s.completedCursor = ""
+	} else {
+		s.completedCursor = docs[len(docs)-1].FileID
+	}
+	for _, doc := range docs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		exists, err := s.storageService.FileExists(doc.FileKey)
+		if err != nil {
+			logging.Logger.Error("fail FileExists", "error", err, "docID", doc.FileID)
+			continue
+		}
+		if !exists {
+			logging.Logger.Warn("stored file of completed document is missing", "docID", doc.FileID, "fileKey", doc.FileKey)
+		}
+	}
+	return nil
+}
+
+func (s *JanitorService) publishFailed(doc *models.DocumentMeta, status, message string) {
+	err := s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
+		Type:    models.EventDocumentFailed,
+		DocID:   doc.FileID,
+		UserID:  doc.UserID,
+		Status:  status,
+		Message: message,
+		Progress: &models.ProgressInfo{
+			ChunksReceived: doc.ChunksReceived,
+			ChunksStored:   doc.ChunksStored,
+			ChunksFailed:   doc.ChunksFailed,
+			TotalChunks:    doc.EstimatedChunks,
+		},
+	})
+	if err != nil {
+		logging.Logger.Error("fail to publish event", "error", err, "docID", doc.FileID)
+	}
+}
+
+// reapOrphanObjects 删除没有文档记录引用的对象
+// 只检查超过 abandonedAfter 的对象：直接上传先写对象再建记录，新对象可能还没有记录
+func (s *JanitorService) reapOrphanObjects(ctx context.Context) error {
+	cutoff := time.Now().Add(-s.abandonedAfter)
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		existing, err := s.docRepo.ExistingFileKeys(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to look up file keys: %w", err)
+		}
+		for _, key := range batch {
+			if slices.Contains(existing, key) {
+				continue
+			}
+			if err := s.storageService.DeleteFile(key); err != nil {
+				logging.Logger.Error("fail DeleteFile", "error", err, "fileKey", key)
+				continue
+			}
+			logging.Logger.Info("removed orphan object", "fileKey", key)
+		}
+		batch = batch[:0]
+		return nil
+	}
+	err := s.storageService.ListFiles(ctx, func(obj storage.ObjectInfo) error {
+		if obj.LastModified.After(cutoff) {
+			return nil
+		}
+		batch = append(batch, obj.Key)
+		if len(batch) < janitorBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+	return flush()
+}
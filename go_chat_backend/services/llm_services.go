@@ -8,71 +8,119 @@ import (
 	"go_chat_backend/repository"
 	"go_chat_backend/utils"
 	"strings"
+	"unicode/utf8"
 )
 
 type LLMService struct {
-	chunkRepository repository.ChunkRepository
-	GRPCService     *GRPCService
+	chunkRepository  repository.ChunkRepository
+	docRepository    repository.DocumentRepository
+	GRPCService      *GRPCService
+	retrievalService *RetrievalService
 }
 
-func NewLLMService(chunkRepository repository.ChunkRepository, grpcService *GRPCService) *LLMService {
+func NewLLMService(chunkRepository repository.ChunkRepository, docRepository repository.DocumentRepository, grpcService *GRPCService, retrievalService *RetrievalService) *LLMService {
 	return &LLMService{
-		chunkRepository: chunkRepository,
-		GRPCService:     grpcService,
+		chunkRepository:  chunkRepository,
+		docRepository:    docRepository,
+		GRPCService:      grpcService,
+		retrievalService: retrievalService,
 	}
 }
-func (s *LLMService) BuildPrompt(history []*models.ChatNode, question, section, fileID, provider, apikey string, ragMode bool) string {
-	// assume
+
+// BuildPrompt 组装发送给 LLM 的 prompt，同时返回检索调试信息（实际使用的上下文、分数、耗时）
+func (s *LLMService) BuildPrompt(ctx context.Context, history []*models.ChatNode, question, section, fileID string, llmConfig *LLMConfig, ragMode bool) (string, *models.RetrievalDebug) {
+	debug := &models.RetrievalDebug{RagMode: ragMode}
 	var builder strings.Builder
 	builder.WriteString("You are an AI assistant helping the user understand a technical document.\n\n")
 
 	if section != "" {
-		ctx := context.Background()
-		chunkContext, err := s.chunkRepository.GetNodeBySection(ctx, section, fileID)
+		chunkContext, err := s.chunkRepository.GetNodeBySection(ctx, section, s.chunkFileID(ctx, fileID))
 		if err != nil {
-			logging.Logger.Error("fail GetNodeBySection", err)
+			logging.Logger.Error("fail GetNodeBySection", "error", err)
 		}
+		debug.SectionContext = chunkContext.ChunkText
 		builder.WriteString(fmt.Sprintf("The following questions are about Section %s:\n%s\n\n", section, chunkContext.ChunkText))
 	}
 	if ragMode {
-		embedding, err := s.GRPCService.GetEmbedding(question)
+		result, err := s.retrievalService.Retrieve(ctx, RetrievalRequest{
+			FileID:    fileID,
+			Question:  question,
+			History:   history,
+			LLMConfig: llmConfig,
+		})
 		if err != nil {
-			logging.Logger.Error("fail GetEmbedding", err)
+			logging.Logger.Error("fail Retrieve", "error", err, "fileID", fileID)
+			debug.RetrievalError = err.Error()
 		}
-		similar, err := s.chunkRepository.SearchSimilar(context.Background(), embedding, 1)
-		if err != nil {
-			logging.Logger.Error("fail SearchSimilar", err)
+		if result != nil {
+			debug.Passages = result.Passages
+			debug.Trace = result.Trace
 		}
-		if len(similar) == 0 {
-			logging.Logger.Error("fail SearchSimilar", "similar", similar)
+		if result != nil && len(result.Passages) > 0 {
+			builder.WriteString("The following context are similar to the question:\n")
+			for _, p := range result.Passages {
+				builder.WriteString(p.Text)
+				builder.WriteString("\n\n")
+				debug.Tokens.RetrievedContext += EstimateTokens(p.Text)
+			}
 		}
-		builder.WriteString(fmt.Sprintf("The following context are similar to the question:\n%s\n\n", similar[0].ChunkText))
 	}
 
-	if len(history) == 0 {
-		return builder.String()
+	if len(history) > 0 {
+		var historyBuilder strings.Builder
+		historyBuilder.WriteString("Previous conversation:\n")
+		for i, node := range history {
+			historyBuilder.WriteString(fmt.Sprintf("Q%d: %s\n", i+1, node.Question))
+			historyBuilder.WriteString(fmt.Sprintf("A%d: %s\n", i+1, node.Answer))
+		}
+		debug.Tokens.History = EstimateTokens(historyBuilder.String())
+		builder.WriteString(historyBuilder.String())
+		builder.WriteString("\nNow answer the following question in context of the above:\n")
 	}
+	builder.WriteString("Q: " + question + "\n")
 
-	builder.WriteString("Previous conversation:\n")
-	for i, node := range history {
-		builder.WriteString(fmt.Sprintf("Q%d: %s\n", i+1, node.Question))
-		builder.WriteString(fmt.Sprintf("A%d: %s\n", i+1, node.Answer))
+	prompt := builder.String()
+	debug.Prompt = prompt
+	debug.Tokens.SectionContext = EstimateTokens(debug.SectionContext)
+	debug.Tokens.Prompt = EstimateTokens(prompt)
+	if llmConfig != nil {
+		debug.Messages = utils.BuildMessages(llmConfig.Provider, prompt)
 	}
+	return prompt, debug
+}
 
-	builder.WriteString("\nNow answer the following question in context of the above:\n")
-	builder.WriteString("Q: " + question + "\n")
-
-	return builder.String()
+// EstimateTokens 粗略估算 token 数（约 4 个字符一个 token）
+func EstimateTokens(text string) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return (n + 3) / 4
 }
 
 func (s *LLMService) CallLLM(prompt, provider, modelName, APIKey string) (string, error) {
+	return callLLM(context.Background(), prompt, provider, modelName, APIKey)
+}
+
+// callLLM 调用 provider 的 LLM，ctx 取消或超时时中止 HTTP 请求
+func callLLM(ctx context.Context, prompt, provider, modelName, APIKey string) (string, error) {
 	switch provider {
 	case "OpenAI":
-		return utils.CallOpenAI(prompt, modelName, APIKey)
+		return utils.CallOpenAI(ctx, prompt, modelName, APIKey)
 	case "Gemini":
-		return utils.CallGemini(prompt, modelName, APIKey)
+		return utils.CallGemini(ctx, prompt, modelName, APIKey)
 	default:
 		logging.Logger.Error("invalid provider", "provider", provider)
 		return "", fmt.Errorf("invalid provider")
 	}
 }
+
+// chunkFileID 文档 chunks 所在的 file_id（内容相同的文档共用 chunks）
+func (s *LLMService) chunkFileID(ctx context.Context, fileID string) string {
+	doc, err := s.docRepository.GetByID(ctx, fileID)
+	if err != nil {
+		logging.Logger.Error("fail GetByID", "error", err, "fileID", fileID)
+		return fileID
+	}
+	return doc.ChunkFileID()
+}
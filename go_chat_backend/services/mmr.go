@@ -0,0 +1,86 @@
+package services
+
+import (
+	"go_chat_backend/models"
+	"math"
+)
+
+// mmrSelect 使用最大边际相关性（MMR）从候选中选出 k 个：
+// score = λ·relevance − (1−λ)·max(sim(candidate, selected))
+// relevance 为候选原分数归一化到 [0,1] 后的值，sim 为 embedding 余弦相似度。
+// 返回选中结果（按选择顺序）以及每个被选中候选在选中时的 MMR 分数
+func mmrSelect(candidates []*models.ScoredChunk, k int, lambda float64) ([]*models.ScoredChunk, map[string]float64) {
+	scores := make(map[string]float64, k)
+	if k <= 0 || len(candidates) == 0 {
+		return nil, scores
+	}
+	relevance := normalizeScores(candidates)
+	vectors := make([][]float32, len(candidates))
+	for i, c := range candidates {
+		vectors[i] = c.Chunk.EmbeddingVector.Slice()
+	}
+
+	used := make([]bool, len(candidates))
+	// maxSim[i] 为候选 i 与已选集合的最大相似度
+	maxSim := make([]float64, len(candidates))
+	var selected []*models.ScoredChunk
+	for len(selected) < k && len(selected) < len(candidates) {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range candidates {
+			if used[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSim[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		used[best] = true
+		selected = append(selected, candidates[best])
+		scores[candidates[best].Chunk.ChunkID] = bestScore
+
+		for i := range candidates {
+			if used[i] {
+				continue
+			}
+			if sim := cosineSimilarity(vectors[i], vectors[best]); sim > maxSim[i] {
+				maxSim[i] = sim
+			}
+		}
+	}
+	return selected, scores
+}
+
+// normalizeScores 将分数 min-max 归一化到 [0,1]
+func normalizeScores(candidates []*models.ScoredChunk) []float64 {
+	minScore, maxScore := math.Inf(1), math.Inf(-1)
+	for _, c := range candidates {
+		minScore = math.Min(minScore, c.Score)
+		maxScore = math.Max(maxScore, c.Score)
+	}
+	res := make([]float64, len(candidates))
+	for i, c := range candidates {
+		if maxScore > minScore {
+			res[i] = (c.Score - minScore) / (maxScore - minScore)
+		} else {
+			res[i] = 1
+		}
+	}
+	return res
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
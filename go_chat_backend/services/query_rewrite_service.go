@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_chat_backend/models"
+	"strings"
+	"time"
+)
+
+// maxRewriteHistory 改写时最多参考的历史轮数
+const maxRewriteHistory = 6
+
+// QueryRewriteConfig 查询改写配置
+type QueryRewriteConfig struct {
+	Enabled     bool // 将追问改写为独立问题
+	Paraphrases int  // 额外生成的同义改写数量
+	HyDE        bool // 生成假设答案（HyDE）用于检索
+	// Timeout 改写超时，超时后使用原问题检索
+	Timeout time.Duration
+}
+
+// RewrittenQuery 改写结果
+type RewrittenQuery struct {
+	Standalone   string   `json:"standalone"`
+	Paraphrases  []string `json:"paraphrases,omitempty"`
+	Hypothetical string   `json:"hypothetical_answer,omitempty"`
+}
+
+// Queries 返回所有需要向量化的查询（去重，独立问题在最前）
+func (q *RewrittenQuery) Queries() []string {
+	seen := make(map[string]bool)
+	var res []string
+	for _, text := range append(append([]string{q.Standalone}, q.Paraphrases...), q.Hypothetical) {
+		text = strings.TrimSpace(text)
+		if text == "" || seen[text] {
+			continue
+		}
+		seen[text] = true
+		res = append(res, text)
+	}
+	return res
+}
+
+// QueryRewriter 利用对话分支历史把追问改写为可独立检索的查询
+type QueryRewriter struct {
+	cfg QueryRewriteConfig
+}
+
+func NewQueryRewriter(cfg QueryRewriteConfig) *QueryRewriter {
+	if cfg.Paraphrases < 0 {
+		cfg.Paraphrases = 0
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 3 * time.Second
+	}
+	return &QueryRewriter{cfg: cfg}
+}
+
+// Active 是否需要调用 LLM
+func (r *QueryRewriter) Active() bool {
+	return r.cfg.Enabled || r.cfg.Paraphrases > 0 || r.cfg.HyDE
+}
+
+// Rewrite 调用 LLM 改写问题；未启用时原样返回。LLM 调用受 ctx 和 Timeout 限制，
+// 失败或超时时返回原问题和错误，调用方可以直接使用原问题检索
+func (r *QueryRewriter) Rewrite(ctx context.Context, history []*models.ChatNode, question string, llmConfig *LLMConfig) (*RewrittenQuery, error) {
+	original := &RewrittenQuery{Standalone: question}
+	if !r.Active() {
+		return original, nil
+	}
+	// 没有历史且不需要扩展查询时无需改写
+	if len(history) == 0 && r.cfg.Paraphrases == 0 && !r.cfg.HyDE {
+		return original, nil
+	}
+	if llmConfig == nil {
+		return original, fmt.Errorf("query rewriting requires an LLM config")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+	answer, err := callLLM(ctx, r.buildPrompt(history, question), llmConfig.Provider, llmConfig.Model, llmConfig.APIKey)
+	if err != nil {
+		return original, err
+	}
+	start := strings.Index(answer, "{")
+	end := strings.LastIndex(answer, "}")
+	if start < 0 || end <= start {
+		return original, fmt.Errorf("no JSON object in rewrite response")
+	}
+	var res RewrittenQuery
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &res); err != nil {
+		return original, fmt.Errorf("failed to parse rewrite response: %w", err)
+	}
+	if strings.TrimSpace(res.Standalone) == "" || !r.cfg.Enabled {
+		res.Standalone = question
+	}
+	if len(res.Paraphrases) > r.cfg.Paraphrases {
+		res.Paraphrases = res.Paraphrases[:r.cfg.Paraphrases]
+	}
+	if !r.cfg.HyDE {
+		res.Hypothetical = ""
+	}
+	return &res, nil
+}
+
+func (r *QueryRewriter) buildPrompt(history []*models.ChatNode, question string) string {
+	var builder strings.Builder
+	builder.WriteString("You rewrite questions about a technical document so they can be used for semantic search.\n\n")
+
+	// 只保留最近几轮；根节点是文档摘要，问题部分是摘要指令，不需要
+	if len(history) > maxRewriteHistory {
+		history = history[len(history)-maxRewriteHistory:]
+	}
+	if len(history) > 0 {
+		builder.WriteString("Conversation so far:\n")
+		for i, node := range history {
+			if node.ParentID == "" {
+				builder.WriteString(fmt.Sprintf("Document summary: %s\n", node.Answer))
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("Q%d: %s\n", i+1, node.Question))
+			builder.WriteString(fmt.Sprintf("A%d: %s\n", i+1, node.Answer))
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString("Follow-up question: " + question + "\n\n")
+
+	builder.WriteString("Reply with a JSON object only, with these fields:\n")
+	builder.WriteString(`- "standalone": the follow-up rewritten as a self-contained question that resolves every reference to the conversation` + "\n")
+	if r.cfg.Paraphrases > 0 {
+		builder.WriteString(fmt.Sprintf(`- "paraphrases": an array of %d different phrasings of the standalone question`+"\n", r.cfg.Paraphrases))
+	}
+	if r.cfg.HyDE {
+		builder.WriteString(`- "hypothetical_answer": a short passage that could plausibly appear in the document and answer the question` + "\n")
+	}
+	return builder.String()
+}
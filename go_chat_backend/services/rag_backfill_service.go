@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/events"
+	"go_chat_backend/repository"
+	"time"
+)
+
+const (
+	// ragBackfillStaleAfter 回填心跳超过该时间未更新视为实例中断，可被接管
+	ragBackfillStaleAfter = 5 * time.Minute
+	// ragBackfillResumeInterval 检查中断回填的间隔
+	ragBackfillResumeInterval = time.Minute
+)
+
+// errRagBackfillCancelled 回填期间 RAG 被关闭
+var errRagBackfillCancelled = errors.New("rag backfill cancelled")
+
+// RagBackfillService 切换已上传文档的 RAG 模式
+// chat-only 模式处理的文档没有向量：开启 RAG 时先通过 embedding 服务为其 chunks 补齐向量，
+// 全部完成后才打开标志。回填状态保存在文档上：中断的回填由 Run 接管，从剩余的 chunks 继续；
+// 回填期间关闭 RAG 会取消回填
+type RagBackfillService struct {
+	docRepo        repository.DocumentRepository
+	chunkRepo      repository.ChunkRepository
+	embedder       Embedder
+	registry       *EmbeddingModelRegistry
+	ragService     *RagModeService
+	eventPublisher *events.EventPublisher
+	batchSize      int
+}
+
+func NewRagBackfillService(
+	docRepo repository.DocumentRepository,
+	chunkRepo repository.ChunkRepository,
+	embedder Embedder,
+	registry *EmbeddingModelRegistry,
+	ragService *RagModeService,
+	eventPublisher *events.EventPublisher,
+	batchSize int,
+) *RagBackfillService {
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	return &RagBackfillService{
+		docRepo:        docRepo,
+		chunkRepo:      chunkRepo,
+		embedder:       embedder,
+		registry:       registry,
+		ragService:     ragService,
+		eventPublisher: eventPublisher,
+		batchSize:      batchSize,
+	}
+}
+
+// SwitchRagMode 关闭 RAG 立即生效并取消进行中的回填；开启时 chunks 缺少向量则在后台回填，完成后再开启。
+// 只能修改 req.UserID 自己的文档
+func (s *RagBackfillService) SwitchRagMode(ctx context.Context, docID string, req models.RagModeReq) (*models.RagModeResp, error) {
+	doc, err := loadOwnedDocument(ctx, s.docRepo, docID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !req.RagMode {
+		// 先取消回填：之后结束的回填不会再开启 RAG
+		if err := s.docRepo.SetRagBackfillStatus(ctx, docID, ""); err != nil {
+			return nil, err
+		}
+		if err := s.ragService.SetRagMode(ctx, docID, false); err != nil {
+			return nil, err
+		}
+		return &models.RagModeResp{DocID: docID, RagMode: false, Status: "updated"}, nil
+	}
+	if doc.IsProcessing() {
+		return nil, ErrDocumentBusy
+	}
+
+	chunkSet := doc.ChunkFileID()
+	missing, err := s.chunkRepo.CountMissingEmbeddings(ctx, chunkSet)
+	if err != nil {
+		return nil, err
+	}
+	if missing == 0 {
+		if err := s.ragService.SetRagMode(ctx, docID, true); err != nil {
+			return nil, err
+		}
+		return &models.RagModeResp{DocID: docID, RagMode: true, Status: "updated"}, nil
+	}
+	now := time.Now()
+	claimed, err := s.docRepo.ClaimRagBackfill(ctx, docID, now, now.Add(-ragBackfillStaleAfter))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return &models.RagModeResp{DocID: docID, RagMode: doc.RagMode, Status: "backfilling"}, nil
+	}
+	go s.backfill(context.Background(), doc, chunkSet, missing)
+	return &models.RagModeResp{DocID: docID, RagMode: doc.RagMode, Status: "backfilling", Missing: missing}, nil
+}
+
+// Run 每隔 ragBackfillResumeInterval 接管心跳超时的回填，直到 ctx 结束
+func (s *RagBackfillService) Run(ctx context.Context) {
+	ticker := time.NewTicker(ragBackfillResumeInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.ResumeStale(ctx); err != nil && ctx.Err() == nil {
+			logging.Logger.Error("fail to resume rag backfills", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ResumeStale 逐个接管中断的回填并执行到结束
+func (s *RagBackfillService) ResumeStale(ctx context.Context) error {
+	staleBefore := time.Now().Add(-ragBackfillStaleAfter)
+	docs, err := s.docRepo.ListStaleRagBackfills(ctx, staleBefore, 20)
+	if err != nil {
+		return fmt.Errorf("failed to list stale rag backfills: %w", err)
+	}
+	for _, doc := range docs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		claimed, err := s.docRepo.ClaimRagBackfill(ctx, doc.FileID, time.Now(), staleBefore)
+		if err != nil {
+			logging.Logger.Error("fail ClaimRagBackfill", "error", err, "docID", doc.FileID)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		chunkSet := doc.ChunkFileID()
+		missing, err := s.chunkRepo.CountMissingEmbeddings(ctx, chunkSet)
+		if err != nil {
+			logging.Logger.Error("fail CountMissingEmbeddings", "error", err, "docID", doc.FileID)
+			continue
+		}
+		logging.Logger.Info("resuming rag backfill", "docID", doc.FileID, "missing", missing)
+		s.backfill(ctx, doc, chunkSet, missing)
+	}
+	return nil
+}
+
+// backfill 分批向量化缺少向量的 chunks，通过文档事件报告进度；需要先领取回填
+func (s *RagBackfillService) backfill(ctx context.Context, doc *models.DocumentMeta, chunkSet string, total int64) {
+	logging.Logger.Info("rag backfill started", "docID", doc.FileID, "chunkSet", chunkSet, "missing", total)
+
+	done, err := s.embedMissing(ctx, doc, chunkSet, total)
+	if err == nil {
+		err = s.docRepo.UpdateEmbeddingModel(ctx, chunkSet, s.registry.ActiveID())
+	}
+	if err == nil {
+		// 条件更新：回填期间 RAG 被关闭时不再开启
+		var enabled bool
+		if enabled, err = s.docRepo.FinishRagBackfill(ctx, doc.FileID); err == nil && !enabled {
+			err = errRagBackfillCancelled
+		}
+	}
+	if errors.Is(err, errRagBackfillCancelled) {
+		logging.Logger.Info("rag backfill cancelled", "docID", doc.FileID, "done", done)
+		return
+	}
+	if err == nil {
+		if cerr := s.ragService.InvalidateCache(doc.FileID); cerr != nil {
+			logging.Logger.Error("fail to invalidate rag mode cache", "error", cerr, "docID", doc.FileID)
+		}
+	}
+	if err != nil {
+		logging.Logger.Error("fail rag backfill", "error", err, "docID", doc.FileID, "done", done)
+		if ctx.Err() != nil {
+			// 服务关闭：保留 running 状态，心跳超时后由其他实例接管
+			return
+		}
+		if serr := s.docRepo.SetRagBackfillStatus(context.Background(), doc.FileID, models.RagBackfillFailed); serr != nil {
+			logging.Logger.Error("fail SetRagBackfillStatus", "error", serr, "docID", doc.FileID)
+		}
+		s.publish(&models.DocumentEvent{
+			Type:     models.EventDocumentFailed,
+			DocID:    doc.FileID,
+			UserID:   doc.UserID,
+			Status:   "backfill_failed",
+			Message:  "Embedding backfill failed, RAG mode unchanged: " + err.Error(),
+			Progress: backfillProgress(done, total),
+		})
+		return
+	}
+	logging.Logger.Info("rag backfill completed", "docID", doc.FileID, "chunks", done)
+	s.publish(&models.DocumentEvent{
+		Type:     models.EventDocumentCompleted,
+		DocID:    doc.FileID,
+		UserID:   doc.UserID,
+		Status:   "rag_enabled",
+		Message:  "Embedding backfill completed, RAG mode enabled",
+		Progress: backfillProgress(done, total),
+	})
+}
+
+func (s *RagBackfillService) embedMissing(ctx context.Context, doc *models.DocumentMeta, chunkSet string, total int64) (int64, error) {
+	model := s.registry.ActiveID()
+	var done int64
+	for {
+		chunks, err := s.chunkRepo.GetMissingEmbeddings(ctx, chunkSet, s.batchSize)
+		if err != nil {
+			return done, err
+		}
+		if len(chunks) == 0 {
+			return done, nil
+		}
+		// 心跳；回填已取消时停止
+		active, err := s.docRepo.TouchRagBackfill(ctx, doc.FileID, time.Now())
+		if err != nil {
+			return done, err
+		}
+		if !active {
+			return done, errRagBackfillCancelled
+		}
+		texts := make([]string, len(chunks))
+		for i, c := range chunks {
+			texts[i] = c.ChunkText
+		}
+		embeddings, err := s.embedder.GetEmbeddings(ctx, texts)
+		if err != nil {
+			return done, err
+		}
+		for i, c := range chunks {
+			if err := s.registry.Validate(embeddings[i]); err != nil {
+				return done, err
+			}
+			// 全零向量写回后仍被视为缺失，会导致死循环
+			if isZeroVector(embeddings[i]) {
+				return done, fmt.Errorf("embedding service returned a zero vector for chunk %s", c.ChunkID)
+			}
+			if err := s.chunkRepo.UpdateEmbedding(ctx, c.ChunkID, model, embeddings[i]); err != nil {
+				return done, err
+			}
+		}
+		done += int64(len(chunks))
+		s.publish(&models.DocumentEvent{
+			Type:     models.EventDocumentProcessing,
+			DocID:    doc.FileID,
+			UserID:   doc.UserID,
+			Status:   "backfilling",
+			Message:  fmt.Sprintf("Embedding backfill: %d/%d chunks", done, total),
+			Progress: backfillProgress(done, total),
+		})
+	}
+}
+
+func (s *RagBackfillService) publish(event *models.DocumentEvent) {
+	if err := s.eventPublisher.PublishDocumentEvent(event); err != nil {
+		logging.Logger.Error("fail PublishDocumentEvent", "error", err, "docID", event.DocID)
+	}
+}
+
+func backfillProgress(done, total int64) *models.ProgressInfo {
+	percentage := 100
+	if total > 0 && done < total {
+		percentage = int(done * 100 / total)
+	}
+	return &models.ProgressInfo{
+		ChunksStored: int32(done),
+		TotalChunks:  int32(total),
+		Percentage:   percentage,
+	}
+}
+
+func isZeroVector(v []float32) bool {
+	for _, x := range v {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
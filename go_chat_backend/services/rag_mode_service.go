@@ -59,21 +59,12 @@ func (s *RagModeService) GetRagMode(ctx context.Context, fileID string) (bool, e
 func (s *RagModeService) SetRagMode(ctx context.Context, fileID string, ragMode bool) error {
 	cacheKey := s.getCacheKey(fileID)
 
-	// 1. 先获取文档
-	doc, err := s.docRepo.GetByID(ctx, fileID)
-	if err != nil {
-		return fmt.Errorf("failed to get document: %w", err)
-	}
-
-	// 2. 更新 RagMode 字段
-	doc.RagMode = ragMode
-
-	// 3. 更新数据库（使用 UpdateMetadata）
-	if err := s.docRepo.UpdateMetadata(ctx, fileID, doc); err != nil {
+	// 1. 更新数据库
+	if err := s.docRepo.UpdateRagMode(ctx, fileID, ragMode); err != nil {
 		return fmt.Errorf("failed to update rag mode in database: %w", err)
 	}
 
-	// 4. 更新 L1 缓存
+	// 2. 更新 L1 缓存
 	if err := s.l1Cache.Set(cacheKey, ragMode, ragModeCacheTTL); err != nil {
 		// 缓存失败不影响主流程，只记录日志
 		// 下次查询时会从数据库重新加载
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/repository"
+	"time"
+)
+
+// ReembedService 后台任务：把仍使用旧模型向量的文档迁移到激活模型
+// 逐批重新向量化并原地替换向量，全部完成后更新文档的 embedding_model；中断后下次从剩余 chunk 继续
+type ReembedService struct {
+	docRepo   repository.DocumentRepository
+	chunkRepo repository.ChunkRepository
+	embedder  Embedder
+	registry  *EmbeddingModelRegistry
+	batchSize int
+	interval  time.Duration
+}
+
+func NewReembedService(
+	docRepo repository.DocumentRepository,
+	chunkRepo repository.ChunkRepository,
+	embedder Embedder,
+	registry *EmbeddingModelRegistry,
+	batchSize int,
+	interval time.Duration,
+) *ReembedService {
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	return &ReembedService{
+		docRepo:   docRepo,
+		chunkRepo: chunkRepo,
+		embedder:  embedder,
+		registry:  registry,
+		batchSize: batchSize,
+		interval:  interval,
+	}
+}
+
+// Run 每个 interval 扫描一次待迁移文档，直到 ctx 结束
+func (s *ReembedService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			logging.Logger.Error("fail re-embed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 迁移一批文档
+func (s *ReembedService) RunOnce(ctx context.Context) error {
+	model := s.registry.ActiveID()
+	if model == "" {
+		return nil
+	}
+	docs, err := s.docRepo.ListStaleEmbeddings(ctx, model, 10)
+	if err != nil {
+		return fmt.Errorf("failed to list stale documents: %w", err)
+	}
+	// 共用 chunks 的文档只处理一次
+	done := make(map[string]bool)
+	for _, doc := range docs {
+		chunkSet := doc.ChunkFileID()
+		if done[chunkSet] {
+			continue
+		}
+		done[chunkSet] = true
+		if err := s.ReembedDocument(ctx, chunkSet); err != nil {
+			return fmt.Errorf("failed to re-embed %s: %w", doc.FileID, err)
+		}
+	}
+	return nil
+}
+
+// ReembedDocument 重新向量化一组 chunks（fileID 为文档的 ChunkFileID）中不属于激活模型的所有 chunk
+func (s *ReembedService) ReembedDocument(ctx context.Context, fileID string) error {
+	model := s.registry.ActiveID()
+	total := 0
+	for {
+		chunks, err := s.chunkRepo.GetStaleEmbeddings(ctx, fileID, model, s.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			break
+		}
+		texts := make([]string, len(chunks))
+		for i, c := range chunks {
+			texts[i] = c.ChunkText
+		}
+		embeddings, err := s.embedder.GetEmbeddings(ctx, texts)
+		if err != nil {
+			return err
+		}
+		for i, c := range chunks {
+			if err := s.registry.Validate(embeddings[i]); err != nil {
+				return err
+			}
+			if err := s.chunkRepo.UpdateEmbedding(ctx, c.ChunkID, model, embeddings[i]); err != nil {
+				return err
+			}
+		}
+		total += len(chunks)
+	}
+	if err := s.docRepo.UpdateEmbeddingModel(ctx, fileID, model); err != nil {
+		return err
+	}
+	logging.Logger.Info("document re-embedded", "fileID", fileID, "model", model, "chunks", total)
+	return nil
+}
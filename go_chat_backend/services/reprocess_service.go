@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/cache"
+	"go_chat_backend/platform/proto/cognicore"
+	"go_chat_backend/platform/storage"
+	"go_chat_backend/repository"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// generationStaleAfter 超过该时间仍在处理的 generation 视为 ETL 已中断，不再阻止新的重新处理
+const generationStaleAfter = time.Hour
+
+// ErrDocumentBusy 文档仍在处理或重新处理中
+var ErrDocumentBusy = errors.New("document is still being processed")
+
+// ErrDocumentNotCompleted 只有处理完成的文档可以重新处理；失败的文档由 ETL 重试
+var ErrDocumentNotCompleted = errors.New("only completed documents can be reprocessed")
+
+// ReprocessService 对已有文档重新执行 ETL
+// 新 chunks 写入新的 generation，处理完成后文档原子地切换过去；处理期间和失败时文档继续使用旧 chunks
+type ReprocessService struct {
+	docRepo             repository.DocumentRepository
+	chunkRepo           repository.ChunkRepository
+	genRepo             repository.ChunkGenerationRepository
+	messageQueueService cache.MessageQueue
+	storageService      *storage.Service
+	cacheService        cache.CacheService
+	registry            *EmbeddingModelRegistry
+	replacedGrace       time.Duration // 旧 chunks 保留多久再交给删除任务，0 表示切换后立即删除
+}
+
+func NewReprocessService(
+	docRepo repository.DocumentRepository,
+	chunkRepo repository.ChunkRepository,
+	genRepo repository.ChunkGenerationRepository,
+	messageQueueService cache.MessageQueue,
+	storageService *storage.Service,
+	cacheService cache.CacheService,
+	registry *EmbeddingModelRegistry,
+	replacedGrace time.Duration,
+) *ReprocessService {
+	return &ReprocessService{
+		docRepo:             docRepo,
+		chunkRepo:           chunkRepo,
+		genRepo:             genRepo,
+		messageQueueService: messageQueueService,
+		storageService:      storageService,
+		cacheService:        cacheService,
+		registry:            registry,
+		replacedGrace:       replacedGrace,
+	}
+}
+
+// Reprocess 创建新的 generation 并为存储中的原文件重新排队 EtlTask
+// EtlTask.DocID 使用 generation ID，ETL 返回的 chunks 因此不会与当前 chunks 混在一起；只能重新处理 req.UserID 自己的文档
+func (s *ReprocessService) Reprocess(ctx context.Context, docID string, req models.ReprocessReq) (*models.ReprocessResp, error) {
+	doc, err := loadOwnedDocument(ctx, s.docRepo, docID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if doc.IsProcessing() {
+		return nil, ErrDocumentBusy
+	}
+	if !slices.Contains(models.CompletedStatuses, doc.Status) {
+		return nil, ErrDocumentNotCompleted
+	}
+	if err := s.checkNoPendingGeneration(ctx, docID); err != nil {
+		return nil, err
+	}
+
+	gen := &models.ChunkGeneration{
+		ID:                uuid.New().String(),
+		DocID:             docID,
+		Status:            models.GenerationProcessing,
+		RegenerateSummary: req.RegenerateSummary,
+		CreatedAt:         time.Now(),
+	}
+	if err := s.genRepo.Create(ctx, gen); err != nil {
+		logging.Logger.Error("fail to create chunk generation", "error", err, "docID", docID)
+		return nil, err
+	}
+	etlTask := models.EtlTask{
+		DocID:     gen.ID,
+		FileName:  doc.Filename,
+		UserID:    doc.UserID,
+		CreatedAt: time.Now(),
+		RagMode:   strconv.FormatBool(doc.RagMode),
+	}
+	if err := s.messageQueueService.PushToQueue("upload_tasks", etlTask); err != nil {
+		logging.Logger.Error("fail PushToQueue", "error", err, "docID", docID)
+		s.Fail(ctx, gen, "failed to queue ETL task: "+err.Error())
+		return nil, err
+	}
+	logging.Logger.Info("document reprocessing queued", "docID", docID, "generation", gen.ID)
+	return &models.ReprocessResp{DocID: docID, GenerationID: gen.ID, Status: "queued"}, nil
+}
+
+// checkNoPendingGeneration 同一文档同时只能有一个进行中的 generation，中断太久的标记为失败
+func (s *ReprocessService) checkNoPendingGeneration(ctx context.Context, docID string) error {
+	gens, err := s.genRepo.ListByDoc(ctx, docID)
+	if err != nil {
+		return err
+	}
+	for _, gen := range gens {
+		if gen.Status != models.GenerationProcessing {
+			continue
+		}
+		if time.Since(gen.CreatedAt) < generationStaleAfter {
+			return ErrDocumentBusy
+		}
+		s.Fail(ctx, gen, "reprocessing timed out")
+	}
+	return nil
+}
+
+// Generation ingest 收到的 file_id 是 generation 时返回它，否则返回 nil
+func (s *ReprocessService) Generation(ctx context.Context, fileID string) (*models.ChunkGeneration, error) {
+	gen, err := s.genRepo.GetByID(ctx, fileID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gen, nil
+}
+
+// Complete 所有 chunks 写入后把文档切换到新 generation；旧 chunks 可能仍被进行中的检索使用，
+// 宽限期过后由删除任务在没有其他文档引用时删除
+func (s *ReprocessService) Complete(ctx context.Context, gen *models.ChunkGeneration, metadata *cognicore.DocumentMetadata, sections []string, received, stored int32) error {
+	doc, err := s.docRepo.GetByID(ctx, gen.DocID)
+	if err != nil {
+		// 文档已被删除：丢弃新 chunks
+		s.Fail(ctx, gen, "document not found: "+err.Error())
+		return err
+	}
+	previous := doc.ChunkFileID()
+
+	now := time.Now()
+	gen.TotalPages = metadata.TotalPages
+	gen.EstimatedChunks = metadata.EstimatedChunks
+	gen.ChunksReceived = received
+	gen.ChunksStored = stored
+	if err := s.docRepo.SwitchChunkSet(ctx, gen.DocID, gen, sections, s.registry.ActiveID()); err != nil {
+		s.Fail(ctx, gen, "failed to switch chunks: "+err.Error())
+		return err
+	}
+	gen.Status = models.GenerationActive
+	gen.CompletedAt = &now
+	if previous != gen.ID && s.replacedGrace > 0 {
+		purgeAfter := now.Add(s.replacedGrace)
+		gen.ReplacedChunkSet = previous
+		gen.ReplacedPurgeAfter = &purgeAfter
+	}
+	if err := s.genRepo.Update(ctx, gen); err != nil {
+		logging.Logger.Error("fail to update chunk generation", "error", err, "generation", gen.ID)
+	}
+	if err := s.genRepo.RetireActive(ctx, gen.DocID, gen.ID); err != nil {
+		logging.Logger.Error("fail RetireActive", "error", err, "docID", gen.DocID)
+	}
+	if err := s.cacheService.DelCache(gen.DocID); err != nil {
+		logging.Logger.Error("fail to invalidate section cache", "error", err, "docID", gen.DocID)
+	}
+	if err := s.chunkRepo.InvalidateVectors(ctx, gen.ID); err != nil {
+		logging.Logger.Error("fail InvalidateVectors", "error", err, "generation", gen.ID)
+	}
+
+	// 旧 chunks 可能仍被内容相同的其他文档共用
+	if previous != gen.ID && s.replacedGrace <= 0 {
+		refs, err := s.docRepo.CountChunkSetRefs(ctx, previous, "")
+		if err != nil {
+			logging.Logger.Error("fail CountChunkSetRefs", "error", err, "chunkSet", previous)
+		} else if refs == 0 {
+			if _, err := s.chunkRepo.DeleteByFileID(ctx, previous); err != nil {
+				logging.Logger.Error("fail to delete previous chunks", "error", err, "chunkSet", previous)
+			}
+		}
+	}
+	logging.Logger.Info("document reprocessed", "docID", gen.DocID, "generation", gen.ID, "previous", previous, "chunks", stored)
+	return nil
+}
+
+// Fail 标记 generation 失败并删除已写入的 chunks，文档继续使用当前 chunks
+func (s *ReprocessService) Fail(ctx context.Context, gen *models.ChunkGeneration, reason string) {
+	now := time.Now()
+	gen.Status = models.GenerationFailed
+	gen.Error = reason
+	gen.CompletedAt = &now
+	if err := s.genRepo.Update(ctx, gen); err != nil {
+		logging.Logger.Error("fail to update chunk generation", "error", err, "generation", gen.ID)
+	}
+	if _, err := s.chunkRepo.DeleteByFileID(ctx, gen.ID); err != nil {
+		logging.Logger.Error("fail to delete generation chunks", "error", err, "generation", gen.ID)
+	}
+	logging.Logger.Warn("document reprocessing failed", "docID", gen.DocID, "generation", gen.ID, "reason", reason)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_chat_backend/models"
+	"go_chat_backend/platform/grpc/clients"
+	pb "go_chat_backend/platform/proto/cognicore"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RerankRequest 重排序请求
+type RerankRequest struct {
+	Query      string
+	Candidates []*models.Chunk
+	TopN       int
+	// LLMConfig 仅 LLM 打分器使用
+	LLMConfig *LLMConfig
+}
+
+// Reranker 对向量召回的候选重新打分，返回最相关的 TopN 个
+type Reranker interface {
+	Name() string
+	Rerank(ctx context.Context, req RerankRequest) ([]*models.ScoredChunk, error)
+}
+
+// NewReranker 根据配置创建 Reranker："grpc"、"llm"，其他值为不做重排序
+func NewReranker(kind string, grpcClients *clients.GrpcClients) Reranker {
+	switch kind {
+	case "grpc":
+		return &grpcReranker{clients: grpcClients}
+	case "llm":
+		return &llmReranker{}
+	default:
+		return &noopReranker{}
+	}
+}
+
+// noopReranker 保持向量召回的原始顺序
+type noopReranker struct{}
+
+func (r *noopReranker) Name() string { return "none" }
+
+func (r *noopReranker) Rerank(ctx context.Context, req RerankRequest) ([]*models.ScoredChunk, error) {
+	return keepOriginalOrder(req.Candidates, req.TopN), nil
+}
+
+// grpcReranker 调用 Python 端的 cross-encoder
+type grpcReranker struct {
+	clients *clients.GrpcClients
+}
+
+func (r *grpcReranker) Name() string { return "grpc" }
+
+func (r *grpcReranker) Rerank(ctx context.Context, req RerankRequest) ([]*models.ScoredChunk, error) {
+	byID := make(map[string]*models.Chunk, len(req.Candidates))
+	candidates := make([]*pb.RerankCandidate, 0, len(req.Candidates))
+	for _, c := range req.Candidates {
+		byID[c.ChunkID] = c
+		candidates = append(candidates, &pb.RerankCandidate{Id: c.ChunkID, Text: c.ChunkText})
+	}
+	resp, err := r.clients.RerankClient.Rerank(ctx, &pb.RerankRequest{
+		TaskId:     "rerank-" + time.Now().Format("20060102150405"),
+		Query:      req.Query,
+		Candidates: candidates,
+		TopN:       int32(req.TopN),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("rerank failed: %s", resp.Message)
+	}
+
+	res := make([]*models.ScoredChunk, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		chunk, ok := byID[result.Id]
+		if !ok {
+			continue
+		}
+		res = append(res, &models.ScoredChunk{Chunk: chunk, Score: float64(result.Score)})
+	}
+	return topScored(res, req.TopN), nil
+}
+
+// llmReranker 让 LLM 为每个候选打 0-10 分
+type llmReranker struct{}
+
+func (r *llmReranker) Name() string { return "llm" }
+
+func (r *llmReranker) Rerank(ctx context.Context, req RerankRequest) ([]*models.ScoredChunk, error) {
+	if req.LLMConfig == nil {
+		return nil, fmt.Errorf("llm reranker requires an LLM config")
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Rate how relevant each passage is to the question on a scale from 0 to 10.\n")
+	builder.WriteString("Reply with a JSON array of numbers only, one score per passage, in the same order.\n\n")
+	builder.WriteString("Question: " + req.Query + "\n\n")
+	for i, c := range req.Candidates {
+		builder.WriteString(fmt.Sprintf("Passage %d:\n%s\n\n", i+1, c.ChunkText))
+	}
+
+	answer, err := callLLM(ctx, builder.String(), req.LLMConfig.Provider, req.LLMConfig.Model, req.LLMConfig.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := parseScores(answer, len(req.Candidates))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*models.ScoredChunk, len(req.Candidates))
+	for i, c := range req.Candidates {
+		res[i] = &models.ScoredChunk{Chunk: c, Score: scores[i]}
+	}
+	return topScored(res, req.TopN), nil
+}
+
+// parseScores 从 LLM 回复中提取 JSON 分数数组
+func parseScores(answer string, n int) ([]float64, error) {
+	start := strings.Index(answer, "[")
+	end := strings.LastIndex(answer, "]")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("no score array in LLM response")
+	}
+	var scores []float64
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM scores: %w", err)
+	}
+	if len(scores) != n {
+		return nil, fmt.Errorf("expected %d scores, got %d", n, len(scores))
+	}
+	return scores, nil
+}
+
+// topScored 按分数从高到低排序并截取前 n 个
+func topScored(scored []*models.ScoredChunk, n int) []*models.ScoredChunk {
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+	if n > 0 && len(scored) > n {
+		scored = scored[:n]
+	}
+	return scored
+}
+
+// keepOriginalOrder 不做重排序，按召回顺序取前 n 个
+func keepOriginalOrder(candidates []*models.Chunk, n int) []*models.ScoredChunk {
+	if n > 0 && len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	res := make([]*models.ScoredChunk, len(candidates))
+	for i, c := range candidates {
+		res[i] = &models.ScoredChunk{Chunk: c, Score: float64(len(candidates) - i)}
+	}
+	return res
+}
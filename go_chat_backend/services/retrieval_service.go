@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/repository"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxNeighborWindow 单个命中最多向前/向后扩展的 chunk 数
+const maxNeighborWindow = 5
+
+// RetrievalConfig 检索参数
+type RetrievalConfig struct {
+	TopK             int           // 最终保留的 chunk 数（m）
+	RerankCandidates int           // 向量召回的候选数（k），用于重排序和 MMR
+	RerankTimeout    time.Duration // 重排序超时，超时后退回原始顺序
+	MMRLambda        float64       // MMR 相关性权重，取值 (0,1)；>=1 表示不做多样化
+	EmbeddingModel   string        // 查询向量所属模型（name@version），只与该模型的 chunk 向量比较
+}
+
+// RetrievalRequest 单次检索请求
+type RetrievalRequest struct {
+	FileID    string
+	Question  string
+	History   []*models.ChatNode
+	LLMConfig *LLMConfig
+}
+
+// Embedder 将文本批量向量化，GRPCService 为默认实现
+type Embedder interface {
+	GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// RetrievalService 负责 RAG 检索：查询改写 + 向量召回 + 重排序 + MMR + 相邻 chunk 扩展
+type RetrievalService struct {
+	chunkRepo repository.ChunkRepository
+	docRepo   repository.DocumentRepository
+	embedder  Embedder
+	reranker  Reranker
+	rewriter  *QueryRewriter
+	cfg       RetrievalConfig
+}
+
+func NewRetrievalService(
+	chunkRepo repository.ChunkRepository,
+	docRepo repository.DocumentRepository,
+	embedder Embedder,
+	reranker Reranker,
+	rewriter *QueryRewriter,
+	cfg RetrievalConfig,
+) *RetrievalService {
+	if cfg.TopK <= 0 {
+		cfg.TopK = 1
+	}
+	if cfg.RerankCandidates < cfg.TopK {
+		cfg.RerankCandidates = cfg.TopK
+	}
+	if cfg.RerankTimeout <= 0 {
+		cfg.RerankTimeout = 2 * time.Second
+	}
+	return &RetrievalService{
+		chunkRepo: chunkRepo,
+		docRepo:   docRepo,
+		embedder:  embedder,
+		reranker:  reranker,
+		rewriter:  rewriter,
+		cfg:       cfg,
+	}
+}
+
+// Retrieve 召回与问题最相似的 chunks，重排序、多样化后按文档配置扩展相邻 chunk
+func (s *RetrievalService) Retrieve(ctx context.Context, req RetrievalRequest) (*models.RetrievalResult, error) {
+	doc, err := s.docRepo.GetByID(ctx, req.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	if s.cfg.EmbeddingModel != "" && doc.EmbeddingModel != "" && doc.EmbeddingModel != s.cfg.EmbeddingModel {
+		// 重新向量化完成前只能命中已迁移的 chunk
+		logging.Logger.Warn("document embeddings are being migrated", "fileID", req.FileID, "doc_model", doc.EmbeddingModel, "query_model", s.cfg.EmbeddingModel)
+	}
+
+	trace := &models.RetrievalTrace{Reranker: s.reranker.Name()}
+	if s.mmrEnabled() {
+		trace.MMRLambda = s.cfg.MMRLambda
+	}
+
+	start := time.Now()
+	query, err := s.rewriter.Rewrite(ctx, req.History, req.Question, req.LLMConfig)
+	if err != nil {
+		logging.Logger.Warn("query rewrite failed, using original question", "error", err, "fileID", req.FileID)
+	}
+	trace.Timings.RewriteMs = time.Since(start).Milliseconds()
+	trace.Queries = query.Queries()
+	candidates, err := s.searchQueries(ctx, doc.ChunkFileID(), trace.Queries, &trace.Timings)
+	if err != nil {
+		return nil, err
+	}
+	traceByID := make(map[string]*models.CandidateTrace, len(candidates))
+	for _, c := range candidates {
+		t := &models.CandidateTrace{
+			ChunkID:    c.Chunk.ChunkID,
+			ChunkIndex: c.Chunk.ChunkIndex,
+			Chapter:    c.Chunk.Chapter,
+			Similarity: c.Score,
+		}
+		traceByID[t.ChunkID] = t
+		trace.Candidates = append(trace.Candidates, t)
+	}
+
+	// 重排序使用改写后的独立问题
+	start = time.Now()
+	ranked, reranked := s.rerank(ctx, query.Standalone, req.LLMConfig, candidates)
+	trace.Timings.RerankMs = time.Since(start).Milliseconds()
+	trace.RerankFallback = !reranked && !s.rerankDisabled()
+	if reranked {
+		for _, r := range ranked {
+			score := r.Score
+			traceByID[r.Chunk.ChunkID].RerankScore = &score
+		}
+	}
+
+	selected := ranked
+	if s.mmrEnabled() {
+		var mmrScores map[string]float64
+		selected, mmrScores = mmrSelect(ranked, s.cfg.TopK, s.cfg.MMRLambda)
+		for id, score := range mmrScores {
+			traceByID[id].MMRScore = &score
+		}
+	} else if len(selected) > s.cfg.TopK {
+		selected = selected[:s.cfg.TopK]
+	}
+
+	hits := make([]*models.Chunk, len(selected))
+	for i, r := range selected {
+		hits[i] = r.Chunk
+		traceByID[r.Chunk.ChunkID].Selected = true
+		traceByID[r.Chunk.ChunkID].SelectionRank = i + 1
+	}
+	start = time.Now()
+	passages, err := s.ExpandNeighbors(ctx, doc.ChunkFileID(), hits, clampNeighborWindow(doc.NeighborWindow))
+	if err != nil {
+		return nil, err
+	}
+	trace.Timings.ExpandMs = time.Since(start).Milliseconds()
+	logging.Logger.Debug("retrieval trace", "fileID", req.FileID, "trace", trace)
+	return &models.RetrievalResult{Passages: passages, Trace: trace}, nil
+}
+
+// searchQueries 一次批量向量化所有查询，分别召回后按 chunk 合并并保留最高相似度
+func (s *RetrievalService) searchQueries(ctx context.Context, fileID string, queries []string, timings *models.RetrievalTimings) ([]*models.ScoredChunk, error) {
+	limit := s.candidateCount()
+	best := make(map[string]*models.ScoredChunk)
+
+	start := time.Now()
+	embeddings, err := s.embedder.GetEmbeddings(ctx, queries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	timings.EmbeddingMs = time.Since(start).Milliseconds()
+
+	for _, embedding := range embeddings {
+		start := time.Now()
+		results, err := s.chunkRepo.SearchSimilar(ctx, fileID, s.cfg.EmbeddingModel, embedding, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search similar chunks: %w", err)
+		}
+		timings.SearchMs += time.Since(start).Milliseconds()
+		for _, r := range results {
+			if prev, ok := best[r.Chunk.ChunkID]; !ok || r.Score > prev.Score {
+				best[r.Chunk.ChunkID] = r
+			}
+		}
+	}
+
+	merged := make([]*models.ScoredChunk, 0, len(best))
+	for _, r := range best {
+		merged = append(merged, r)
+	}
+	return topScored(merged, limit), nil
+}
+
+func (s *RetrievalService) rerankDisabled() bool {
+	_, ok := s.reranker.(*noopReranker)
+	return ok
+}
+
+func (s *RetrievalService) mmrEnabled() bool {
+	return s.cfg.MMRLambda > 0 && s.cfg.MMRLambda < 1
+}
+
+// candidateCount 不做重排序和 MMR 时直接取 TopK，否则多召回一些候选
+func (s *RetrievalService) candidateCount() int {
+	if s.rerankDisabled() && !s.mmrEnabled() {
+		return s.cfg.TopK
+	}
+	return s.cfg.RerankCandidates
+}
+
+// rerank 在超时时间内重排序候选，失败或超时时退回向量召回的原始顺序。
+// 启用 MMR 时保留全部候选供 MMR 选择，否则只保留 TopK；第二个返回值表示是否使用了重排序结果
+func (s *RetrievalService) rerank(ctx context.Context, query string, llmConfig *LLMConfig, candidates []*models.ScoredChunk) ([]*models.ScoredChunk, bool) {
+	if len(candidates) == 0 || s.rerankDisabled() {
+		return candidates, false
+	}
+	topN := s.cfg.TopK
+	if s.mmrEnabled() {
+		topN = len(candidates)
+	}
+	chunks := make([]*models.Chunk, len(candidates))
+	for i, c := range candidates {
+		chunks[i] = c.Chunk
+	}
+
+	rerankCtx, cancel := context.WithTimeout(ctx, s.cfg.RerankTimeout)
+	defer cancel()
+
+	type result struct {
+		ranked []*models.ScoredChunk
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ranked, err := s.reranker.Rerank(rerankCtx, RerankRequest{
+			Query:      query,
+			Candidates: chunks,
+			TopN:       topN,
+			LLMConfig:  llmConfig,
+		})
+		done <- result{ranked, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err == nil && len(r.ranked) > 0 {
+			return r.ranked, true
+		}
+		logging.Logger.Warn("rerank failed, falling back to vector order", "reranker", s.reranker.Name(), "error", r.err)
+	case <-rerankCtx.Done():
+		logging.Logger.Warn("rerank timed out, falling back to vector order", "reranker", s.reranker.Name(), "timeout", s.cfg.RerankTimeout)
+	}
+	return candidates, false
+}
+
+// ExpandNeighbors 将每个命中扩展为 [index-window, index+window] 的窗口，
+// 同一章节内重叠或相邻的窗口会被合并，重复 chunk 只保留一次
+func (s *RetrievalService) ExpandNeighbors(ctx context.Context, fileID string, hits []*models.Chunk, window int32) ([]*models.RetrievedPassage, error) {
+	type span struct {
+		chapter  string
+		from, to int32
+		hits     []string
+		rank     int
+	}
+
+	// 按章节分组
+	byChapter := make(map[string][]*span)
+	var chapters []string
+	for rank, hit := range hits {
+		if _, ok := byChapter[hit.Chapter]; !ok {
+			chapters = append(chapters, hit.Chapter)
+		}
+		from := hit.ChunkIndex - window
+		if from < 0 {
+			from = 0
+		}
+		byChapter[hit.Chapter] = append(byChapter[hit.Chapter], &span{
+			chapter: hit.Chapter,
+			from:    from,
+			to:      hit.ChunkIndex + window,
+			hits:    []string{hit.ChunkID},
+			rank:    rank,
+		})
+	}
+
+	// 合并重叠窗口
+	var merged []*span
+	for _, chapter := range chapters {
+		spans := byChapter[chapter]
+		sort.Slice(spans, func(i, j int) bool { return spans[i].from < spans[j].from })
+		curr := spans[0]
+		for _, next := range spans[1:] {
+			if next.from <= curr.to+1 {
+				if next.to > curr.to {
+					curr.to = next.to
+				}
+				curr.hits = append(curr.hits, next.hits...)
+				if next.rank < curr.rank {
+					curr.rank = next.rank
+				}
+				continue
+			}
+			merged = append(merged, curr)
+			curr = next
+		}
+		merged = append(merged, curr)
+	}
+	// 保持原始相似度排序：包含最相似命中的窗口排在前面
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].rank < merged[j].rank })
+
+	seen := make(map[string]bool)
+	passages := make([]*models.RetrievedPassage, 0, len(merged))
+	for _, sp := range merged {
+		chunks, err := s.chunkRepo.GetByIndexRange(ctx, fileID, sp.chapter, sp.from, sp.to)
+		if err != nil {
+			logging.Logger.Error("fail GetByIndexRange", "error", err, "fileID", fileID, "chapter", sp.chapter)
+			return nil, err
+		}
+		passage := &models.RetrievedPassage{
+			FileID:      fileID,
+			Chapter:     sp.chapter,
+			StartIndex:  sp.from,
+			EndIndex:    sp.to,
+			HitChunkIDs: sp.hits,
+		}
+		var text strings.Builder
+		for _, c := range chunks {
+			if seen[c.ChunkID] {
+				continue
+			}
+			seen[c.ChunkID] = true
+			passage.ChunkIDs = append(passage.ChunkIDs, c.ChunkID)
+			if text.Len() > 0 {
+				text.WriteString("\n")
+			}
+			text.WriteString(c.ChunkText)
+		}
+		if len(passage.ChunkIDs) == 0 {
+			continue
+		}
+		passage.StartIndex = chunks[0].ChunkIndex
+		passage.EndIndex = chunks[len(chunks)-1].ChunkIndex
+		passage.Text = text.String()
+		passages = append(passages, passage)
+	}
+	return passages, nil
+}
+
+func clampNeighborWindow(window int32) int32 {
+	if window < 0 {
+		return 0
+	}
+	if window > maxNeighborWindow {
+		return maxNeighborWindow
+	}
+	return window
+}
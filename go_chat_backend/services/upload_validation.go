@@ -0,0 +1,281 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go_chat_backend/models"
+	"go_chat_backend/pkg/logging"
+	"go_chat_backend/platform/storage"
+	"hash"
+	"io"
+	"strconv"
+)
+
+// 上传校验失败，被拒绝的对象会从存储中删除
+var (
+	ErrUploadMissing = errors.New("file does not exist in storage")
+	ErrEmptyFile     = errors.New("file is empty")
+	ErrFileTooLarge  = errors.New("file too large")
+	ErrSizeMismatch  = errors.New("file size does not match the declared size")
+	ErrNotPDF        = errors.New("file is not a PDF")
+	ErrEncryptedPDF  = errors.New("encrypted PDFs are not supported")
+)
+
+// UploadErrorCode 上传校验错误对应的错误码，不是校验错误时返回空字符串
+func UploadErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrUploadMissing):
+		return "file_missing"
+	case errors.Is(err, ErrEmptyFile):
+		return "file_empty"
+	case errors.Is(err, ErrFileTooLarge):
+		return "file_too_large"
+	case errors.Is(err, ErrSizeMismatch):
+		return "file_size_mismatch"
+	case errors.Is(err, ErrNotPDF):
+		return "file_not_pdf"
+	case errors.Is(err, ErrEncryptedPDF):
+		return "file_encrypted"
+	}
+	return ""
+}
+
+// pdfMagic PDF 文件头
+var pdfMagic = []byte("%PDF-")
+
+// pdfTailSize 保留的文件末尾长度，startxref 按规范位于最后 1024 字节内
+const pdfTailSize = 8 * 1024
+
+// pdfSectionSize 从 startxref 指向的位置读取的长度，足以包含交叉引用表之后的 trailer 或交叉引用流的字典
+const pdfSectionSize = 64 * 1024
+
+// pdfInspector 在一次流式读取中计算 SHA-256、检查文件头并保留文件末尾，用于定位最后的 trailer
+type pdfInspector struct {
+	hash hash.Hash
+	size int64
+	head []byte
+	tail []byte
+}
+
+func newPDFInspector() *pdfInspector {
+	return &pdfInspector{hash: sha256.New()}
+}
+
+func (p *pdfInspector) Write(b []byte) (int, error) {
+	p.hash.Write(b)
+	p.size += int64(len(b))
+	if need := len(pdfMagic) - len(p.head); need > 0 {
+		p.head = append(p.head, b[:min(need, len(b))]...)
+	}
+	if len(b) >= pdfTailSize {
+		p.tail = append(p.tail[:0], b[len(b)-pdfTailSize:]...)
+	} else {
+		p.tail = append(p.tail, b...)
+		if extra := len(p.tail) - pdfTailSize; extra > 0 {
+			p.tail = append(p.tail[:0], p.tail[extra:]...)
+		}
+	}
+	return len(b), nil
+}
+
+// check 读取完毕后的格式校验
+func (p *pdfInspector) check() error {
+	switch {
+	case p.size == 0:
+		return ErrEmptyFile
+	case !bytes.Equal(p.head, pdfMagic):
+		return ErrNotPDF
+	}
+	return nil
+}
+
+// xrefOffset 解析最后一个 startxref 指向的交叉引用段偏移量，找不到时返回 -1
+func (p *pdfInspector) xrefOffset() int64 {
+	i := bytes.LastIndex(p.tail, []byte("startxref"))
+	if i < 0 {
+		return -1
+	}
+	fields := bytes.Fields(p.tail[i+len("startxref"):])
+	if len(fields) == 0 {
+		return -1
+	}
+	off, err := strconv.ParseInt(string(fields[0]), 10, 64)
+	if err != nil || off < 0 || off >= p.size {
+		return -1
+	}
+	return off
+}
+
+// trailerEncrypted 判断最后的交叉引用段的 trailer 是否带有 /Encrypt。section 从交叉引用段开始：
+// 交叉引用表（xref ... trailer << >>）或交叉引用流对象（N G obj << /Type /XRef ... >> stream）。
+// 增量更新时最后的 trailer 包含之前 trailer 的所有项，因此只检查最后一段
+func trailerEncrypted(section []byte) bool {
+	section = bytes.TrimLeft(section, " \t\r\n\f\x00")
+	if bytes.HasPrefix(section, []byte("xref")) || bytes.HasPrefix(section, []byte("trailer")) {
+		i := bytes.Index(section, []byte("trailer"))
+		if i < 0 {
+			return false
+		}
+		section = section[i+len("trailer"):]
+	}
+	i := bytes.Index(section, []byte("<<"))
+	if i < 0 {
+		return false
+	}
+	return dictHasKey(section[i:], []byte("/Encrypt"))
+}
+
+// dictHasKey 判断 b 开头的字典的顶层是否有 key，跳过字符串和嵌套字典（如 /Encrypt 字典里的 /EncryptMetadata）
+func dictHasKey(b, key []byte) bool {
+	depth := 0
+	for i := 0; i < len(b); i++ {
+		switch {
+		case bytes.HasPrefix(b[i:], []byte("<<")):
+			depth++
+			i++
+		case bytes.HasPrefix(b[i:], []byte(">>")):
+			depth--
+			i++
+			if depth == 0 {
+				return false
+			}
+		case b[i] == '<':
+			// 十六进制字符串
+			j := bytes.IndexByte(b[i:], '>')
+			if j < 0 {
+				return false
+			}
+			i += j
+		case b[i] == '(':
+			i = skipLiteralString(b, i)
+		case depth == 1 && bytes.HasPrefix(b[i:], key):
+			end := i + len(key)
+			if end == len(b) || isPDFDelimiter(b[end]) {
+				return true
+			}
+			i = end - 1
+		}
+	}
+	return false
+}
+
+// skipLiteralString 返回从 b[i] 的 '(' 开始的字符串结束处 ')' 的下标，处理转义和嵌套括号
+func skipLiteralString(b []byte, i int) int {
+	nest := 0
+	for ; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '(':
+			nest++
+		case ')':
+			nest--
+			if nest == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+func isPDFDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte(" \t\r\n\f\x00()<>[]{}/%"), c) >= 0
+}
+
+func (p *pdfInspector) sum() string {
+	return hex.EncodeToString(p.hash.Sum(nil))
+}
+
+// validateUpload 校验浏览器直传的对象：大小与声明一致、是未加密的 PDF，返回内容的 SHA-256
+func (s *DocumentService) validateUpload(ctx context.Context, info *models.DocumentMeta) (string, error) {
+	obj, err := s.storageService.Stat(ctx, info.FileKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return "", ErrUploadMissing
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case obj.Size == 0:
+		return "", ErrEmptyFile
+	case obj.Size > s.maxFileSize:
+		return "", fmt.Errorf("%w: max %d bytes", ErrFileTooLarge, s.maxFileSize)
+	case info.FileSize > 0 && obj.Size != info.FileSize:
+		return "", fmt.Errorf("%w: declared %d, stored %d", ErrSizeMismatch, info.FileSize, obj.Size)
+	}
+
+	r, err := s.storageService.OpenFile(ctx, info.FileKey)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	inspector := newPDFInspector()
+	if _, err := io.Copy(inspector, r); err != nil {
+		return "", err
+	}
+	if err := inspector.check(); err != nil {
+		return "", err
+	}
+	encrypted, err := s.isEncryptedPDF(ctx, info.FileKey, inspector)
+	if err != nil {
+		return "", err
+	}
+	if encrypted {
+		return "", ErrEncryptedPDF
+	}
+	return inspector.sum(), nil
+}
+
+// isEncryptedPDF 检查文件末尾的 trailer，再读取 startxref 指向的交叉引用段并检查其 trailer。交叉引用段在保留的文件末尾之前时
+// （线性化文件的主交叉引用段在文件开头）重新打开对象读取；无法定位 startxref 时不拒绝，由 ETL 处理
+func (s *DocumentService) isEncryptedPDF(ctx context.Context, fileKey string, p *pdfInspector) (bool, error) {
+	// 交叉引用表的 trailer 通常紧挨着 startxref；交叉引用表很大时从偏移量读取的长度可能到不了 trailer
+	if i := bytes.LastIndex(p.tail, []byte("trailer")); i >= 0 && trailerEncrypted(p.tail[i:]) {
+		return true, nil
+	}
+	off := p.xrefOffset()
+	if off < 0 {
+		return false, nil
+	}
+	if tailStart := p.size - int64(len(p.tail)); off >= tailStart {
+		return trailerEncrypted(p.tail[off-tailStart:]), nil
+	}
+	r, err := s.storageService.OpenFile(ctx, fileKey)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	if _, err := io.CopyN(io.Discard, r, off); err != nil {
+		return false, err
+	}
+	section, err := io.ReadAll(io.LimitReader(r, pdfSectionSize))
+	if err != nil {
+		return false, err
+	}
+	return trailerEncrypted(section), nil
+}
+
+// rejectUpload 删除未通过校验的对象并把文档标记为失败
+func (s *DocumentService) rejectUpload(ctx context.Context, info *models.DocumentMeta, reason error) {
+	logging.Logger.Warn("upload rejected", "docID", info.FileID, "reason", reason)
+	if err := s.storageService.DeleteFile(info.FileKey); err != nil {
+		logging.Logger.Error("fail DeleteFile", "error", err, "fileKey", info.FileKey)
+	}
+	if err := transitionStatus(ctx, s.docRepo, info, models.StatusFailed, reason.Error()); err != nil {
+		logging.Logger.Error("fail TransitionStatus", "error", err, "docID", info.FileID)
+	}
+	err := s.eventPublisher.PublishDocumentEvent(&models.DocumentEvent{
+		Type:    models.EventDocumentFailed,
+		DocID:   info.FileID,
+		UserID:  info.UserID,
+		Status:  UploadErrorCode(reason),
+		Message: reason.Error(),
+	})
+	if err != nil {
+		logging.Logger.Error("fail to publish event", "error", err, "docID", info.FileID)
+	}
+}
@@ -34,6 +34,11 @@ func NewFileKeyGenerator(strategy FileKeyStrategy, prefix string) *FileKeyGenera
 	}
 }
 
+// Prefix 生成的 key 都位于 Prefix()/ 之下
+func (fkg *FileKeyGenerator) Prefix() string {
+	return fkg.prefix
+}
+
 func (fkg *FileKeyGenerator) GenerateFileKey(filename, userID string) string {
 	switch fkg.strategy {
 	case StrategyHashBased:
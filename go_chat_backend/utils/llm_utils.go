@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"go_chat_backend/models"
@@ -9,17 +10,30 @@ import (
 	"net/http"
 )
 
-func CallOpenAI(prompt string, modelName, apiKey string) (string, error) {
+// BuildMessages 返回实际发送给对应 provider 的消息列表
+func BuildMessages(provider, prompt string) []models.ChatMessage {
+	if provider == "OpenAI" {
+		return openAIMessages(prompt)
+	}
+	return []models.ChatMessage{{Role: "user", Content: prompt}}
+}
 
-	reqBody := models.LLMChatRequest{
-		Model: modelName,
-		Messages: []models.ChatMessage{
-			{Role: "user", Content: "You are a helpful assistant."},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
+func openAIMessages(prompt string) []models.ChatMessage {
+	return []models.ChatMessage{
+		{Role: "user", Content: "You are a helpful assistant."},
+		{
+			Role:    "user",
+			Content: prompt,
 		},
+	}
+}
+
+// CallOpenAI 调用 OpenAI；ctx 取消或超时时中止请求
+func CallOpenAI(ctx context.Context, prompt string, modelName, apiKey string) (string, error) {
+
+	reqBody := models.LLMChatRequest{
+		Model:       modelName,
+		Messages:    openAIMessages(prompt),
 		MaxTokens:   2000,
 		Temperature: 0.7,
 	}
@@ -27,7 +41,7 @@ func CallOpenAI(prompt string, modelName, apiKey string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -61,7 +75,8 @@ func CallOpenAI(prompt string, modelName, apiKey string) (string, error) {
 
 }
 
-func CallGemini(prompt string, modelName string, apiKey string) (string, error) {
+// CallGemini 调用 Gemini；ctx 取消或超时时中止请求
+func CallGemini(ctx context.Context, prompt string, modelName string, apiKey string) (string, error) {
 	// Gemini API 请求体结构
 	type GeminiContent struct {
 		Parts []struct {
@@ -108,7 +123,7 @@ func CallGemini(prompt string, modelName string, apiKey string) (string, error)
 	//modelName := "gemini-1.5-flash" // 您也可以使用 "gemini-1.5-pro"
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", modelName, apiKey)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
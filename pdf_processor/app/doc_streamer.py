@@ -1,12 +1,50 @@
 # broadcaster/document_streamer.py
+from typing import Optional, Set, Tuple
+import grpc
 from utils import get_logger
 import traceback
 from infra.grpc_infra.grpc_client import CRPCClient
+from infra.grpc_infra.protos import cognicore_pb2
 from service.grpc_ingest_service import IngestService
 
 logger = get_logger(__name__)
 
 
+async def fetch_task_url(doc_id: str) -> Optional[str]:
+    """Ask the Go service for a freshly signed download URL of the task's file.
+
+    The URL is minted when the task is dequeued, so it cannot expire while the task waits in the queue.
+    Returns None when the document no longer exists (e.g. deleted while queued).
+    """
+    async with CRPCClient() as stub:
+        try:
+            response = await stub.GetTaskURL(cognicore_pb2.TaskURLRequest(file_id=doc_id))  # type: ignore
+        except grpc.aio.AioRpcError as e:
+            if e.code() == grpc.StatusCode.NOT_FOUND:
+                return None
+            raise
+    return response.url
+
+
+# Statuses (of a document or a reprocess generation) that no longer accept an ingest stream.
+FINISHED_STATUSES = {"completed", "completed_without_summary", "active", "retired"}
+
+
+async def fetch_stored_chunks(doc_id: str) -> Tuple[Set[int], str]:
+    """Ask the Go service which chunk indexes of the file are already stored, and its current status.
+
+    A retried task resumes by sending only the missing chunks. On any error nothing is skipped and the
+    whole document is streamed again (chunks are written idempotently by (file_id, chunk_index)).
+    """
+    try:
+        async with CRPCClient() as stub:
+            response = await stub.GetStoredChunks(cognicore_pb2.StoredChunksRequest(file_id=doc_id))  # type: ignore
+    except Exception as e:
+        logger.warning(f"Failed to fetch stored chunks for {doc_id}, streaming all chunks: {e}")
+        return set(), ""
+    return set(response.chunk_indexes), response.status
+
+
 async def stream_to_go_service(
     doc_id: str,
     user_id: str,
@@ -16,10 +54,25 @@ async def stream_to_go_service(
 ):
     """High-level broadcaster that streams document to Go service."""
     try:
+        stored_indexes, status = await fetch_stored_chunks(doc_id)
+        if status in FINISHED_STATUSES:
+            logger.info(f"Document {doc_id} is already {status}, nothing to stream")
+            return {
+                "success": True,
+                "message": f"document already {status}",
+                "chunks_received": len(stored_indexes),
+                "chunks_stored": len(stored_indexes),
+                "chunks_failed": 0,
+                "processing_time_ms": 0,
+                "file_id": doc_id,
+            }
+        if stored_indexes:
+            logger.info(f"Resuming {doc_id}: {len(stored_indexes)} chunks already stored")
+
         ingest_service = IngestService()
         metadata = ingest_service.build_metadata(doc_id, user_id, pdf_path, estimated_chunks)
         async with CRPCClient() as stub:
-            request_gen = ingest_service.request_stream(doc_id, metadata, data_generator)
+            request_gen = ingest_service.request_stream(doc_id, metadata, data_generator, stored_indexes)
             logger.info(f"🚀 Streaming document {doc_id}...")
             response = await stub.IngestDocument(request_gen)
 
@@ -12,7 +12,7 @@ from pathlib import Path
 from datetime import datetime
 from infra.bucket_infra.file_downloader import download_from_bucket
 from infra.document_infra.processing import process_and_vectorize
-from app.doc_streamer import stream_to_go_service
+from app.doc_streamer import stream_to_go_service, fetch_task_url
 
 
 async def process_data(task_data: dict, redis_client: redis.Redis, rag_mode: bool) -> dict:
@@ -29,7 +29,6 @@ async def process_data(task_data: dict, redis_client: redis.Redis, rag_mode: boo
 
         file_name = task_data.get("FileName")
         user_id = task_data.get("UserID")
-        download_url = task_data.get("URL")
         file_size = task_data.get("FileSize")
         created_at = task_data.get("CreatedAt")
 
@@ -39,16 +38,25 @@ async def process_data(task_data: dict, redis_client: redis.Redis, rag_mode: boo
         logger.info(f"  - UserID: {user_id}")
         logger.info(f"  - FileSize: {file_size} bytes" if file_size else "  - FileSize: N/A")
         logger.info(f"  - CreatedAt: {created_at}")
-        logger.info(f"  - URL: {download_url[:80]}..." if download_url else "  - URL: None")
 
         # Validate required fields
         if not doc_id:
             logger.error(f" Missing DocID in task data. Skipping.")
             return {"success": False, "error": "Missing DocID"}
 
-        if not download_url:
-            logger.error(f" No download URL provided for document {doc_id}. Skipping.")
-            return {"success": False, "error": "Missing URL"}
+        # The download URL is minted now rather than at enqueue time, so it is valid however long the task waited
+        try:
+            download_url = await fetch_task_url(doc_id)
+        except Exception:
+            # tasks queued by older versions still carry a URL
+            download_url = task_data.get("URL")
+            if not download_url:
+                raise
+            logger.warning(f"GetTaskURL failed for document {doc_id}, using the URL from the task")
+        if download_url is None:
+            logger.warning(f" Document {doc_id} no longer exists. Skipping.")
+            return {"success": False, "error": "Document not found"}
+        logger.info(f"  - URL: {download_url[:80]}...")
 
         if not file_name:
             logger.warning(f"Missing FileName for document {doc_id}, using DocID as filename")
@@ -5,10 +5,19 @@ import traceback
 
 logger = get_logger(__name__)
 
+def _is_signed_url(download_url: str) -> bool:
+    """Go 服务下发的链接自带签名（presigned S3/MinIO 或本地存储的 /api/blob 链接），直接 GET 即可。"""
+    return "X-Amz-Signature=" in download_url or "signature=" in download_url
+
+
 async def download_from_bucket(download_url: str, local_path: Path):
-    """Download a file from the given URL to the specified local path."""
+    """Download a file from the given URL to the specified local path.
+
+    签名链接（任意 STORAGE_TYPE，包括 local）直接通过 HTTP GET 下载；只有 STORAGE_TYPE=s3 且链接未签名时
+    才通过 SDK 使用本地凭据读取对象。
+    """
     try:
-        if config.STORAGE_TYPE == "s3":
+        if config.STORAGE_TYPE == "s3" and not _is_signed_url(download_url):
             import aioboto3
             session = aioboto3.Session()
             async with session.client(
@@ -23,8 +32,7 @@ async def download_from_bucket(download_url: str, local_path: Path):
                 )
                 await s3_client.download_file(bucket_name, object_key, str(local_path))
 
-
-        elif config.STORAGE_TYPE == "minio":
+        else:
             import aiohttp, aiofiles
 
             async with aiohttp.ClientSession() as session:
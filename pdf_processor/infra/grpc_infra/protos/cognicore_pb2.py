@@ -24,7 +24,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0f\x63ognicore.proto\x12\tcognicore\"\x97\x01\n\tTextChunk\x12\x10\n\x08\x63hunk_id\x18\x01 \x01(\t\x12\x0f\n\x07\x66ile_id\x18\x02 \x01(\t\x12\x0f\n\x07\x63hapter\x18\x03 \x01(\t\x12\x13\n\x0b\x63hapter_num\x18\x04 \x01(\t\x12\x12\n\nchunk_text\x18\x05 \x01(\t\x12\x18\n\x10\x65mbedding_vector\x18\x06 \x03(\x02\x12\x13\n\x0b\x63hunk_index\x18\x08 \x01(\x05\"\xaf\x01\n\x10\x44ocumentMetadata\x12\x0f\n\x07\x66ile_id\x18\x01 \x01(\t\x12\x0f\n\x07user_id\x18\x02 \x01(\t\x12\x10\n\x08\x66ilename\x18\x03 \x01(\t\x12\x13\n\x0btotal_pages\x18\x04 \x01(\x05\x12\x18\n\x10\x65stimated_chunks\x18\x05 \x01(\x05\x12\x11\n\tfile_hash\x18\x06 \x01(\t\x12\x11\n\tfile_size\x18\x07 \x01(\x03\x12\x12\n\ncreated_at\x18\x08 \x01(\t\"w\n\rIngestRequest\x12/\n\x08metadata\x18\x01 \x01(\x0b\x32\x1b.cognicore.DocumentMetadataH\x00\x12%\n\x05\x63hunk\x18\x02 \x01(\x0b\x32\x14.cognicore.TextChunkH\x00\x42\x0e\n\x0crequest_type\"\xa6\x01\n\x0eIngestResponse\x12\x0f\n\x07success\x18\x01 \x01(\x08\x12\x0f\n\x07message\x18\x02 \x01(\t\x12\x17\n\x0f\x63hunks_received\x18\x03 \x01(\x05\x12\x15\n\rchunks_stored\x18\x04 \x01(\x05\x12\x15\n\rchunks_failed\x18\x05 \x01(\x05\x12\x1a\n\x12processing_time_ms\x18\x07 \x01(\x03\x12\x0f\n\x07\x66ile_id\x18\x08 \x01(\t\"1\n\x10\x45mbeddingRequest\x12\x0f\n\x07task_id\x18\x01 \x01(\t\x12\x0c\n\x04text\x18\x02 \x01(\t\"\\\n\x11\x45mbeddingResponse\x12\x0f\n\x07success\x18\x01 \x01(\x08\x12\x0f\n\x07message\x18\x02 \x01(\t\x12\x12\n\nembeddings\x18\x03 \x03(\x02\x12\x11\n\tdimension\x18\x04 \x01(\x05\x32\x9e\x01\n\rIngestService\x12G\n\x0eIngestDocument\x12\x18.cognicore.IngestRequest\x1a\x19.cognicore.IngestResponse(\x01\x12\x44\n\x11IngestSingleChunk\x12\x14.cognicore.TextChunk\x1a\x19.cognicore.IngestResponse2]\n\x10\x45mbeddingService\x12I\n\x0cGetEmbedding\x12\x1b.cognicore.EmbeddingRequest\x1a\x1c.cognicore.EmbeddingResponseb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0f\x63ognicore.proto\x12\tcognicore\"\x97\x01\n\tTextChunk\x12\x10\n\x08\x63hunk_id\x18\x01 \x01(\t\x12\x0f\n\x07\x66ile_id\x18\x02 \x01(\t\x12\x0f\n\x07\x63hapter\x18\x03 \x01(\t\x12\x13\n\x0b\x63hapter_num\x18\x04 \x01(\t\x12\x12\n\nchunk_text\x18\x05 \x01(\t\x12\x18\n\x10\x65mbedding_vector\x18\x06 \x03(\x02\x12\x13\n\x0b\x63hunk_index\x18\x08 \x01(\x05\"\xaf\x01\n\x10\x44ocumentMetadata\x12\x0f\n\x07\x66ile_id\x18\x01 \x01(\t\x12\x0f\n\x07user_id\x18\x02 \x01(\t\x12\x10\n\x08\x66ilename\x18\x03 \x01(\t\x12\x13\n\x0btotal_pages\x18\x04 \x01(\x05\x12\x18\n\x10\x65stimated_chunks\x18\x05 \x01(\x05\x12\x11\n\tfile_hash\x18\x06 \x01(\t\x12\x11\n\tfile_size\x18\x07 \x01(\x03\x12\x12\n\ncreated_at\x18\x08 \x01(\t\"w\n\rIngestRequest\x12/\n\x08metadata\x18\x01 \x01(\x0b\x32\x1b.cognicore.DocumentMetadataH\x00\x12%\n\x05\x63hunk\x18\x02 \x01(\x0b\x32\x14.cognicore.TextChunkH\x00\x42\x0e\n\x0crequest_type\"\xa6\x01\n\x0eIngestResponse\x12\x0f\n\x07success\x18\x01 \x01(\x08\x12\x0f\n\x07message\x18\x02 \x01(\t\x12\x17\n\x0f\x63hunks_received\x18\x03 \x01(\x05\x12\x15\n\rchunks_stored\x18\x04 \x01(\x05\x12\x15\n\rchunks_failed\x18\x05 \x01(\x05\x12\x1a\n\x12processing_time_ms\x18\x07 \x01(\x03\x12\x0f\n\x07\x66ile_id\x18\x08 \x01(\t\"&\n\x13StoredChunksRequest\x12\x0f\n\x07\x66ile_id\x18\x01 \x01(\t\"N\n\x14StoredChunksResponse\x12\x0f\n\x07\x66ile_id\x18\x01 \x01(\t\x12\x15\n\rchunk_indexes\x18\x02 \x03(\x05\x12\x0e\n\x06status\x18\x03 \x01(\t\"!\n\x0eTaskURLRequest\x12\x0f\n\x07\x66ile_id\x18\x01 \x01(\t\"C\n\x0fTaskURLResponse\x12\x0f\n\x07\x66ile_id\x18\x01 \x01(\t\x12\x0b\n\x03url\x18\x02 \x01(\t\x12\x12\n\nexpires_at\x18\x03 \x01(\x03\"1\n\x10\x45mbeddingRequest\x12\x0f\n\x07task_id\x18\x01 \x01(\t\x12\x0c\n\x04text\x18\x02 \x01(\t\"\\\n\x11\x45mbeddingResponse\x12\x0f\n\x07success\x18\x01 \x01(\x08\x12\x0f\n\x07message\x18\x02 \x01(\t\x12\x12\n\nembeddings\x18\x03 \x03(\x02\x12\x11\n\tdimension\x18\x04 \x01(\x05\"7\n\x15\x42\x61tchEmbeddingRequest\x12\x0f\n\x07task_id\x18\x01 \x01(\t\x12\r\n\x05texts\x18\x02 \x03(\t\"!\n\x0f\x45mbeddingVector\x12\x0e\n\x06values\x18\x01 \x03(\x02\"\x8c\x01\n\x16\x42\x61tchEmbeddingResponse\x12\x0f\n\x07success\x18\x01 \x01(\x08\x12\x0f\n\x07message\x18\x02 \x01(\t\x12.\n\nembeddings\x18\x03 \x03(\x0b\x32\x1a.cognicore.EmbeddingVector\x12\x11\n\tdimension\x18\x04 \x01(\x05\x12\r\n\x05model\x18\x05 \x01(\t\"+\n\x0fRerankCandidate\x12\n\n\x02id\x18\x01 \x01(\t\x12\x0c\n\x04text\x18\x02 \x01(\t\"n\n\rRerankRequest\x12\x0f\n\x07task_id\x18\x01 \x01(\t\x12\r\n\x05query\x18\x02 \x01(\t\x12.\n\ncandidates\x18\x03 \x03(\x0b\x32\x1a.cognicore.RerankCandidate\x12\r\n\x05top_n\x18\x04 \x01(\x05\")\n\x0cRerankResult\x12\n\n\x02id\x18\x01 \x01(\t\x12\r\n\x05score\x18\x02 \x01(\x02\"\\\n\x0eRerankResponse\x12\x0f\n\x07success\x18\x01 \x01(\x08\x12\x0f\n\x07message\x18\x02 \x01(\t\x12(\n\x07results\x18\x03 \x03(\x0b\x32\x17.cognicore.RerankResult2\xb7\x02\n\rIngestService\x12G\n\x0eIngestDocument\x12\x18.cognicore.IngestRequest\x1a\x19.cognicore.IngestResponse(\x01\x12\x44\n\x11IngestSingleChunk\x12\x14.cognicore.TextChunk\x1a\x19.cognicore.IngestResponse\x12R\n\x0fGetStoredChunks\x12\x1e.cognicore.StoredChunksRequest\x1a\x1f.cognicore.StoredChunksResponse\x12\x43\n\nGetTaskURL\x12\x19.cognicore.TaskURLRequest\x1a\x1a.cognicore.TaskURLResponse2\xb3\x01\n\x10\x45mbeddingService\x12I\n\x0cGetEmbedding\x12\x1b.cognicore.EmbeddingRequest\x1a\x1c.cognicore.EmbeddingResponse\x12T\n\rGetEmbeddings\x12 .cognicore.BatchEmbeddingRequest\x1a!.cognicore.BatchEmbeddingResponse2N\n\rRerankService\x12=\n\x06Rerank\x12\x18.cognicore.RerankRequest\x1a\x19.cognicore.RerankResponseb\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -39,12 +39,36 @@ if not _descriptor._USE_C_DESCRIPTORS:
   _globals['_INGESTREQUEST']._serialized_end=481
   _globals['_INGESTRESPONSE']._serialized_start=484
   _globals['_INGESTRESPONSE']._serialized_end=650
-  _globals['_EMBEDDINGREQUEST']._serialized_start=652
-  _globals['_EMBEDDINGREQUEST']._serialized_end=701
-  _globals['_EMBEDDINGRESPONSE']._serialized_start=703
-  _globals['_EMBEDDINGRESPONSE']._serialized_end=795
-  _globals['_INGESTSERVICE']._serialized_start=798
-  _globals['_INGESTSERVICE']._serialized_end=956
-  _globals['_EMBEDDINGSERVICE']._serialized_start=958
-  _globals['_EMBEDDINGSERVICE']._serialized_end=1051
+  _globals['_STOREDCHUNKSREQUEST']._serialized_start=652
+  _globals['_STOREDCHUNKSREQUEST']._serialized_end=690
+  _globals['_STOREDCHUNKSRESPONSE']._serialized_start=692
+  _globals['_STOREDCHUNKSRESPONSE']._serialized_end=770
+  _globals['_TASKURLREQUEST']._serialized_start=772
+  _globals['_TASKURLREQUEST']._serialized_end=805
+  _globals['_TASKURLRESPONSE']._serialized_start=807
+  _globals['_TASKURLRESPONSE']._serialized_end=874
+  _globals['_EMBEDDINGREQUEST']._serialized_start=876
+  _globals['_EMBEDDINGREQUEST']._serialized_end=925
+  _globals['_EMBEDDINGRESPONSE']._serialized_start=927
+  _globals['_EMBEDDINGRESPONSE']._serialized_end=1019
+  _globals['_BATCHEMBEDDINGREQUEST']._serialized_start=1021
+  _globals['_BATCHEMBEDDINGREQUEST']._serialized_end=1076
+  _globals['_EMBEDDINGVECTOR']._serialized_start=1078
+  _globals['_EMBEDDINGVECTOR']._serialized_end=1111
+  _globals['_BATCHEMBEDDINGRESPONSE']._serialized_start=1114
+  _globals['_BATCHEMBEDDINGRESPONSE']._serialized_end=1254
+  _globals['_RERANKCANDIDATE']._serialized_start=1256
+  _globals['_RERANKCANDIDATE']._serialized_end=1299
+  _globals['_RERANKREQUEST']._serialized_start=1301
+  _globals['_RERANKREQUEST']._serialized_end=1411
+  _globals['_RERANKRESULT']._serialized_start=1413
+  _globals['_RERANKRESULT']._serialized_end=1454
+  _globals['_RERANKRESPONSE']._serialized_start=1456
+  _globals['_RERANKRESPONSE']._serialized_end=1548
+  _globals['_INGESTSERVICE']._serialized_start=1551
+  _globals['_INGESTSERVICE']._serialized_end=1862
+  _globals['_EMBEDDINGSERVICE']._serialized_start=1865
+  _globals['_EMBEDDINGSERVICE']._serialized_end=2044
+  _globals['_RERANKSERVICE']._serialized_start=2046
+  _globals['_RERANKSERVICE']._serialized_end=2124
 # @@protoc_insertion_point(module_scope)
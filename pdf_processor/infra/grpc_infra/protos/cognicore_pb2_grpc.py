@@ -51,6 +51,16 @@ class IngestServiceStub(object):
                 request_serializer=cognicore__pb2.TextChunk.SerializeToString,
                 response_deserializer=cognicore__pb2.IngestResponse.FromString,
                 _registered_method=True)
+        self.GetStoredChunks = channel.unary_unary(
+                '/cognicore.IngestService/GetStoredChunks',
+                request_serializer=cognicore__pb2.StoredChunksRequest.SerializeToString,
+                response_deserializer=cognicore__pb2.StoredChunksResponse.FromString,
+                _registered_method=True)
+        self.GetTaskURL = channel.unary_unary(
+                '/cognicore.IngestService/GetTaskURL',
+                request_serializer=cognicore__pb2.TaskURLRequest.SerializeToString,
+                response_deserializer=cognicore__pb2.TaskURLResponse.FromString,
+                _registered_method=True)
 
 
 class IngestServiceServicer(object):
@@ -79,6 +89,22 @@ class IngestServiceServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def GetStoredChunks(self, request, context):
+        """Unary 模式：查询已存储的 chunk_index
+        使用场景：流中断后重试，先查询再只发送缺失的 chunks（metadata 仍需发送）
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def GetTaskURL(self, request, context):
+        """Unary 模式：获取任务文件的下载链接
+        使用场景：ETL worker 从队列取出任务后再获取，链接不会因任务排队过久而过期
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_IngestServiceServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -92,6 +118,16 @@ def add_IngestServiceServicer_to_server(servicer, server):
                     request_deserializer=cognicore__pb2.TextChunk.FromString,
                     response_serializer=cognicore__pb2.IngestResponse.SerializeToString,
             ),
+            'GetStoredChunks': grpc.unary_unary_rpc_method_handler(
+                    servicer.GetStoredChunks,
+                    request_deserializer=cognicore__pb2.StoredChunksRequest.FromString,
+                    response_serializer=cognicore__pb2.StoredChunksResponse.SerializeToString,
+            ),
+            'GetTaskURL': grpc.unary_unary_rpc_method_handler(
+                    servicer.GetTaskURL,
+                    request_deserializer=cognicore__pb2.TaskURLRequest.FromString,
+                    response_serializer=cognicore__pb2.TaskURLResponse.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'cognicore.IngestService', rpc_method_handlers)
@@ -164,6 +200,60 @@ class IngestService(object):
             metadata,
             _registered_method=True)
 
+    @staticmethod
+    def GetStoredChunks(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            '/cognicore.IngestService/GetStoredChunks',
+            cognicore__pb2.StoredChunksRequest.SerializeToString,
+            cognicore__pb2.StoredChunksResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)
+
+    @staticmethod
+    def GetTaskURL(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            '/cognicore.IngestService/GetTaskURL',
+            cognicore__pb2.TaskURLRequest.SerializeToString,
+            cognicore__pb2.TaskURLResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)
+
 
 class EmbeddingServiceStub(object):
     """Embedding 服务
@@ -184,6 +274,11 @@ class EmbeddingServiceStub(object):
                 request_serializer=cognicore__pb2.EmbeddingRequest.SerializeToString,
                 response_deserializer=cognicore__pb2.EmbeddingResponse.FromString,
                 _registered_method=True)
+        self.GetEmbeddings = channel.unary_unary(
+                '/cognicore.EmbeddingService/GetEmbeddings',
+                request_serializer=cognicore__pb2.BatchEmbeddingRequest.SerializeToString,
+                response_deserializer=cognicore__pb2.BatchEmbeddingResponse.FromString,
+                _registered_method=True)
 
 
 class EmbeddingServiceServicer(object):
@@ -201,6 +296,13 @@ class EmbeddingServiceServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def GetEmbeddings(self, request, context):
+        """Go 调用 Python：一次发送多条文本，获取对应的向量
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_EmbeddingServiceServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -209,6 +311,11 @@ def add_EmbeddingServiceServicer_to_server(servicer, server):
                     request_deserializer=cognicore__pb2.EmbeddingRequest.FromString,
                     response_serializer=cognicore__pb2.EmbeddingResponse.SerializeToString,
             ),
+            'GetEmbeddings': grpc.unary_unary_rpc_method_handler(
+                    servicer.GetEmbeddings,
+                    request_deserializer=cognicore__pb2.BatchEmbeddingRequest.FromString,
+                    response_serializer=cognicore__pb2.BatchEmbeddingResponse.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'cognicore.EmbeddingService', rpc_method_handlers)
@@ -251,3 +358,118 @@ class EmbeddingService(object):
             timeout,
             metadata,
             _registered_method=True)
+
+    @staticmethod
+    def GetEmbeddings(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            '/cognicore.EmbeddingService/GetEmbeddings',
+            cognicore__pb2.BatchEmbeddingRequest.SerializeToString,
+            cognicore__pb2.BatchEmbeddingResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)
+
+
+class RerankServiceStub(object):
+    """Rerank 服务
+    角色分配：
+    - Python：服务端（Server），使用 cross-encoder 对候选打分
+    - Go：客户端（Client），发送查询和候选 chunks
+    数据流向：Go → Python → Go
+    """
+
+    def __init__(self, channel):
+        """Constructor.
+
+        Args:
+            channel: A grpc.Channel.
+        """
+        self.Rerank = channel.unary_unary(
+                '/cognicore.RerankService/Rerank',
+                request_serializer=cognicore__pb2.RerankRequest.SerializeToString,
+                response_deserializer=cognicore__pb2.RerankResponse.FromString,
+                _registered_method=True)
+
+
+class RerankServiceServicer(object):
+    """Rerank 服务
+    角色分配：
+    - Python：服务端（Server），使用 cross-encoder 对候选打分
+    - Go：客户端（Client），发送查询和候选 chunks
+    数据流向：Go → Python → Go
+    """
+
+    def Rerank(self, request, context):
+        """Go 调用 Python：发送查询和候选，获取排序后的分数
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+
+def add_RerankServiceServicer_to_server(servicer, server):
+    rpc_method_handlers = {
+            'Rerank': grpc.unary_unary_rpc_method_handler(
+                    servicer.Rerank,
+                    request_deserializer=cognicore__pb2.RerankRequest.FromString,
+                    response_serializer=cognicore__pb2.RerankResponse.SerializeToString,
+            ),
+    }
+    generic_handler = grpc.method_handlers_generic_handler(
+            'cognicore.RerankService', rpc_method_handlers)
+    server.add_generic_rpc_handlers((generic_handler,))
+    server.add_registered_method_handlers('cognicore.RerankService', rpc_method_handlers)
+
+
+ # This class is part of an EXPERIMENTAL API.
+class RerankService(object):
+    """Rerank 服务
+    角色分配：
+    - Python：服务端（Server），使用 cross-encoder 对候选打分
+    - Go：客户端（Client），发送查询和候选 chunks
+    数据流向：Go → Python → Go
+    """
+
+    @staticmethod
+    def Rerank(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            '/cognicore.RerankService/Rerank',
+            cognicore__pb2.RerankRequest.SerializeToString,
+            cognicore__pb2.RerankResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)
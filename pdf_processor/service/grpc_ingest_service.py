@@ -6,7 +6,7 @@ from utils import get_logger
 from infra.grpc_infra.protos import cognicore_pb2
 
 logger = get_logger(__name__)
-from typing import Generator
+from typing import Generator, Optional, Set
 
 class IngestService:
     "base class for gRPC Ingest Service"
@@ -39,11 +39,16 @@ class IngestService:
         doc_id : str,
         metadata: cognicore_pb2.DocumentMetadata, # type: ignore
         data_generator: Generator[dict, None, None],
+        skip_indexes: Optional[Set[int]] = None,
     ):
+        """Yield the metadata followed by the chunks; indexes in skip_indexes are already stored and not sent again."""
         yield cognicore_pb2.IngestRequest(metadata=metadata)  # type: ignore
         logger.info(f"Metadata prepared and sent for document {doc_id}.")
 
+        skip_indexes = skip_indexes or set()
         for idx, chunk_data in enumerate(data_generator):
+            if idx in skip_indexes:
+                continue
             try:
                 embeddings = chunk_data.get("embeddings", [])
                 if not embeddings: